@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+
+	"url-shortener/api"
+)
+
+func (us *URLShortener) PurgeExpired() int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	return us.purgeExpiredLocked(time.Now())
+}
+
+func (us *URLShortener) purgeExpiredLocked(now time.Time) int {
+	purged := 0
+	for code, mapping := range us.storage {
+		if mapping.DeletedAt == nil && mapping.isExpired(now) {
+			us.removeLocked(code, StatusExpired, now)
+			purged++
+		}
+	}
+	return purged
+}
+
+// PurgeStaleBefore removes links that have not been accessed since t. Links
+// that were never accessed are judged by their creation time.
+func (us *URLShortener) PurgeStaleBefore(t time.Time) int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	now := time.Now()
+	purged := 0
+	for code, mapping := range us.storage {
+		lastUsed := mapping.CreatedAt
+		if last := lastAccessed(mapping); last != nil {
+			lastUsed = *last
+		}
+		if lastUsed.Before(t) {
+			us.removeLocked(code, StatusDeleted, now)
+			purged++
+		}
+	}
+	return purged
+}
+
+func (us *URLShortener) purgeHandler(w http.ResponseWriter, r *http.Request) {
+	var staleBefore *time.Time
+	if param := r.URL.Query().Get("stale_before"); param != "" {
+		parsed, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			us.writeJSONError(w, r, http.StatusBadRequest, errorf(ErrInvalidTimestamp, "stale_before must be an RFC3339 timestamp"))
+			return
+		}
+		staleBefore = &parsed
+	}
+
+	response := map[string]int{
+		"expired": us.PurgeExpired(),
+	}
+	if staleBefore != nil {
+		response["stale"] = us.PurgeStaleBefore(*staleBefore)
+	}
+	response["purged"] = response["expired"] + response["stale"]
+
+	log.Printf("Admin purge removed %d URL(s) (expired: %d, stale: %d)", response["purged"], response["expired"], response["stale"])
+
+	writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+}
+
+// Rough per-entry costs used by StorageStats: map bucket overhead and the
+// header of a heap-allocated time.Time.
+const (
+	mapEntryOverhead = 48
+	timeValueSize    = int64(unsafe.Sizeof(time.Time{}))
+)
+
+// StorageStats estimates how much memory the in-memory store holds. The byte
+// count covers struct sizes, string contents and map entries; it is an
+// approximation meant for capacity planning, not an exact accounting. There is
+// no separate dedup index: duplicate detection scans the link map.
+func (us *URLShortener) StorageStats() api.StorageStats {
+	stats := us.estimateStorage()
+
+	// ReadMemStats stops the world, so it runs after the store lock is
+	// released.
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats.HeapAllocBytes = mem.HeapAlloc
+	return stats
+}
+
+func (us *URLShortener) estimateStorage() api.StorageStats {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	stats := api.StorageStats{
+		Links:      len(us.storage),
+		Tombstones: len(us.tombstones),
+		Campaigns:  len(us.campaigns),
+	}
+
+	var bytes int64
+	for key, mapping := range us.storage {
+		bytes += int64(len(key)) + mapEntryOverhead + int64(unsafe.Sizeof(*mapping))
+		bytes += int64(len(mapping.ID) + len(mapping.ShortCode) + len(mapping.Namespace) + len(mapping.OriginalURL) + len(mapping.Owner) + len(mapping.CampaignID))
+		bytes += int64(len(mapping.Description) + len(mapping.SubmittedURL) + len(mapping.AliasOf) + len(mapping.ConfirmTokenHash))
+		for _, alias := range mapping.Aliases {
+			bytes += int64(len(alias)) + int64(unsafe.Sizeof(alias))
+		}
+		for _, t := range []*time.Time{mapping.ExpiresAt, mapping.DeletedAt, mapping.LastAccessedAt} {
+			if t != nil {
+				bytes += timeValueSize
+			}
+		}
+		for country := range mapping.ClicksByCountry {
+			bytes += int64(len(country)) + 8 + mapEntryOverhead
+		}
+		for param, value := range mapping.AppendParams {
+			bytes += int64(len(param)+len(value)) + mapEntryOverhead
+		}
+		for _, rules := range []map[string]string{mapping.RedirectHeaders, mapping.DeviceRules, mapping.GeoRules} {
+			for key, value := range rules {
+				bytes += int64(len(key)+len(value)) + mapEntryOverhead
+			}
+		}
+		bytes += int64(cap(mapping.clicks)) * timeValueSize
+		for visitor := range mapping.recentVisitors {
+			bytes += int64(len(visitor)) + timeValueSize + mapEntryOverhead
+		}
+		stats.TrackedVisitors += len(mapping.recentVisitors)
+	}
+	for alias, target := range us.aliases {
+		bytes += int64(len(alias)+len(target)) + mapEntryOverhead
+	}
+	for key, preview := range us.previews {
+		bytes += int64(len(key)+len(preview.token)+len(preview.owner)+len(preview.originalURL)) + int64(unsafe.Sizeof(preview)) + mapEntryOverhead
+		bytes += int64(len(preview.token)+len(key)) + mapEntryOverhead
+	}
+	for key := range us.tombstones {
+		bytes += int64(len(key)) + int64(unsafe.Sizeof(tombstone{})) + mapEntryOverhead
+	}
+	for id, campaign := range us.campaigns {
+		bytes += int64(len(id)+len(campaign.ID)+len(campaign.Name)+len(campaign.Owner)) + int64(unsafe.Sizeof(*campaign)) + mapEntryOverhead
+	}
+	stats.ApproxBytes = bytes
+	return stats
+}
+
+func (us *URLShortener) storageStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, us.StorageStats(), us.jsonOptions(r))
+}
+
+// SetBaseURL changes the base used to build short URLs in responses, e.g.
+// after moving to a new domain. It must be an absolute http(s) URL without a
+// query or fragment. The previous base is returned.
+func (us *URLShortener) SetBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+		parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return "", fmt.Errorf("%w: base URL must be an absolute http(s) URL without query or fragment", ErrInvalidURL)
+	}
+
+	base := strings.TrimSuffix(parsed.String(), "/")
+	previous := us.baseURL.Swap(base).(string)
+	return previous, nil
+}
+
+// rebaseHandler changes the base URL of every short link, so unlike the other
+// admin routes it stays closed when auth is disabled: without ADMIN_API_KEYS
+// nobody may rebase.
+func (us *URLShortener) rebaseHandler(w http.ResponseWriter, r *http.Request) {
+	if len(us.config.AdminAPIKeys) == 0 {
+		us.writeJSONError(w, r, http.StatusForbidden, errorf(ErrAdminRequired, "Admin API key required"))
+		return
+	}
+
+	var req api.RebaseRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	previous, err := us.SetBaseURL(req.BaseURL)
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	response := api.RebaseResponse{BaseURL: us.currentBaseURL(), PreviousBaseURL: previous}
+	log.Printf("Admin rebase changed the base URL from %s to %s", previous, response.BaseURL)
+	if us.config.TrustProxyHeaders {
+		log.Printf("Warning: TRUST_PROXY_HEADERS is enabled, so responses keep using the forwarded host instead of the new base URL")
+	}
+
+	writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func newAdminTestShortener(t *testing.T) *URLShortener {
+	return newTestShortener(t, func(c *Config) {
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+	})
+}
+
+func TestPurgeRemovesOnlyExpiredAndStale(t *testing.T) {
+	us := newAdminTestShortener(t)
+	expired := mustCreate(t, us, "https://example.com/expired", CreateOptions{CustomName: "expired"})
+	stale := mustCreate(t, us, "https://example.com/stale", CreateOptions{CustomName: "stale"})
+	mustCreate(t, us, "https://example.com/fresh", CreateOptions{CustomName: "fresh"})
+
+	us.mutex.Lock()
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	lastUsed := time.Now().Add(-30 * 24 * time.Hour)
+	stale.CreatedAt = lastUsed
+	stale.LastAccessedAt = &lastUsed
+	us.mutex.Unlock()
+
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/admin/purge", ""), http.StatusUnauthorized)
+
+	cutoff := time.Now().Add(-7 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	rec := serve(t, us, http.MethodPost, "/api/admin/purge?stale_before="+cutoff, "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	var counts map[string]int
+	decodeBody(t, rec, &counts)
+	if counts["expired"] != 1 || counts["stale"] != 1 || counts["purged"] != 2 {
+		t.Fatalf("counts = %v, want 1 expired and 1 stale", counts)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/fresh", ""), http.StatusMovedPermanently)
+	for _, path := range []string{"/expired", "/stale"} {
+		if rec := serve(t, us, http.MethodGet, path, ""); rec.Code == http.StatusMovedPermanently {
+			t.Errorf("%s still redirects after purge", path)
+		}
+	}
+}
+
+func TestPurgeRejectsBadStaleBefore(t *testing.T) {
+	us := newAdminTestShortener(t)
+	rec := serve(t, us, http.MethodPost, "/api/admin/purge?stale_before=yesterday", "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusBadRequest)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeInvalidTimestamp {
+		t.Fatalf("error code = %s, want %s", body.ErrorCode, CodeInvalidTimestamp)
+	}
+}
+
+func TestStorageStatsCounts(t *testing.T) {
+	us := newAdminTestShortener(t)
+	for i, url := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		mustCreate(t, us, url, CreateOptions{CustomName: []string{"sa1", "sa2", "sa3"}[i]})
+	}
+	if err := us.DeleteURL("sa3", Principal{Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/admin/storage", ""), http.StatusUnauthorized)
+	rec := serve(t, us, http.MethodGet, "/api/admin/storage", "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.StorageStats
+	decodeBody(t, rec, &stats)
+	if stats.Links != 2 || stats.Tombstones != 1 {
+		t.Fatalf("links = %d, tombstones = %d, want 2 and 1", stats.Links, stats.Tombstones)
+	}
+	if stats.ApproxBytes <= 0 || stats.HeapAllocBytes == 0 {
+		t.Fatalf("sizes = %d approx, %d heap", stats.ApproxBytes, stats.HeapAllocBytes)
+	}
+
+	before := stats.ApproxBytes
+	mustCreate(t, us, "https://example.com/"+strings.Repeat("x", 1000), CreateOptions{})
+	if after := us.StorageStats().ApproxBytes; after < before+1000 {
+		t.Fatalf("approx bytes grew from %d to %d after adding a 1000+ byte URL", before, after)
+	}
+}
+
+func TestStorageEstimateCoversLinkExtras(t *testing.T) {
+	us := newAdminTestShortener(t)
+	mapping := mustCreate(t, us, "https://example.com/extras", CreateOptions{CustomName: "extras"})
+	long := strings.Repeat("x", 1000)
+
+	for _, tt := range []struct {
+		name string
+		add  func()
+	}{
+		{"description", func() { mapping.Description = long }},
+		{"country counts", func() { mapping.ClicksByCountry = map[string]int64{long: 1} }},
+		{"device rules", func() { mapping.DeviceRules = map[string]string{"mobile": "https://m.example.com/" + long} }},
+		{"geo rules", func() { mapping.GeoRules = map[string]string{"GB": "https://uk.example.com/" + long} }},
+		{"click history", func() { mapping.clicks = make([]time.Time, 100) }},
+		{"previews", func() {
+			us.previews["held"] = codePreview{token: "token", originalURL: "https://example.com/" + long, expiresAt: time.Now().Add(time.Minute)}
+		}},
+	} {
+		before := us.StorageStats().ApproxBytes
+		us.mutex.Lock()
+		tt.add()
+		us.mutex.Unlock()
+		if after := us.StorageStats().ApproxBytes; after < before+1000 {
+			t.Errorf("approx bytes grew from %d to %d after adding %s", before, after, tt.name)
+		}
+	}
+}
+
+func TestRebaseChangesNewShortURLs(t *testing.T) {
+	us := newAdminTestShortener(t)
+	mustCreate(t, us, "https://example.com/before", CreateOptions{CustomName: "before"})
+
+	rec := serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"https://sho.rt/"}`, "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	var rebase api.RebaseResponse
+	decodeBody(t, rec, &rebase)
+	if rebase.BaseURL != "https://sho.rt" || rebase.PreviousBaseURL != testBaseURL {
+		t.Fatalf("rebase response = %+v", rebase)
+	}
+
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/after","custom_name":"after"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var created api.CreateURLResponse
+	decodeBody(t, rec, &created)
+	if created.ShortURL != "https://sho.rt/after" {
+		t.Fatalf("short_url = %s, want the new base", created.ShortURL)
+	}
+
+	// Links made before the move are reported under the new host too.
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/before","custom_name":"before"}`)
+	decodeBody(t, rec, &created)
+	if created.ShortURL != "https://sho.rt/before" {
+		t.Fatalf("existing link short_url = %s", created.ShortURL)
+	}
+}
+
+func TestRebaseRejectsInvalidBase(t *testing.T) {
+	us := newAdminTestShortener(t)
+	for _, base := range []string{"", "sho.rt", "ftp://sho.rt", "https://sho.rt/?x=1", "https://sho.rt/#top", "https://user@sho.rt"} {
+		rec := serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"`+base+`"}`, "X-API-Key", "admin-key")
+		expectStatus(t, rec, http.StatusBadRequest)
+		var body api.ErrorResponse
+		decodeBody(t, rec, &body)
+		if body.ErrorCode != CodeInvalidURL {
+			t.Errorf("base %q: error code = %s, want %s", base, body.ErrorCode, CodeInvalidURL)
+		}
+	}
+	if got := us.currentBaseURL(); got != testBaseURL {
+		t.Fatalf("base URL = %s after rejected rebases", got)
+	}
+}
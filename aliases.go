@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"url-shortener/api"
+)
+
+// Alias stats modes. Shared aliases are extra keys for the same mapping, so
+// every alias adds to one set of counts and stats for any alias return the
+// original's. Separate aliases are independent copies of the link that only
+// remember which link they were made from (AliasOf) and count on their own.
+const (
+	AliasStatsShared   = "shared"
+	AliasStatsSeparate = "separate"
+)
+
+// followAliasLocked returns the storage key a shared alias points at, or key
+// itself when it is not an alias. Callers must hold the lock.
+func (us *URLShortener) followAliasLocked(key string) string {
+	if target, isAlias := us.aliases[key]; isAlias {
+		return target
+	}
+	return key
+}
+
+// codeTakenLocked reports whether key is in use by a link or a shared alias.
+// Callers must hold the lock.
+func (us *URLShortener) codeTakenLocked(key string) bool {
+	_, isLink := us.storage[key]
+	_, isAlias := us.aliases[key]
+	return isLink || isAlias || us.previewHeldLocked(key)
+}
+
+// AddAlias makes aliasCode, in the same namespace as existingCode, redirect
+// to the same destination. Whether the alias shares the original's stats is
+// decided by the AliasStats config.
+func (us *URLShortener) AddAlias(existingCode, aliasCode string) error {
+	if !isValidCustomName(aliasCode) {
+		return errorf(ErrInvalidCustomName, "invalid alias '%s': must be 3-20 characters, using only letters, numbers, hyphens, and underscores", aliasCode)
+	}
+	if us.isReservedCode(aliasCode) {
+		return errorf(ErrReservedCode, "alias '%s' is reserved. Please choose a different name", aliasCode)
+	}
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[us.followAliasLocked(us.canonicalCode(existingCode))]
+	if !exists || mapping.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	aliasCode = us.canonicalCode(aliasCode)
+	aliasKey := storageKey(mapping.Namespace, aliasCode)
+	if us.codeTakenLocked(aliasKey) {
+		return errorf(ErrCodeTaken, "alias '%s' is already taken. Please choose a different name", aliasCode)
+	}
+
+	if us.config.AliasStats == AliasStatsSeparate {
+		if err := us.ensureCapacityLocked(); err != nil {
+			return err
+		}
+		us.storage[aliasKey] = &URLMapping{
+			ID:           aliasKey,
+			ShortCode:    aliasCode,
+			Namespace:    mapping.Namespace,
+			OriginalURL:  mapping.OriginalURL,
+			SubmittedURL: mapping.SubmittedURL,
+			CreatedAt:    time.Now(),
+			Owner:        mapping.Owner,
+			ExpiresAt:    mapping.ExpiresAt,
+			Enabled:      mapping.Enabled,
+			Description:  mapping.Description,
+
+			AppendParams:    mapping.AppendParams,
+			OverwriteParams: mapping.OverwriteParams,
+			CampaignID:      mapping.CampaignID,
+			DelaySeconds:    mapping.DelaySeconds,
+			AliasOf:         mapping.ID,
+			RedirectHeaders: mapping.RedirectHeaders,
+			DeviceRules:     mapping.DeviceRules,
+			GeoRules:        mapping.GeoRules,
+
+			ConfirmTokenHash: mapping.ConfirmTokenHash,
+			CountsOnly:       mapping.CountsOnly,
+		}
+	} else {
+		us.aliases[aliasKey] = mapping.ID
+		mapping.Aliases = append(mapping.Aliases, aliasCode)
+	}
+	delete(us.tombstones, aliasKey)
+
+	log.Printf("Added %s alias '%s' for '%s'", us.config.AliasStats, aliasKey, mapping.ID)
+	return nil
+}
+
+// dropAliasesLocked removes mapping's shared aliases, leaving a tombstone
+// with status for each. Callers must hold the lock.
+func (us *URLShortener) dropAliasesLocked(mapping *URLMapping, status string, now time.Time) {
+	for _, alias := range mapping.Aliases {
+		key := storageKey(mapping.Namespace, alias)
+		delete(us.aliases, key)
+		us.tombstones[key] = tombstone{status: status, removedAt: now}
+	}
+}
+
+// repointAliasesLocked points mapping's shared aliases, and the AliasOf of
+// separate aliases made from it, at its current key after it has moved from
+// oldKey. Callers must hold the lock.
+func (us *URLShortener) repointAliasesLocked(mapping *URLMapping, oldKey string) {
+	for _, alias := range mapping.Aliases {
+		us.aliases[storageKey(mapping.Namespace, alias)] = mapping.ID
+	}
+	for _, alias := range us.storage {
+		if alias.AliasOf == oldKey {
+			alias.AliasOf = mapping.ID
+		}
+	}
+}
+
+func (us *URLShortener) addAliasHandler(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	var req api.CreateAliasRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	mapping, err := us.GetStats(shortCode)
+	if err != nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	if err := us.AddAlias(shortCode, req.Alias); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		case errors.Is(err, ErrCodeTaken):
+			us.writeJSONError(w, r, http.StatusConflict, err)
+		case errors.Is(err, ErrStoreFull):
+			us.writeJSONError(w, r, http.StatusInsufficientStorage, errorf(ErrStoreFull, "Link storage is full, please try again later"))
+		default:
+			us.writeJSONError(w, r, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	alias := URLMapping{ShortCode: us.canonicalCode(req.Alias), Namespace: mapping.Namespace}
+	response := api.CreateURLResponse{
+		ShortCode:   alias.ShortCode,
+		Namespace:   alias.Namespace,
+		OriginalURL: mapping.OriginalURL,
+		ShortURL:    fmt.Sprintf("%s/%s", us.publicBaseURL(r), alias.path()),
+	}
+	writeJSON(w, http.StatusCreated, response, us.jsonOptions(r))
+}
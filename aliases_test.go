@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func addAlias(t *testing.T, us *URLShortener, code, alias string, wantStatus int) {
+	t.Helper()
+	rec := serve(t, us, http.MethodPost, "/api/urls/"+code+"/alias", `{"alias":"`+alias+`"}`)
+	expectStatus(t, rec, wantStatus)
+}
+
+func TestSharedAliasesShareCounts(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/sale", CreateOptions{CustomName: "sale"})
+
+	rec := serve(t, us, http.MethodPost, "/api/urls/sale/alias", `{"alias":"bargains"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var created api.CreateURLResponse
+	decodeBody(t, rec, &created)
+	if created.ShortURL != testBaseURL+"/bargains" || created.OriginalURL != "https://example.com/sale" {
+		t.Fatalf("alias response = %+v", created)
+	}
+
+	for _, code := range []string{"sale", "bargains", "bargains"} {
+		rec := serve(t, us, http.MethodGet, "/"+code, "")
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != "https://example.com/sale" {
+			t.Fatalf("%s redirected to %q", code, got)
+		}
+	}
+	for _, code := range []string{"sale", "bargains"} {
+		if got := accessCount(t, us, code); got != 3 {
+			t.Fatalf("%s access count = %d, want the shared 3", code, got)
+		}
+	}
+
+	// Deleting the link takes its aliases with it.
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/sale", ""), http.StatusNoContent)
+	expectStatus(t, serve(t, us, http.MethodGet, "/bargains", ""), http.StatusGone)
+}
+
+func TestSeparateAliasesCountOnTheirOwn(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.AliasStats = AliasStatsSeparate })
+	mustCreate(t, us, "https://example.com/sale", CreateOptions{CustomName: "sale"})
+	addAlias(t, us, "sale", "bargains", http.StatusCreated)
+
+	serve(t, us, http.MethodGet, "/sale", "")
+	serve(t, us, http.MethodGet, "/bargains", "")
+	serve(t, us, http.MethodGet, "/bargains", "")
+
+	if got := accessCount(t, us, "sale"); got != 1 {
+		t.Fatalf("sale access count = %d, want 1", got)
+	}
+	if got := accessCount(t, us, "bargains"); got != 2 {
+		t.Fatalf("bargains access count = %d, want 2", got)
+	}
+}
+
+func TestAddAliasErrors(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/sale", CreateOptions{CustomName: "sale"})
+	mustCreate(t, us, "https://example.com/other", CreateOptions{CustomName: "other"})
+	addAlias(t, us, "sale", "bargains", http.StatusCreated)
+
+	addAlias(t, us, "sale", "other", http.StatusConflict)
+	addAlias(t, us, "sale", "bargains", http.StatusConflict)
+	addAlias(t, us, "nosuch", "fresh", http.StatusNotFound)
+	addAlias(t, us, "sale", "no", http.StatusBadRequest)
+	addAlias(t, us, "sale", "admin", http.StatusBadRequest)
+
+	// An alias of an alias points at the original link.
+	addAlias(t, us, "bargains", "deals", http.StatusCreated)
+	if got := serve(t, us, http.MethodGet, "/deals", "").Header().Get("Location"); got != "https://example.com/sale" {
+		t.Fatalf("deals redirected to %q", got)
+	}
+}
+
+func TestBatchStatsResolveAliases(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/sale", CreateOptions{CustomName: "sale"})
+	addAlias(t, us, "sale", "bargains", http.StatusCreated)
+	expired := mustCreate(t, us, "https://example.com/old", CreateOptions{CustomName: "stale"})
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	serve(t, us, http.MethodGet, "/bargains", "")
+
+	rec := serve(t, us, http.MethodPost, "/api/stats/batch", `{"short_codes":["bargains","stale"]}`)
+	expectStatus(t, rec, http.StatusOK)
+	var results map[string]api.BatchStatsResult
+	decodeBody(t, rec, &results)
+
+	var single api.URLStats
+	decodeBody(t, serve(t, us, http.MethodGet, "/api/stats/bargains", ""), &single)
+	if got := results["bargains"].Stats; got == nil || got.ShortCode != single.ShortCode || got.AccessCount != single.AccessCount || got.Status != single.Status {
+		t.Fatalf("batch stats for the alias = %+v, want %+v", got, single)
+	}
+	if got := results["stale"].Stats; got == nil || got.Status != StatusExpired {
+		t.Fatalf("batch stats for an expired link = %+v, want status %s", results["stale"], StatusExpired)
+	}
+}
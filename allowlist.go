@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// hostMatches reports whether host matches pattern. "*.example.com" matches
+// any subdomain of example.com at any depth, but not example.com itself.
+func hostMatches(host, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// isDestinationAllowed checks the destination host against
+// DestinationAllowlist. An empty list allows every host; with a list set,
+// destinations without a host (mailto:, tel:) are rejected.
+func (us *URLShortener) isDestinationAllowed(destination string) bool {
+	if len(us.config.DestinationAllowlist) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return false
+	}
+
+	for _, pattern := range us.config.DestinationAllowlist {
+		if hostMatches(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
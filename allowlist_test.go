@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDestinationAllowlist(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.DestinationAllowlist = []string{"intranet.example.com", "*.corp.example.com"}
+	})
+
+	for url, allowed := range map[string]bool{
+		"https://intranet.example.com/wiki":       true,
+		"https://INTRANET.example.com./wiki":      true,
+		"https://hr.corp.example.com/forms":       true,
+		"https://a.b.corp.example.com/deep":       true,
+		"https://corp.example.com/":               false,
+		"https://evilcorp.example.com/":           false,
+		"https://corp.example.com.evil.test/":     false,
+		"https://intranet.example.com@evil.test/": false,
+		"https://example.org/":                    false,
+	} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"`+url+`"}`)
+		if got := rec.Code == http.StatusCreated; got != allowed {
+			t.Errorf("%s: status = %d, allowed = %v", url, rec.Code, allowed)
+		}
+	}
+}
+
+func TestEmptyAllowlistAllowsAnyHost(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://anything.example.net/"}`), http.StatusCreated)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const analyticsQueueSize = 1024
+
+// ClickRecord is the analytics record emitted for each counted redirect.
+type ClickRecord struct {
+	ShortCode string    `json:"short_code"`
+	Namespace string    `json:"namespace,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Country   string    `json:"country,omitempty"`
+}
+
+// AnalyticsSink receives click records, separately from the request log.
+// Record is called off the redirect path but should still return quickly.
+type AnalyticsSink interface {
+	Record(ClickRecord)
+}
+
+// JSONLinesSink writes click records to an io.Writer as JSON lines. Records
+// are queued and written by a background goroutine through a buffer that is
+// flushed whenever the queue runs dry; when the queue is full, records are
+// dropped rather than slowing redirects down.
+type JSONLinesSink struct {
+	queue   chan ClickRecord
+	done    chan struct{}
+	closer  io.Closer
+	dropped atomic.Int64
+
+	// mu guards closed; Record holds it for reading so Close cannot close
+	// the queue under a send.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	s := &JSONLinesSink{
+		queue: make(chan ClickRecord, analyticsQueueSize),
+		done:  make(chan struct{}),
+	}
+	if closer, ok := w.(io.Closer); ok {
+		s.closer = closer
+	}
+	go s.run(w)
+	return s
+}
+
+// OpenJSONLinesFile appends click records to the file at path.
+func OpenJSONLinesFile(path string) (*JSONLinesSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesSink(file), nil
+}
+
+func (s *JSONLinesSink) run(w io.Writer) {
+	defer close(s.done)
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	for record := range s.queue {
+		if err := encoder.Encode(record); err != nil {
+			log.Printf("Warning: failed to write click record: %v", err)
+		}
+		if len(s.queue) == 0 {
+			buffered.Flush()
+		}
+	}
+	buffered.Flush()
+}
+
+// Record queues record for writing. Records arriving after Close, from
+// click goroutines still running at shutdown, are discarded.
+func (s *JSONLinesSink) Record(record ClickRecord) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.queue <- record:
+	default:
+		if dropped := s.dropped.Add(1); dropped%analyticsQueueSize == 1 {
+			log.Printf("Warning: analytics sink is falling behind, %d click record(s) dropped so far", dropped)
+		}
+	}
+}
+
+// Close writes out every queued record and closes the underlying writer if
+// it is an io.Closer. Closing twice is a no-op.
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// newAnalyticsSink returns config.AnalyticsSink if set, a JSON lines file
+// sink for config.AnalyticsFile, or nil when analytics are off.
+func newAnalyticsSink(config Config) AnalyticsSink {
+	if config.AnalyticsSink != nil {
+		return config.AnalyticsSink
+	}
+	if config.AnalyticsFile == "" {
+		return nil
+	}
+	sink, err := OpenJSONLinesFile(config.AnalyticsFile)
+	if err != nil {
+		log.Printf("Warning: could not open analytics file %s, click analytics disabled: %v", config.AnalyticsFile, err)
+		return nil
+	}
+	return sink
+}
+
+func newClickRecord(mapping *URLMapping, r *http.Request) ClickRecord {
+	return ClickRecord{
+		ShortCode: mapping.ShortCode,
+		Namespace: mapping.Namespace,
+		Timestamp: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// CloseAnalytics flushes and closes the analytics sink, if it can be closed.
+func (us *URLShortener) CloseAnalytics() error {
+	if closer, ok := us.analytics.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
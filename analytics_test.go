@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memorySink hands each record to a channel so tests can wait for it.
+type memorySink chan ClickRecord
+
+func (s memorySink) Record(record ClickRecord) { s <- record }
+
+func nextRecord(t *testing.T, sink memorySink) ClickRecord {
+	t.Helper()
+	select {
+	case record := <-sink:
+		return record
+	case <-time.After(2 * time.Second):
+		t.Fatal("no click record emitted")
+		return ClickRecord{}
+	}
+}
+
+func TestRedirectEmitsClickRecord(t *testing.T) {
+	sink := make(memorySink, 4)
+	us := newTestShortener(t, func(c *Config) {
+		c.TrustProxyHeaders = true
+		c.AnalyticsSink = sink
+	})
+	us.geo = fakeGeo{"81.2.69.142": "GB"}
+	mustCreate(t, us, "https://example.com/tracked", CreateOptions{CustomName: "tracked", Namespace: "team"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/team/tracked", "",
+		"X-Forwarded-For", "81.2.69.142",
+		"Referer", "https://news.example.org/post",
+		"User-Agent", "Mozilla/5.0 (X11; Linux x86_64)"), http.StatusMovedPermanently)
+
+	record := nextRecord(t, sink)
+	if record.ShortCode != "tracked" || record.Namespace != "team" || record.Country != "GB" ||
+		record.Referrer != "https://news.example.org/post" || record.UserAgent != "Mozilla/5.0 (X11; Linux x86_64)" ||
+		time.Since(record.Timestamp) > time.Minute {
+		t.Fatalf("record = %+v", record)
+	}
+
+	// Uncounted requests emit nothing.
+	expectStatus(t, serve(t, us, http.MethodHead, "/team/tracked", ""), http.StatusMovedPermanently)
+	select {
+	case extra := <-sink:
+		t.Fatalf("HEAD emitted %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestJSONLinesSinkWritesOneLinePerRecord(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewJSONLinesSink(&out)
+	sink.Record(ClickRecord{ShortCode: "first", Timestamp: time.Now()})
+	sink.Record(ClickRecord{ShortCode: "second", Country: "US", Timestamp: time.Now()})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2:\n%s", len(lines), out.String())
+	}
+	var second ClickRecord
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.ShortCode != "second" || second.Country != "US" {
+		t.Fatalf("second record = %+v", second)
+	}
+}
+
+func TestJSONLinesSinkDropsRecordsAfterClose(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewJSONLinesSink(&out)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A click goroutine finishing after shutdown must not panic.
+	sink.Record(ClickRecord{ShortCode: "late", Timestamp: time.Now()})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %q after Close", out.String())
+	}
+}
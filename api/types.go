@@ -0,0 +1,239 @@
+package api
+
+import "time"
+
+type CreateURLRequest struct {
+	URL              string `json:"url"`
+	CustomName       string `json:"custom_name,omitempty"`
+	Namespace        string `json:"namespace,omitempty"`
+	ExpiresInSeconds int64  `json:"expires_in_seconds,omitempty"`
+	ExpiresAt        string `json:"expires_at,omitempty"`
+
+	// AppendParams are merged into the destination query on every redirect.
+	// Params already in the destination are kept unless OverwriteParams is set.
+	AppendParams    map[string]string `json:"append_params,omitempty"`
+	OverwriteParams bool              `json:"overwrite_params,omitempty"`
+	CampaignID      string            `json:"campaign_id,omitempty"`
+	CodeLength      int               `json:"code_length,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	DelaySeconds    int               `json:"delay_seconds,omitempty"`
+
+	// SlugFromTitle asks for a readable code derived from the destination
+	// page's <title>. It needs TITLE_SLUGS enabled on the server.
+	SlugFromTitle bool `json:"slug_from_title,omitempty"`
+
+	// FollowRedirects stores the URL the submitted one finally redirects to,
+	// when the server has FOLLOW_REDIRECTS enabled.
+	FollowRedirects bool `json:"follow_redirects,omitempty"`
+
+	// ConfirmToken makes visitors enter this token once per browser session
+	// before the link redirects.
+	ConfirmToken string `json:"confirm_token,omitempty"`
+
+	// RedirectHeaders are extra response headers, such as X-Campaign-ID,
+	// sent with every redirect.
+	RedirectHeaders map[string]string `json:"redirect_headers,omitempty"`
+
+	// DeviceRules send visitors on a "mobile", "tablet" or "desktop"
+	// device to another URL; other visitors go to URL.
+	DeviceRules map[string]string `json:"device_rules,omitempty"`
+
+	// GeoRules send visitors from a country, given as an ISO 3166-1 alpha-2
+	// code or "EU", to another URL. They take precedence over DeviceRules.
+	GeoRules map[string]string `json:"geo_rules,omitempty"`
+
+	// PreviewToken creates the link with the code a ?preview=1 request
+	// returned. The rest of the request must match the preview.
+	PreviewToken string `json:"preview_token,omitempty"`
+
+	// CountsOnly keeps the link's counters but no click history.
+	CountsOnly bool `json:"counts_only,omitempty"`
+}
+
+// CreateURLResponse is the minimal v1 create response. APIVersion is only
+// set when the client explicitly asked for v1, so unversioned callers keep
+// seeing the original shape.
+type CreateURLResponse struct {
+	APIVersion  string `json:"api_version,omitempty"`
+	ShortCode   string `json:"short_code"`
+	Namespace   string `json:"namespace,omitempty"`
+	OriginalURL string `json:"original_url"`
+	ShortURL    string `json:"short_url"`
+}
+
+// PreviewURLResponse answers POST /api/shorten?preview=1. When Exists is
+// false, ShortCode is held until ExpiresAt for a create request carrying
+// PreviewToken; when true, the URL is already shortened as ShortCode.
+type PreviewURLResponse struct {
+	ShortCode    string     `json:"short_code"`
+	Namespace    string     `json:"namespace,omitempty"`
+	OriginalURL  string     `json:"original_url"`
+	ShortURL     string     `json:"short_url"`
+	Exists       bool       `json:"exists"`
+	PreviewToken string     `json:"preview_token,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateURLDetails is the expanded description of a link returned by v2 of
+// the create endpoint.
+type CreateURLDetails struct {
+	ShortCode    string            `json:"short_code"`
+	Namespace    string            `json:"namespace,omitempty"`
+	OriginalURL  string            `json:"original_url"`
+	SubmittedURL string            `json:"submitted_url,omitempty"`
+	ShortURL     string            `json:"short_url"`
+	Created      bool              `json:"created"`
+	CreatedAt    time.Time         `json:"created_at"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	Enabled      bool              `json:"enabled"`
+	Description  string            `json:"description,omitempty"`
+	CampaignID   string            `json:"campaign_id,omitempty"`
+	DelaySeconds int               `json:"delay_seconds,omitempty"`
+	AppendParams map[string]string `json:"append_params,omitempty"`
+	// RedirectHeaders are sent with every redirect of the link.
+	RedirectHeaders map[string]string `json:"redirect_headers,omitempty"`
+	// DeviceRules and GeoRules are the per-device and per-country
+	// destinations of the link.
+	DeviceRules map[string]string `json:"device_rules,omitempty"`
+	GeoRules    map[string]string `json:"geo_rules,omitempty"`
+	// ConfirmRequired reports whether visitors must enter a confirm token.
+	ConfirmRequired bool `json:"confirm_required,omitempty"`
+}
+
+// CreateURLResponseV2 wraps CreateURLDetails in a versioned envelope.
+type CreateURLResponseV2 struct {
+	APIVersion string           `json:"api_version"`
+	Data       CreateURLDetails `json:"data"`
+}
+
+type UpdateURLRequest struct {
+	Enabled     *bool   `json:"enabled,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+type URLStats struct {
+	ShortCode      string     `json:"short_code"`
+	Namespace      string     `json:"namespace,omitempty"`
+	OriginalURL    string     `json:"original_url,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AccessCount    int64      `json:"access_count"`
+	UniqueClicks   int64      `json:"unique_clicks"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Status         string     `json:"status,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	RecentPerMin   int64      `json:"recent_rate_per_min"`
+
+	// RecentClicks is the retained click history, oldest first, and
+	// Retention the limits it is kept to.
+	RecentClicks []time.Time       `json:"recent_clicks,omitempty"`
+	Retention    *HistoryRetention `json:"history_retention,omitempty"`
+
+	// AccessCountLabel is set when counts are rounded for privacy, e.g. "1k+".
+	AccessCountLabel string `json:"access_count_label,omitempty"`
+}
+
+// HistoryRetention describes how much click history a link keeps. A zero
+// limit is no limit; CountsOnly means no history is kept at all.
+type HistoryRetention struct {
+	MaxEvents     int   `json:"max_events,omitempty"`
+	MaxAgeSeconds int64 `json:"max_age_seconds,omitempty"`
+	CountsOnly    bool  `json:"counts_only,omitempty"`
+}
+
+// ErrorResponse is the JSON body of error responses that carry a
+// machine-readable code.
+type ErrorResponse struct {
+	ErrorCode string `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+type BatchStatsRequest struct {
+	ShortCodes []string `json:"short_codes"`
+}
+
+type BatchStatsResult struct {
+	Stats *URLStats `json:"stats,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+type SummaryStats struct {
+	TotalLinks    int        `json:"total_links"`
+	TotalClicks   int64      `json:"total_clicks"`
+	AverageClicks float64    `json:"average_clicks"`
+	CreatedToday  int        `json:"created_today"`
+	LastCreatedAt *time.Time `json:"last_created_at,omitempty"`
+
+	// CharsSaved is the total length of destinations minus the total length of
+	// their short URLs; CompressionRatio is short length over original length.
+	CharsSaved       int64   `json:"chars_saved"`
+	CompressionRatio float64 `json:"compression_ratio"`
+}
+
+type BatchCreateRequest struct {
+	URLs []CreateURLRequest `json:"urls"`
+}
+
+type BatchCreateResult struct {
+	Index     int              `json:"index"`
+	Input     CreateURLRequest `json:"input"`
+	ShortCode string           `json:"short_code,omitempty"`
+	ShortURL  string           `json:"short_url,omitempty"`
+	ErrorCode string           `json:"error_code,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type BatchCreateResponse struct {
+	Results []BatchCreateResult `json:"results"`
+}
+
+type BatchDeleteRequest struct {
+	ShortCodes []string `json:"short_codes"`
+}
+
+type BatchDeleteResult struct {
+	ShortCode string `json:"short_code"`
+	Deleted   bool   `json:"deleted"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchDeleteResponse struct {
+	Results []BatchDeleteResult `json:"results"`
+}
+
+type CreateCampaignRequest struct {
+	Name string `json:"name"`
+}
+
+type CampaignStats struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	TotalLinks   int        `json:"total_links"`
+	TotalClicks  int64      `json:"total_clicks"`
+	UniqueClicks int64      `json:"unique_clicks"`
+	Links        []URLStats `json:"links"`
+}
+
+type StorageStats struct {
+	Links           int    `json:"links"`
+	Tombstones      int    `json:"tombstones"`
+	Campaigns       int    `json:"campaigns"`
+	TrackedVisitors int    `json:"tracked_visitors"`
+	ApproxBytes     int64  `json:"approx_bytes"`
+	HeapAllocBytes  uint64 `json:"heap_alloc_bytes"`
+}
+
+type RebaseRequest struct {
+	BaseURL string `json:"base_url"`
+}
+
+type RebaseResponse struct {
+	BaseURL         string `json:"base_url"`
+	PreviousBaseURL string `json:"previous_base_url"`
+}
+
+type CreateAliasRequest struct {
+	Alias string `json:"alias"`
+}
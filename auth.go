@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+type Principal struct {
+	Owner string
+	Admin bool
+}
+
+type principalContextKey struct{}
+
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func principalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+
+func (us *URLShortener) authEnabled() bool {
+	return len(us.config.APIKeys) > 0 || len(us.config.AdminAPIKeys) > 0
+}
+
+func (us *URLShortener) lookupAPIKey(key string) (Principal, bool) {
+	var (
+		found     Principal
+		matched   bool
+		candidate = []byte(key)
+	)
+	for apiKey, owner := range us.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), candidate) == 1 {
+			found, matched = Principal{Owner: owner}, true
+		}
+	}
+	for apiKey, owner := range us.config.AdminAPIKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), candidate) == 1 {
+			found, matched = Principal{Owner: owner, Admin: true}, true
+		}
+	}
+	return found, matched
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// authMiddleware attaches the calling principal to the request context.
+// Requests without a key pass through anonymously; an unknown key is rejected.
+func (us *URLShortener) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !us.authEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := apiKeyFromRequest(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := us.lookupAPIKey(key)
+		if !ok {
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+// requireAuth rejects anonymous callers when API keys are configured. With auth
+// disabled every caller is treated as an admin, matching the open behavior.
+func (us *URLShortener) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !us.authEnabled() {
+			next(w, r.WithContext(withPrincipal(r.Context(), Principal{Admin: true})))
+			return
+		}
+
+		if _, ok := principalFromContext(r.Context()); !ok {
+			http.Error(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (us *URLShortener) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return us.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := principalFromContext(r.Context())
+		if !principal.Admin {
+			http.Error(w, "Admin API key required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// callerOwns reports whether the caller of r is an admin or the owner named.
+func callerOwns(r *http.Request, owner string) bool {
+	principal, ok := principalFromContext(r.Context())
+	return ok && (principal.Admin || (principal.Owner != "" && principal.Owner == owner))
+}
+
+func ownerFromRequest(r *http.Request) string {
+	principal, _ := principalFromContext(r.Context())
+	return principal.Owner
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestRebaseClosedWithoutAdminKeys(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"https://evil.example"}`)
+	expectStatus(t, rec, http.StatusForbidden)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeAdminRequired {
+		t.Fatalf("error code = %s, want %s", body.ErrorCode, CodeAdminRequired)
+	}
+	if got := us.currentBaseURL(); got != testBaseURL {
+		t.Fatalf("base URL changed to %s", got)
+	}
+
+	// The other admin routes stay open while auth is disabled.
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/admin/storage", ""), http.StatusOK)
+}
+
+func TestAdminRoutesNeedAdminKey(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.APIKeys = map[string]string{"user-key": "alice"}
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+	})
+
+	rec := serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"https://new.example"}`)
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	rec = serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"https://new.example"}`, "X-API-Key", "user-key")
+	expectStatus(t, rec, http.StatusForbidden)
+
+	rec = serve(t, us, http.MethodPost, "/api/admin/rebase", `{"base_url":"https://new.example"}`, "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	if got := us.currentBaseURL(); got != "https://new.example" {
+		t.Fatalf("base URL = %s, want https://new.example", got)
+	}
+}
+
+func TestOwnersOnlySeeTheirOwnLinks(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.APIKeys = map[string]string{"alice-key": "alice", "bob-key": "bob"}
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+	})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/alice","custom_name":"alices"}`, "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusCreated)
+	if owner := us.storage["alices"].Owner; owner != "alice" {
+		t.Fatalf("owner = %q, want alice", owner)
+	}
+
+	listed := func(key, query string) []URLMapping {
+		t.Helper()
+		rec := serve(t, us, http.MethodGet, "/api/urls"+query, "", "X-API-Key", key)
+		expectStatus(t, rec, http.StatusOK)
+		var urls []URLMapping
+		decodeBody(t, rec, &urls)
+		return urls
+	}
+	if urls := listed("alice-key", ""); len(urls) != 1 || urls[0].ShortCode != "alices" {
+		t.Fatalf("alice lists %+v", urls)
+	}
+	if urls := listed("bob-key", ""); len(urls) != 0 {
+		t.Fatalf("bob lists %+v", urls)
+	}
+	if urls := listed("bob-key", "?all=true"); len(urls) != 0 {
+		t.Fatalf("bob lists %+v with all=true", urls)
+	}
+	if urls := listed("admin-key", "?all=true"); len(urls) != 1 {
+		t.Fatalf("admin lists %+v with all=true", urls)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/urls/alices", "", "X-API-Key", "bob-key"), http.StatusNotFound)
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/alices", "", "X-API-Key", "bob-key"), http.StatusNotFound)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/urls/alices", "", "X-API-Key", "alice-key"), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/alices", "", "X-API-Key", "admin-key"), http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	unavailableInvalid  = "invalid"
+	unavailableReserved = "reserved"
+	unavailableTaken    = "taken"
+)
+
+// CodeAvailability reports whether a custom code could be created right now
+// in namespace and, if not, why. It applies the same validation,
+// canonicalization and reserved list as CreateShortURL.
+func (us *URLShortener) CodeAvailability(namespace, code string) (bool, string) {
+	if !isValidCustomName(code) || (namespace != "" && (!isValidNamespace(namespace) || us.reservedPaths[namespace])) {
+		return false, unavailableInvalid
+	}
+	if us.isReservedCode(code) {
+		return false, unavailableReserved
+	}
+
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	if us.codeTakenLocked(storageKey(namespace, us.canonicalCode(code))) {
+		return false, unavailableTaken
+	}
+	return true, ""
+}
+
+func (us *URLShortener) availabilityHandler(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	available, reason := us.CodeAvailability(r.URL.Query().Get("namespace"), code)
+
+	response := map[string]interface{}{
+		"code":      code,
+		"available": available,
+	}
+	status := http.StatusOK
+	if reason != "" {
+		response["reason"] = reason
+	}
+	if reason == unavailableInvalid {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, response, us.jsonOptions(r))
+}
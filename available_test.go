@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+type availability struct {
+	Code      string `json:"code"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason"`
+}
+
+func checkAvailability(t *testing.T, us *URLShortener, target string, wantStatus int) availability {
+	t.Helper()
+	rec := serve(t, us, http.MethodGet, target, "")
+	expectStatus(t, rec, wantStatus)
+	var got availability
+	decodeBody(t, rec, &got)
+	return got
+}
+
+func TestCodeAvailability(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ReservedCodes = []string{"promo"} })
+	mustCreate(t, us, "https://example.com/taken", CreateOptions{CustomName: "taken"})
+	mustCreate(t, us, "https://example.com/team", CreateOptions{CustomName: "taken", Namespace: "team"})
+
+	for _, tt := range []struct {
+		target     string
+		wantStatus int
+		available  bool
+		reason     string
+	}{
+		{"/api/available/freebie", http.StatusOK, true, ""},
+		{"/api/available/taken", http.StatusOK, false, unavailableTaken},
+		{"/api/available/Taken", http.StatusOK, true, ""},
+		{"/api/available/promo", http.StatusOK, false, unavailableReserved},
+		{"/api/available/admin", http.StatusOK, false, unavailableReserved},
+		{"/api/available/bad!code", http.StatusBadRequest, false, unavailableInvalid},
+		{"/api/available/ab", http.StatusBadRequest, false, unavailableInvalid},
+		{"/api/available/taken?namespace=team", http.StatusOK, false, unavailableTaken},
+		{"/api/available/taken?namespace=other", http.StatusOK, true, ""},
+	} {
+		got := checkAvailability(t, us, tt.target, tt.wantStatus)
+		if got.Available != tt.available || got.Reason != tt.reason {
+			t.Errorf("%s = %+v, want available=%v reason=%q", tt.target, got, tt.available, tt.reason)
+		}
+	}
+
+	// The answer matches what creation would do.
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/new","custom_name":"freebie"}`), http.StatusCreated)
+	if got := checkAvailability(t, us, "/api/available/freebie", http.StatusOK); got.Available {
+		t.Fatal("code still available after creating it")
+	}
+}
+
+func TestCodeAvailabilityCaseInsensitive(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CaseInsensitiveCodes = true })
+	mustCreate(t, us, "https://example.com/taken", CreateOptions{CustomName: "taken"})
+
+	if got := checkAvailability(t, us, "/api/available/TAKEN", http.StatusOK); got.Available || got.Reason != unavailableTaken {
+		t.Fatalf("TAKEN = %+v, want taken", got)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"url-shortener/api"
+)
+
+// CreateShortURLsBatch creates each item independently so one bad entry does
+// not fail the whole batch. Results are returned in input order.
+func (us *URLShortener) CreateShortURLsBatch(ctx context.Context, items []api.CreateURLRequest, owner, baseURL string) []api.BatchCreateResult {
+	results := make([]api.BatchCreateResult, len(items))
+	for i, item := range items {
+		result := api.BatchCreateResult{Index: i, Input: item}
+
+		mapping, _, err := us.createFromRequest(ctx, item, owner)
+		if err != nil {
+			result.ErrorCode = errorCode(err)
+			result.Error = err.Error()
+		} else {
+			result.ShortCode = mapping.ShortCode
+			result.ShortURL = fmt.Sprintf("%s/%s", baseURL, mapping.path())
+		}
+
+		results[i] = result
+	}
+	return results
+}
+
+func (us *URLShortener) batchCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.BatchCreateRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		us.writeJSONError(w, r, http.StatusBadRequest, errors.New("urls is required and cannot be empty"))
+		return
+	}
+	if len(req.URLs) > us.config.MaxBatchSize {
+		us.writeJSONError(w, r, http.StatusBadRequest, fmt.Errorf("Batch size %d exceeds the maximum of %d", len(req.URLs), us.config.MaxBatchSize))
+		return
+	}
+
+	results := us.CreateShortURLsBatch(r.Context(), req.URLs, ownerFromRequest(r), us.publicBaseURL(r))
+
+	failed := 0
+	for _, result := range results {
+		if result.ErrorCode != "" {
+			failed++
+		}
+	}
+
+	status := http.StatusOK
+	switch {
+	case failed == len(results):
+		status = http.StatusBadRequest
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+
+	writeJSON(w, status, api.BatchCreateResponse{Results: results}, us.jsonOptions(r))
+}
+
+// DeleteURLsBatch deletes each code under a single write lock, reporting a
+// result per code in input order.
+func (us *URLShortener) DeleteURLsBatch(shortCodes []string, principal Principal) []api.BatchDeleteResult {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	now := time.Now()
+	results := make([]api.BatchDeleteResult, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		result := api.BatchDeleteResult{ShortCode: shortCode, Deleted: true}
+		if err := us.deleteLocked(shortCode, principal, now); err != nil {
+			result.Deleted = false
+			result.ErrorCode = errorCode(err)
+			result.Error = err.Error()
+		}
+		results[i] = result
+	}
+	return results
+}
+
+func (us *URLShortener) batchDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.BatchDeleteRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.ShortCodes) == 0 {
+		us.writeJSONError(w, r, http.StatusBadRequest, errors.New("short_codes is required and cannot be empty"))
+		return
+	}
+	if len(req.ShortCodes) > us.config.MaxBatchSize {
+		us.writeJSONError(w, r, http.StatusBadRequest, fmt.Errorf("Batch size %d exceeds the maximum of %d", len(req.ShortCodes), us.config.MaxBatchSize))
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	results := us.DeleteURLsBatch(req.ShortCodes, principal)
+
+	status := http.StatusOK
+	for _, result := range results {
+		if !result.Deleted {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+
+	writeJSON(w, status, api.BatchDeleteResponse{Results: results}, us.jsonOptions(r))
+}
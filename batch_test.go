@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestBatchStatsMixesFoundAndMissing(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/one", CreateOptions{CustomName: "one"})
+	mustCreate(t, us, "https://example.com/two", CreateOptions{CustomName: "two"})
+
+	rec := serve(t, us, http.MethodPost, "/api/stats/batch", `{"short_codes":["one","two","nope"]}`)
+	expectStatus(t, rec, http.StatusOK)
+	var results map[string]api.BatchStatsResult
+	decodeBody(t, rec, &results)
+
+	for _, code := range []string{"one", "two"} {
+		if result := results[code]; result.Stats == nil || result.Stats.ShortCode != code || result.Error != "" {
+			t.Errorf("%s: %+v", code, result)
+		}
+	}
+	if result := results["nope"]; result.Stats != nil || result.Error == "" {
+		t.Errorf("nope: %+v, want an error", result)
+	}
+}
+
+func TestBatchStatsRejectsOversizedBatches(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxBatchSize = 2 })
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/stats/batch", `{"short_codes":["a","b","c"]}`), http.StatusBadRequest)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/stats/batch", `{"short_codes":[]}`), http.StatusBadRequest)
+}
+
+func TestBatchCreateReportsEachItem(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten/batch",
+		`{"urls":[{"url":"https://example.com/ok"},{"url":""},{"url":"not a url"}]}`)
+	expectStatus(t, rec, http.StatusMultiStatus)
+	var body api.BatchCreateResponse
+	decodeBody(t, rec, &body)
+	if len(body.Results) != 3 {
+		t.Fatalf("%d results, want 3", len(body.Results))
+	}
+
+	ok := body.Results[0]
+	if ok.Index != 0 || ok.ErrorCode != "" || ok.ShortCode == "" || ok.ShortURL != testBaseURL+"/"+ok.ShortCode {
+		t.Errorf("valid item: %+v", ok)
+	}
+	for i, want := range []string{CodeEmptyURL, CodeInvalidURL} {
+		result := body.Results[i+1]
+		if result.Index != i+1 || result.ErrorCode != want || result.Error == "" || result.ShortCode != "" {
+			t.Errorf("item %d: %+v, want error code %s", i+1, result, want)
+		}
+	}
+	if body.Results[2].Input.URL != "not a url" {
+		t.Errorf("input not echoed: %+v", body.Results[2].Input)
+	}
+}
+
+func TestBatchCreateStatus(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten/batch",
+		`{"urls":[{"url":"https://example.com/a"},{"url":"https://example.com/b"}]}`), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten/batch",
+		`{"urls":[{"url":""},{"url":"nope"}]}`), http.StatusBadRequest)
+}
+
+func TestBatchDeleteMixesExistingAndMissing(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/one", CreateOptions{CustomName: "gone1"})
+	mustCreate(t, us, "https://example.com/two", CreateOptions{CustomName: "gone2"})
+	mustCreate(t, us, "https://example.com/keep", CreateOptions{CustomName: "keeper"})
+
+	rec := serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":["gone1","nosuch","gone2","gone1"]}`)
+	expectStatus(t, rec, http.StatusMultiStatus)
+	var resp api.BatchDeleteResponse
+	decodeBody(t, rec, &resp)
+
+	want := []struct {
+		code    string
+		deleted bool
+	}{{"gone1", true}, {"nosuch", false}, {"gone2", true}, {"gone1", false}}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.ShortCode != w.code || got.Deleted != w.deleted {
+			t.Fatalf("result %d = %+v, want %s deleted=%v", i, got, w.code, w.deleted)
+		}
+		if !w.deleted && got.ErrorCode != CodeNotFound {
+			t.Fatalf("result %d error code = %q", i, got.ErrorCode)
+		}
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/gone1", ""), http.StatusGone)
+	expectStatus(t, serve(t, us, http.MethodGet, "/keeper", ""), http.StatusMovedPermanently)
+
+	// The deleted URL is no longer found by duplicate detection.
+	again := mustCreate(t, us, "https://example.com/one", CreateOptions{})
+	if again.ShortCode == "gone1" {
+		t.Fatal("shortening a deleted URL returned the deleted code")
+	}
+}
+
+func TestBatchDeleteAllSucceed(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/one", CreateOptions{CustomName: "first"})
+	mustCreate(t, us, "https://example.com/two", CreateOptions{CustomName: "second"})
+
+	rec := serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":["first","second"]}`)
+	expectStatus(t, rec, http.StatusOK)
+	if got := countLinksTo(us, "https://example.com/one") + countLinksTo(us, "https://example.com/two"); got != 0 {
+		t.Fatalf("%d links left after deleting both", got)
+	}
+}
+
+func TestBatchDeleteValidatesRequest(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxBatchSize = 2 })
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":["a","b","c"]}`), http.StatusBadRequest)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":[]}`), http.StatusBadRequest)
+}
+
+func TestBatchDeleteOnlyRemovesOwnLinks(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.APIKeys = map[string]string{"alice-key": "alice", "bob-key": "bob"} })
+	mustCreate(t, us, "https://example.com/a", CreateOptions{CustomName: "alices", Owner: "alice"})
+	mustCreate(t, us, "https://example.com/b", CreateOptions{CustomName: "bobs", Owner: "bob"})
+
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":["alices"]}`), http.StatusUnauthorized)
+
+	rec := serve(t, us, http.MethodPost, "/api/urls/delete-batch", `{"short_codes":["alices","bobs"]}`, "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusMultiStatus)
+	expectStatus(t, serve(t, us, http.MethodGet, "/bobs", ""), http.StatusMovedPermanently)
+}
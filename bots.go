@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// defaultBotUserAgents matches common crawlers and link-preview fetchers.
+const defaultBotUserAgents = `(?i)(bot|crawler|spider|slurp|facebookexternalhit|embedly|quora link preview|whatsapp|skypeuripreview|bitlybot|vkshare|redditbot|applebot|headlesschrome)`
+
+func compileBotPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = defaultBotUserAgents
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Printf("Warning: invalid BOT_USER_AGENTS pattern, using the default: %v", err)
+		return regexp.MustCompile(defaultBotUserAgents)
+	}
+	return compiled
+}
+
+// countsAsClick reports whether a redirect should be counted. HEAD requests
+// never count, and neither do crawlers unless CountBots is set.
+func (us *URLShortener) countsAsClick(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if us.config.CountBots {
+		return true
+	}
+	return !us.botPattern.MatchString(r.UserAgent())
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+const (
+	browserUA = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15"
+	slackUA   = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+)
+
+func TestCrawlerRedirectsWithoutCounting(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/share", CreateOptions{CustomName: "shared"})
+
+	for _, ua := range []string{slackUA, "facebookexternalhit/1.1", "Twitterbot/1.0"} {
+		rec := serve(t, us, http.MethodGet, "/shared", "", "User-Agent", ua)
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != "https://example.com/share" {
+			t.Fatalf("%s redirected to %q", ua, got)
+		}
+	}
+	if got := accessCount(t, us, "shared"); got != 0 {
+		t.Fatalf("access count after crawler hits = %d, want 0", got)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/shared", "", "User-Agent", browserUA), http.StatusMovedPermanently)
+	if got := accessCount(t, us, "shared"); got != 1 {
+		t.Fatalf("access count after a browser hit = %d, want 1", got)
+	}
+}
+
+func TestCountBotsCountsCrawlers(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CountBots = true })
+	mustCreate(t, us, "https://example.com/share", CreateOptions{CustomName: "shared"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/shared", "", "User-Agent", slackUA), http.StatusMovedPermanently)
+	if got := accessCount(t, us, "shared"); got != 1 {
+		t.Fatalf("access count = %d, want 1", got)
+	}
+}
+
+func TestCustomBotUserAgents(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.BotUserAgents = `(?i)^monitor/` })
+	mustCreate(t, us, "https://example.com/share", CreateOptions{CustomName: "shared"})
+
+	serve(t, us, http.MethodGet, "/shared", "", "User-Agent", "Monitor/2.0")
+	serve(t, us, http.MethodGet, "/shared", "", "User-Agent", slackUA)
+	if got := accessCount(t, us, "shared"); got != 1 {
+		t.Fatalf("access count = %d, want only the Slackbot hit counted", got)
+	}
+}
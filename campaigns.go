@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"url-shortener/api"
+)
+
+const maxCampaignNameLength = 100
+
+// Campaign groups links so their clicks can be reported together.
+type Campaign struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newCampaignID() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return "cmp_" + hex.EncodeToString(buf)
+}
+
+func (us *URLShortener) CreateCampaign(name, owner string) (*Campaign, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxCampaignNameLength {
+		return nil, errorf(ErrInvalidCampaign, "campaign name is required and must be at most %d characters", maxCampaignNameLength)
+	}
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	campaign := &Campaign{Name: name, Owner: owner, CreatedAt: time.Now()}
+	for {
+		campaign.ID = newCampaignID()
+		if _, exists := us.campaigns[campaign.ID]; !exists {
+			break
+		}
+	}
+	us.campaigns[campaign.ID] = campaign
+	return campaign, nil
+}
+
+// checkCampaignLocked verifies that a link created by owner may join the
+// campaign. Callers must hold the lock.
+func (us *URLShortener) checkCampaignLocked(campaignID, owner string) error {
+	if campaignID == "" {
+		return nil
+	}
+	campaign, exists := us.campaigns[campaignID]
+	if !exists || (campaign.Owner != "" && campaign.Owner != owner) {
+		return errorf(ErrCampaignNotFound, "campaign '%s' does not exist", campaignID)
+	}
+	return nil
+}
+
+// GetCampaignStats sums clicks across the campaign's live links and returns
+// a per-link breakdown, busiest first. Only the campaign's owner or an admin
+// may see it; counts are rounded as for /api/stats.
+func (us *URLShortener) GetCampaignStats(r *http.Request, campaignID string) (api.CampaignStats, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	campaign, exists := us.campaigns[campaignID]
+	principal, _ := principalFromContext(r.Context())
+	if !exists || !(principal.Admin || (campaign.Owner != "" && campaign.Owner == principal.Owner)) {
+		return api.CampaignStats{}, ErrCampaignNotFound
+	}
+
+	stats := api.CampaignStats{
+		ID:        campaign.ID,
+		Name:      campaign.Name,
+		CreatedAt: campaign.CreatedAt,
+		Links:     []api.URLStats{},
+	}
+	now := time.Now()
+	exact := true
+	for _, mapping := range us.storage {
+		if mapping.CampaignID != campaignID || mapping.status(now) != StatusActive {
+			continue
+		}
+		linkStats := us.toURLStats(mapping)
+		stats.TotalLinks++
+		stats.TotalClicks += linkStats.AccessCount
+		stats.UniqueClicks += linkStats.UniqueClicks
+		stats.Links = append(stats.Links, us.publicURLStats(r, mapping))
+		exact = exact && us.seesExactCounts(r, mapping)
+	}
+	if !exact {
+		stats.TotalClicks, _ = roundCount(stats.TotalClicks, us.config.StatsPrecision)
+		stats.UniqueClicks, _ = roundCount(stats.UniqueClicks, us.config.StatsPrecision)
+	}
+	sort.Slice(stats.Links, func(i, j int) bool {
+		if stats.Links[i].AccessCount != stats.Links[j].AccessCount {
+			return stats.Links[i].AccessCount > stats.Links[j].AccessCount
+		}
+		return stats.Links[i].ShortCode < stats.Links[j].ShortCode
+	})
+	return stats, nil
+}
+
+func (us *URLShortener) createCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateCampaignRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	campaign, err := us.CreateCampaign(req.Name, ownerFromRequest(r))
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Printf("Created campaign '%s' (%s)", campaign.ID, campaign.Name)
+	w.Header().Set("Location", "/api/campaigns/"+campaign.ID+"/stats")
+	writeJSON(w, http.StatusCreated, campaign, us.jsonOptions(r))
+}
+
+func (us *URLShortener) campaignStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := us.GetCampaignStats(r, mux.Vars(r)["id"])
+	if errors.Is(err, ErrCampaignNotFound) {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrCampaignNotFound, "Campaign not found"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats, us.jsonOptions(r))
+}
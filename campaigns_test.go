@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func newCampaignTestShortener(t *testing.T) *URLShortener {
+	return newTestShortener(t, func(c *Config) {
+		c.APIKeys = map[string]string{"alice-key": "alice", "bob-key": "bob"}
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+		c.StatsPrecision = 2
+	})
+}
+
+func TestCampaignStatsOwnerOrAdminOnly(t *testing.T) {
+	us := newCampaignTestShortener(t)
+	campaign, err := us.CreateCampaign("launch", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, us, "https://example.com/a", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+	path := "/api/campaigns/" + campaign.ID + "/stats"
+
+	expectStatus(t, serve(t, us, http.MethodGet, path, ""), http.StatusUnauthorized)
+	expectStatus(t, serve(t, us, http.MethodGet, path, "", "X-API-Key", "bob-key"), http.StatusNotFound)
+	expectStatus(t, serve(t, us, http.MethodGet, path, "", "X-API-Key", "alice-key"), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodGet, path, "", "X-API-Key", "admin-key"), http.StatusOK)
+}
+
+func TestCampaignStatsSkipsExpiredLinks(t *testing.T) {
+	us := newCampaignTestShortener(t)
+	campaign, _ := us.CreateCampaign("launch", "alice")
+	live := mustCreate(t, us, "https://example.com/live", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+	expired := mustCreate(t, us, "https://example.com/old", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+
+	us.mutex.Lock()
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	expired.AccessCount = 50
+	us.mutex.Unlock()
+
+	rec := serve(t, us, http.MethodGet, "/api/campaigns/"+campaign.ID+"/stats", "", "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.CampaignStats
+	decodeBody(t, rec, &stats)
+	if stats.TotalLinks != 1 || len(stats.Links) != 1 || stats.Links[0].ShortCode != live.ShortCode {
+		t.Fatalf("links = %+v, want only %s", stats.Links, live.ShortCode)
+	}
+	if stats.TotalClicks != 0 {
+		t.Fatalf("total clicks = %d, want 0", stats.TotalClicks)
+	}
+}
+
+func TestCampaignStatsRoundsOthersLinks(t *testing.T) {
+	us := newCampaignTestShortener(t)
+	campaign, _ := us.CreateCampaign("launch", "alice")
+	mapping := mustCreate(t, us, "https://example.com/a", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+
+	us.mutex.Lock()
+	mapping.AccessCount = 123
+	mapping.Owner = "carol"
+	us.mutex.Unlock()
+
+	rec := serve(t, us, http.MethodGet, "/api/campaigns/"+campaign.ID+"/stats", "", "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.CampaignStats
+	decodeBody(t, rec, &stats)
+	if stats.TotalClicks != 120 || stats.Links[0].AccessCount != 120 {
+		t.Fatalf("total = %d, link = %d, want both rounded to 120", stats.TotalClicks, stats.Links[0].AccessCount)
+	}
+
+	rec = serve(t, us, http.MethodGet, "/api/campaigns/"+campaign.ID+"/stats", "", "X-API-Key", "admin-key")
+	decodeBody(t, rec, &stats)
+	if stats.TotalClicks != 123 {
+		t.Fatalf("admin total = %d, want exact 123", stats.TotalClicks)
+	}
+}
+
+func TestCampaignStatsAggregateLinks(t *testing.T) {
+	us := newCampaignTestShortener(t)
+	rec := serve(t, us, http.MethodPost, "/api/campaigns", `{"name":"spring"}`, "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusCreated)
+	var campaign Campaign
+	decodeBody(t, rec, &campaign)
+
+	a := mustCreate(t, us, "https://example.com/a", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+	b := mustCreate(t, us, "https://example.com/b", CreateOptions{Owner: "alice", CampaignID: campaign.ID})
+	mustCreate(t, us, "https://example.com/other", CreateOptions{Owner: "alice"})
+	us.mutex.Lock()
+	a.AccessCount, b.AccessCount = 3, 4
+	us.mutex.Unlock()
+
+	rec = serve(t, us, http.MethodGet, "/api/campaigns/"+campaign.ID+"/stats", "", "X-API-Key", "alice-key")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.CampaignStats
+	decodeBody(t, rec, &stats)
+	if stats.TotalLinks != 2 || stats.TotalClicks != 7 || len(stats.Links) != 2 {
+		t.Fatalf("stats = %+v, want 2 links and 7 clicks", stats)
+	}
+
+	if _, _, err := us.CreateShortURL("https://example.com/c", CreateOptions{Owner: "alice", CampaignID: "no-such-campaign"}); err == nil {
+		t.Fatal("created a link in a campaign that does not exist")
+	}
+}
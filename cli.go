@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+const defaultSnapshotPath = "quicklink.json"
+
+// isCLICommand reports whether the arguments name a CLI subcommand rather
+// than starting the server.
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "shorten", "resolve":
+		return true
+	}
+	return false
+}
+
+// runCLI executes a subcommand against the snapshot file, without starting the
+// server, and returns the process exit code.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	command := args[0]
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	snapshotPath := defaultSnapshotPath
+	if envPath := os.Getenv("SNAPSHOT_PATH"); envPath != "" {
+		snapshotPath = envPath
+	}
+	flags.StringVar(&snapshotPath, "snapshot", snapshotPath, "snapshot file to read and write")
+	verbose := flags.Bool("v", false, "log store activity to stderr")
+	customName := flags.String("custom", "", "custom short code (shorten only)")
+	namespace := flags.String("namespace", "", "namespace for the short code")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "usage: quicklink %s [flags] <%s>\n", command, map[string]string{"shorten": "url", "resolve": "code"}[command])
+		return 2
+	}
+
+	if !*verbose {
+		previous := log.Writer()
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(previous)
+	}
+
+	config := LoadConfig("8080")
+	config.Validate()
+	us := NewURLShortener(config)
+	if err := us.LoadSnapshot(snapshotPath); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "shorten":
+		mapping, _, err := us.CreateShortURL(flags.Arg(0), CreateOptions{CustomName: *customName, Namespace: *namespace})
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := us.SaveSnapshot(snapshotPath); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, mapping.path())
+	case "resolve":
+		mapping, err := us.LookupURL(storageKey(*namespace, flags.Arg(0)))
+		if errors.Is(err, ErrNotFound) {
+			fmt.Fprintf(stderr, "Error: short code '%s' not found\n", flags.Arg(0))
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, mapping.OriginalURL)
+	}
+	return 0
+}
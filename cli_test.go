@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLIForTest(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = runCLI(args, &out, &errOut)
+	return code, strings.TrimSpace(out.String()), errOut.String()
+}
+
+func TestCLIShortenThenResolve(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "links.json")
+
+	code, shortCode, stderr := runCLIForTest(t, "shorten", "-snapshot", snapshot, "https://example.com/cli")
+	if code != 0 || shortCode == "" {
+		t.Fatalf("shorten exited %d with %q: %s", code, shortCode, stderr)
+	}
+
+	code, url, stderr := runCLIForTest(t, "resolve", "-snapshot", snapshot, shortCode)
+	if code != 0 || url != "https://example.com/cli" {
+		t.Fatalf("resolve exited %d with %q: %s", code, url, stderr)
+	}
+
+	code, custom, _ := runCLIForTest(t, "shorten", "-snapshot", snapshot, "-custom", "mine", "-namespace", "d", "https://example.com/docs")
+	if code != 0 || custom != "d/mine" {
+		t.Fatalf("shorten -custom exited %d with %q", code, custom)
+	}
+	if code, url, _ := runCLIForTest(t, "resolve", "-snapshot", snapshot, "-namespace", "d", "mine"); code != 0 || url != "https://example.com/docs" {
+		t.Fatalf("resolve -namespace exited %d with %q", code, url)
+	}
+}
+
+func TestCLIErrors(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "links.json")
+
+	if code, _, stderr := runCLIForTest(t, "resolve", "-snapshot", snapshot, "nope12"); code != 1 || !strings.Contains(stderr, "not found") {
+		t.Fatalf("unknown code: exit %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCLIForTest(t, "shorten", "-snapshot", snapshot, "not a url"); code != 1 {
+		t.Fatalf("invalid URL: exit %d, want 1", code)
+	}
+	if code, _, stderr := runCLIForTest(t, "shorten", "-snapshot", snapshot); code != 2 || !strings.Contains(stderr, "usage:") {
+		t.Fatalf("missing argument: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestIsCLICommand(t *testing.T) {
+	for args, want := range map[string]bool{"shorten": true, "resolve": true, "serve": false, "": false} {
+		var list []string
+		if args != "" {
+			list = []string{args}
+		}
+		if got := isCLICommand(list); got != want {
+			t.Errorf("isCLICommand(%q) = %v, want %v", list, got, want)
+		}
+	}
+}
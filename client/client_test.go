@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/shorten", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req api.CreateURLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(api.CreateURLResponse{ShortCode: "abc123", OriginalURL: req.URL, ShortURL: "http://sho.rt/abc123"})
+	})
+	mux.HandleFunc("/api/stats/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stats/abc123" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(api.ErrorResponse{ErrorCode: "NOT_FOUND", Error: "Short URL not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(api.URLStats{ShortCode: "abc123", AccessCount: 7})
+	})
+	mux.HandleFunc("/abc123", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/dest", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/api/urls/abc123", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	server := newTestServer(t)
+	c := New(server.URL+"/", WithAPIKey("secret"))
+	ctx := context.Background()
+
+	created, err := c.CreateShortURL(ctx, api.CreateURLRequest{URL: "https://example.com/dest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ShortCode != "abc123" || created.OriginalURL != "https://example.com/dest" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	stats, err := c.GetStats(ctx, "abc123")
+	if err != nil || stats.AccessCount != 7 {
+		t.Fatalf("stats = %+v, err = %v", stats, err)
+	}
+
+	destination, err := c.Resolve(ctx, "abc123")
+	if err != nil || destination != "https://example.com/dest" {
+		t.Fatalf("resolve = %q, err = %v", destination, err)
+	}
+
+	if err := c.Delete(ctx, "abc123"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := New(server.URL).GetStats(ctx, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Short URL not found" || apiErr.Code != "NOT_FOUND" {
+		t.Fatalf("err = %#v, want the server's message and code", err)
+	}
+
+	_, err = New(server.URL).CreateShortURL(ctx, api.CreateURLRequest{URL: "https://example.com"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "" {
+		t.Fatalf("err = %v, want a 401 APIError without a code", err)
+	}
+}
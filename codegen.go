@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// CodeGenerator produces candidate short codes for links created without a
+// custom name. Candidates that are already taken or reserved are discarded
+// and Generate is called again, so a generator need not avoid collisions
+// itself, but every code it returns must be a valid short code.
+type CodeGenerator interface {
+	Generate() string
+}
+
+// maxCodeAttempts bounds how many generated codes are tried for one link, so
+// a nearly used-up code space or a generator that keeps repeating itself
+// fails the create instead of spinning under the store lock.
+const maxCodeAttempts = 1000
+
+const (
+	CodeSchemeRandom        = "random"
+	CodeSchemeWords         = "words"
+	CodeSchemeDeterministic = "deterministic"
+)
+
+const (
+	mixedCaseCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	lowerCaseCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// RandomCodeGenerator produces Length characters drawn uniformly from Charset.
+// It is the default generator.
+type RandomCodeGenerator struct {
+	Length  int
+	Charset string
+}
+
+func (g RandomCodeGenerator) Generate() string {
+	result := make([]byte, g.Length)
+	for i := range result {
+		result[i] = g.Charset[randomIndex(len(g.Charset))]
+	}
+	return string(result)
+}
+
+// WordsCodeGenerator joins Words words from a small built-in list with
+// hyphens, giving codes such as "brave-otter". Words is clamped to 1-3 so the
+// result always fits the short code length limit.
+type WordsCodeGenerator struct {
+	Words int
+}
+
+func (g WordsCodeGenerator) Generate() string {
+	n := min(max(g.Words, 1), 3)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = codeWords[randomIndex(len(codeWords))]
+	}
+	return strings.Join(words, "-")
+}
+
+// DeterministicCodeGenerator derives a link's code from its destination:
+// Length characters of Charset encoding the HMAC-SHA256 of the normalized URL
+// keyed with Salt. Instances sharing a salt give a URL the same code without
+// sharing storage; without the salt, codes cannot be predicted from URLs.
+type DeterministicCodeGenerator struct {
+	Length  int
+	Charset string
+	Salt    string
+}
+
+// CodeFor returns the code for url. Attempt 0 is the plain hash; later
+// attempts add a "-<attempt>" suffix, for the rare code that collides with
+// a different link.
+func (g DeterministicCodeGenerator) CodeFor(url string, attempt int) string {
+	mac := hmac.New(sha256.New, []byte(g.Salt))
+	mac.Write([]byte(url))
+	sum := new(big.Int).SetBytes(mac.Sum(nil))
+
+	base := big.NewInt(int64(len(g.Charset)))
+	digit := new(big.Int)
+	code := make([]byte, g.Length)
+	for i := range code {
+		sum.DivMod(sum, base, digit)
+		code[i] = g.Charset[digit.Int64()]
+	}
+	if attempt == 0 {
+		return string(code)
+	}
+
+	suffix := "-" + strconv.Itoa(attempt)
+	return string(code[:min(len(code), maxShortCodeLength-len(suffix))]) + suffix
+}
+
+// Generate returns a random code, for callers that have no URL to hash, such
+// as rotation to a new code.
+func (g DeterministicCodeGenerator) Generate() string {
+	return RandomCodeGenerator{Length: g.Length, Charset: g.Charset}.Generate()
+}
+
+// codeWords are short, common, inoffensive words. None is longer than six
+// letters, so three of them and two hyphens stay within maxShortCodeLength.
+var codeWords = []string{
+	"amber", "apple", "arrow", "badge", "basil", "beach", "birch", "bison",
+	"brave", "brick", "brook", "cabin", "camel", "candle", "cedar", "chalk",
+	"cider", "cloud", "clover", "comet", "coral", "crane", "daisy", "delta",
+	"dune", "eagle", "ember", "fable", "falcon", "fern", "flint", "frost",
+	"garnet", "ginger", "glade", "grove", "harbor", "hazel", "heron", "honey",
+	"horse", "iris", "ivory", "jade", "kettle", "koala", "lemon", "lilac",
+	"linen", "lotus", "maple", "meadow", "mint", "north", "oasis", "ocean",
+	"olive", "orbit", "otter", "pebble", "pepper", "pine", "plum", "quartz",
+	"quill", "raven", "river", "robin", "sable", "sage", "shell", "silver",
+	"spruce", "stone", "summit", "swift", "tiger", "topaz", "trail", "tulip",
+	"velvet", "violet", "walnut", "willow", "winter", "zebra", "zephyr",
+}
+
+func randomIndex(n int) int {
+	num, _ := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	return int(num.Int64())
+}
+
+func codeCharset(caseInsensitive bool) string {
+	if caseInsensitive {
+		return lowerCaseCharset
+	}
+	return mixedCaseCharset
+}
+
+// newCodeGenerator returns config.CodeGenerator if set, otherwise the built-in
+// generator named by config.CodeScheme.
+func newCodeGenerator(config Config) CodeGenerator {
+	if config.CodeGenerator != nil {
+		return config.CodeGenerator
+	}
+	switch config.CodeScheme {
+	case CodeSchemeWords:
+		return WordsCodeGenerator{Words: 2}
+	case CodeSchemeDeterministic:
+		return DeterministicCodeGenerator{Length: config.CodeLength, Charset: codeCharset(config.CaseInsensitiveCodes), Salt: config.CodeSalt}
+	}
+	return RandomCodeGenerator{Length: config.CodeLength, Charset: codeCharset(config.CaseInsensitiveCodes)}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"url-shortener/api"
+)
+
+// sequenceGenerator hands out codes in order, repeating the last one.
+type sequenceGenerator struct {
+	codes []string
+	calls int
+}
+
+func (g *sequenceGenerator) Generate() string {
+	code := g.codes[min(g.calls, len(g.codes)-1)]
+	g.calls++
+	return code
+}
+
+func TestRandomCodeGenerator(t *testing.T) {
+	gen := RandomCodeGenerator{Length: 8, Charset: lowerCaseCharset}
+	for i := 0; i < 20; i++ {
+		code := gen.Generate()
+		if len(code) != 8 || strings.Trim(code, lowerCaseCharset) != "" {
+			t.Fatalf("Generate() = %q, want 8 characters from the charset", code)
+		}
+	}
+}
+
+func TestWordsCodeGenerator(t *testing.T) {
+	for _, words := range []int{0, 1, 2, 3, 5} {
+		code := WordsCodeGenerator{Words: words}.Generate()
+		want := min(max(words, 1), 3)
+		if parts := strings.Split(code, "-"); len(parts) != want {
+			t.Fatalf("Words=%d generated %q, want %d words", words, code, want)
+		}
+		if !isValidCustomName(code) {
+			t.Fatalf("Words=%d generated invalid code %q", words, code)
+		}
+	}
+}
+
+func TestWordsSchemeCreatesWordCodes(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CodeScheme = CodeSchemeWords })
+	mapping := mustCreate(t, us, "https://example.com/words", CreateOptions{})
+	if parts := strings.Split(mapping.ShortCode, "-"); len(parts) != 2 {
+		t.Fatalf("generated %q, want two hyphenated words", mapping.ShortCode)
+	}
+
+	// An explicit code_length still asks for a random code of that length.
+	sized := mustCreate(t, us, "https://example.com/sized", CreateOptions{CodeLength: 10})
+	if len(sized.ShortCode) != 10 || strings.Contains(sized.ShortCode, "-") {
+		t.Fatalf("code_length 10 generated %q", sized.ShortCode)
+	}
+}
+
+func TestCustomGeneratorRetriesCollisions(t *testing.T) {
+	gen := &sequenceGenerator{codes: []string{"taken", "admin", "fresh"}}
+	us := newTestShortener(t, func(c *Config) { c.CodeGenerator = gen })
+	mustCreate(t, us, "https://example.com/first", CreateOptions{CustomName: "taken"})
+
+	mapping := mustCreate(t, us, "https://example.com/second", CreateOptions{})
+	if mapping.ShortCode != "fresh" || gen.calls != 3 {
+		t.Fatalf("got %q after %d calls, want fresh after 3", mapping.ShortCode, gen.calls)
+	}
+}
+
+func TestGeneratorGivesUpWhenCodesRunOut(t *testing.T) {
+	gen := &sequenceGenerator{codes: []string{"taken"}}
+	us := newTestShortener(t, func(c *Config) { c.CodeGenerator = gen })
+	mustCreate(t, us, "https://example.com/first", CreateOptions{CustomName: "taken"})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/second"}`)
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeCodesExhausted || gen.calls != maxCodeAttempts {
+		t.Fatalf("error code = %s after %d calls, want %s after %d", body.ErrorCode, gen.calls, CodeCodesExhausted, maxCodeAttempts)
+	}
+}
+
+// Both built-in generators must keep retrying until they find a free code,
+// even when most of their space is already used.
+func TestBuiltinGeneratorsRetryCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		gen   CodeGenerator
+		space int
+	}{
+		{"random", RandomCodeGenerator{Length: 3, Charset: "xy"}, 8},
+		{"words", WordsCodeGenerator{Words: 1}, len(codeWords)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := newTestShortener(t, func(c *Config) { c.CodeGenerator = tt.gen })
+			seen := make(map[string]bool)
+			for i := 0; i < tt.space; i++ {
+				mapping := mustCreate(t, us, "https://example.com/"+strings.Repeat("x", i+1), CreateOptions{})
+				if seen[mapping.ShortCode] {
+					t.Fatalf("code %q handed out twice", mapping.ShortCode)
+				}
+				seen[mapping.ShortCode] = true
+			}
+		})
+	}
+}
+
+func newDeterministicShortener(t *testing.T, salt string) *URLShortener {
+	t.Helper()
+	return newTestShortener(t, func(c *Config) {
+		c.CodeScheme = CodeSchemeDeterministic
+		c.CodeSalt = salt
+	})
+}
+
+func TestDeterministicCodesMatchAcrossInstances(t *testing.T) {
+	first := mustCreate(t, newDeterministicShortener(t, "pepper"), "https://example.com/same", CreateOptions{})
+	second := mustCreate(t, newDeterministicShortener(t, "pepper"), "https://example.com/same", CreateOptions{})
+	if first.ShortCode != second.ShortCode {
+		t.Fatalf("same URL and salt gave %q and %q", first.ShortCode, second.ShortCode)
+	}
+	if len(first.ShortCode) != 6 || !isValidCustomName(first.ShortCode) {
+		t.Fatalf("derived invalid code %q", first.ShortCode)
+	}
+
+	salted := mustCreate(t, newDeterministicShortener(t, "other"), "https://example.com/same", CreateOptions{})
+	if salted.ShortCode == first.ShortCode {
+		t.Fatalf("different salts gave the same code %q", salted.ShortCode)
+	}
+}
+
+func TestDeterministicCodeCollisionFallsBackToSuffix(t *testing.T) {
+	us := newDeterministicShortener(t, "pepper")
+	gen := us.codeGenerator.(DeterministicCodeGenerator)
+	code := gen.CodeFor("https://example.com/wanted", 0)
+
+	// Another link already holds the code the wanted URL hashes to.
+	mustCreate(t, us, "https://example.com/squatter", CreateOptions{CustomName: code})
+
+	mapping := mustCreate(t, us, "https://example.com/wanted", CreateOptions{})
+	if want := code + "-1"; mapping.ShortCode != want {
+		t.Fatalf("colliding URL got %q, want %q", mapping.ShortCode, want)
+	}
+	again := mustCreate(t, newDeterministicShortener(t, "pepper"), "https://example.com/wanted", CreateOptions{})
+	if again.ShortCode != code {
+		t.Fatalf("without the collision the URL got %q, want %q", again.ShortCode, code)
+	}
+}
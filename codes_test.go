@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCaseInsensitiveCodesResolveInAnyCase(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CaseInsensitiveCodes = true })
+	mapping := mustCreate(t, us, "https://example.com/flyer", CreateOptions{CustomName: "Promo"})
+	if mapping.ShortCode != "promo" {
+		t.Fatalf("stored code %q, want promo", mapping.ShortCode)
+	}
+
+	for _, typed := range []string{"/promo", "/PROMO", "/pRoMo"} {
+		rec := serve(t, us, http.MethodGet, typed, "")
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != "https://example.com/flyer" {
+			t.Fatalf("%s redirected to %q", typed, got)
+		}
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/stats/PROMO", ""), http.StatusOK)
+
+	random := mustCreate(t, us, "https://example.com/random", CreateOptions{})
+	if random.ShortCode != strings.ToLower(random.ShortCode) {
+		t.Fatalf("generated code %q is not lowercase", random.ShortCode)
+	}
+}
+
+func TestCodesAreCaseSensitiveByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/flyer", CreateOptions{CustomName: "Promo"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/Promo", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/promo", ""), http.StatusNotFound)
+}
+
+func TestCaseInsensitiveCustomCodeClash(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CaseInsensitiveCodes = true })
+	mustCreate(t, us, "https://example.com/first", CreateOptions{CustomName: "promo"})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/second","custom_name":"Promo"}`)
+	expectStatus(t, rec, http.StatusConflict)
+
+	rec = serve(t, us, http.MethodGet, "/PROMO", "")
+	if got := rec.Header().Get("Location"); got != "https://example.com/first" {
+		t.Fatalf("clash replaced the original link; redirected to %q", got)
+	}
+}
+
+func TestCaseSensitiveCustomCodesDoNotClash(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/first", CreateOptions{CustomName: "promo"})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/second","custom_name":"Promo"}`)
+	expectStatus(t, rec, http.StatusCreated)
+
+	for code, want := range map[string]string{"/promo": "https://example.com/first", "/Promo": "https://example.com/second"} {
+		if got := serve(t, us, http.MethodGet, code, "").Header().Get("Location"); got != want {
+			t.Fatalf("%s redirected to %q, want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough, and of a suitable type, to be worth compressing.
+// Responses to clients that do not accept gzip are passed straight through.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize  int
+	accepted bool
+	status   int
+	buf      []byte
+	decided  bool
+	gz       *gzip.Writer
+}
+
+func (gw *gzipResponseWriter) WriteHeader(status int) {
+	if gw.decided {
+		gw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if gw.status == 0 {
+		gw.status = status
+	}
+	if !gw.accepted {
+		gw.decide(false)
+	}
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !gw.decided && !gw.accepted {
+		gw.decide(false)
+	}
+	if gw.decided {
+		if gw.gz != nil {
+			return gw.gz.Write(b)
+		}
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buf = append(gw.buf, b...)
+	if len(gw.buf) >= gw.minSize {
+		if err := gw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide commits the headers and flushes whatever has been buffered, through
+// gzip when compress is set and the response is compressible. Vary is added
+// here rather than up front because http.TimeoutHandler replaces any header
+// the inner handler also sets, such as Vary: Accept.
+func (gw *gzipResponseWriter) decide(compress bool) error {
+	gw.decided = true
+	status := gw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := gw.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if compress && isCompressible(status, header) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(status)
+
+	if len(gw.buf) == 0 {
+		return nil
+	}
+	buf := gw.buf
+	gw.buf = nil
+	if gw.gz != nil {
+		_, err := gw.gz.Write(buf)
+		return err
+	}
+	_, err := gw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (gw *gzipResponseWriter) finish() {
+	if !gw.decided {
+		gw.decide(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Close()
+	}
+}
+
+func (gw *gzipResponseWriter) Flush() {
+	if !gw.decided {
+		gw.decide(len(gw.buf) >= gw.minSize)
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// isCompressible skips bodiless statuses, responses that are already encoded
+// and content types such as images that are compressed already.
+func isCompressible(status int, header http.Header) bool {
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range []string{"image/", "video/", "audio/", "application/zip", "application/gzip"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(encoding), "gzip") && strings.TrimSpace(params) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipMiddleware compresses API responses of at least minSize bytes for clients
+// that accept gzip. Paths with one of the exempt prefixes (streams, images)
+// are passed through untouched.
+func gzipMiddleware(minSize int, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if minSize <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || hasAnyPrefix(r.URL.Path, exemptPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			accepted := acceptsGzip(r) && r.Method != http.MethodHead
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize, accepted: accepted}
+			defer gw.finish()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
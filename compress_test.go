@@ -0,0 +1,70 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestGzipLargeListResponse(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for i := 0; i < 40; i++ {
+		mustCreate(t, us, fmt.Sprintf("https://example.com/page/%d", i), CreateOptions{})
+	}
+
+	rec := serve(t, us, http.MethodGet, "/api/urls", "", "Accept-Encoding", "gzip")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Accept-Encoding") {
+		t.Fatalf("Vary = %q, want Accept-Encoding listed", vary)
+	}
+
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []URLMapping
+	if err := json.Unmarshal(body, &links); err != nil || len(links) != 40 {
+		t.Fatalf("decompressed %d links (%v), want 40", len(links), err)
+	}
+
+	plain := serve(t, us, http.MethodGet, "/api/urls", "")
+	if plain.Header().Get("Content-Encoding") != "" {
+		t.Fatal("compressed a response for a client that did not ask for gzip")
+	}
+}
+
+func TestGzipSkipsSmallAndCompressedBodies(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/small", CreateOptions{CustomName: "small"})
+
+	for _, path := range []string{"/api/stats/small", "/api/qr/small?size=1024"} {
+		rec := serve(t, us, http.MethodGet, path, "", "Accept-Encoding", "gzip")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: Content-Encoding = %q, want none", path, got)
+		}
+	}
+}
+
+func TestGzipVaryKeepsHandlerVary(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/vary", CreateOptions{})
+
+	for _, encoding := range []string{"gzip", ""} {
+		vary := serve(t, us, http.MethodGet, "/api/urls", "", "Accept-Encoding", encoding).Header().Values("Vary")
+		if !slices.Contains(vary, "Accept-Encoding") || !slices.Contains(vary, "Accept") {
+			t.Errorf("Accept-Encoding %q: Vary = %q, want both Accept and Accept-Encoding", encoding, vary)
+		}
+	}
+}
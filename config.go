@@ -0,0 +1,468 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RootBehaviorUI       = "ui"
+	RootBehaviorJSON     = "json"
+	RootBehaviorRedirect = "redirect"
+)
+
+const (
+	DuplicateResponseExisting = "existing"
+	DuplicateResponseConflict = "conflict"
+)
+
+type Config struct {
+	BaseURL               string
+	CodeLength            int
+	CaseInsensitiveCodes  bool
+	APIKeys               map[string]string
+	AdminAPIKeys          map[string]string
+	RequestTimeout        time.Duration
+	TrustProxyHeaders     bool
+	SoftDelete            bool
+	SoftDeleteGracePeriod time.Duration
+	SweepInterval         time.Duration
+	LogURLMode            string
+	MaxBatchSize          int
+	DefaultScheme         string
+	GeoIPDatabasePath     string
+	MaxLinks              int
+	EvictWhenFull         bool
+	EvictionPolicy        string
+	TombstoneRetention    time.Duration
+	RequireHTTPS          bool
+	QRCacheMaxAge         time.Duration
+	VisitorSalt           string
+	UniqueVisitorWindow   time.Duration
+	MaxTrackedVisitors    int
+	ClickHistoryRetention HistoryRetention
+	RootBehavior          string
+	RootRedirectURL       string
+	MaxConcurrent         int
+	GlobalRateLimit       float64
+	GlobalRateBurst       int
+	GlobalRateWritesOnly  bool
+	ReservedCodes         []string
+	ReservedCodesFile     string
+	PrettyJSON            bool
+	StripFragment         bool
+	StripTrackingParams   bool
+	TrackingParams        []string
+	GzipMinSize           int
+	IdempotencyKeyTTL     time.Duration
+	PreviewTTL            time.Duration
+	IdempotencyCacheSize  int
+	SnapshotPath          string
+	LogSampleRate         float64
+	DuplicateResponse     string
+	AllowedSchemes        []string
+	NonHTTPBehavior       string
+	CORSMaxAge            time.Duration
+	TitleSlugs            bool
+	TitleFetchTimeout     time.Duration
+	MinTTL                time.Duration
+	MaxTTL                time.Duration
+	DestinationAllowlist  []string
+	MinCodeLength         int
+	MaxCodeLength         int
+	StorageRetryAttempts  int
+	StorageRetryBaseDelay time.Duration
+	StorageRetryMaxDelay  time.Duration
+	ExpiredRedirectURL    string
+	NotFoundRedirectURL   string
+	CountBots             bool
+	BotUserAgents         string
+	ReservedPaths         []string
+	MetricsLinkRates      bool
+	JSONNaming            string
+	RobotsFile            string
+	FollowRedirects       bool
+	MaxRedirectHops       int
+	FollowTimeout         time.Duration
+	StatsPrecision        int
+	AliasStats            string
+	CodeScheme            string
+	CodeSalt              string
+	CountFlushInterval    time.Duration
+	CountFlushThreshold   int
+	CounterShards         int
+	AnalyticsFile         string
+	UIBasicAuth           BasicAuth
+	ReloadFile            string
+	StrictJSON            bool
+	WSMaxSubscribers      int
+	// CodeGenerator, when set, replaces the generator CodeScheme names.
+	CodeGenerator CodeGenerator
+	// AnalyticsSink, when set, receives click records in place of
+	// AnalyticsFile.
+	AnalyticsSink AnalyticsSink
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CodeLength:            6,
+		MinCodeLength:         4,
+		MaxCodeLength:         maxShortCodeLength,
+		BotUserAgents:         defaultBotUserAgents,
+		ReservedPaths:         defaultReservedPaths,
+		TrackingParams:        defaultTrackingParams,
+		JSONNaming:            JSONNamingSnake,
+		AliasStats:            AliasStatsShared,
+		CodeScheme:            CodeSchemeRandom,
+		MaxRedirectHops:       5,
+		FollowTimeout:         5 * time.Second,
+		StorageRetryAttempts:  3,
+		StorageRetryBaseDelay: 100 * time.Millisecond,
+		StorageRetryMaxDelay:  2 * time.Second,
+		RequestTimeout:        10 * time.Second,
+		SoftDeleteGracePeriod: 7 * 24 * time.Hour,
+		SweepInterval:         time.Minute,
+		LogURLMode:            LogURLModeFull,
+		MaxBatchSize:          100,
+		DefaultScheme:         "http",
+		EvictionPolicy:        EvictionPolicyLRU,
+		TombstoneRetention:    30 * 24 * time.Hour,
+		QRCacheMaxAge:         24 * time.Hour,
+		UniqueVisitorWindow:   24 * time.Hour,
+		MaxTrackedVisitors:    1000,
+		RootBehavior:          RootBehaviorUI,
+		GzipMinSize:           1024,
+		IdempotencyKeyTTL:     24 * time.Hour,
+		PreviewTTL:            5 * time.Minute,
+		IdempotencyCacheSize:  10000,
+		LogSampleRate:         1.0,
+		DuplicateResponse:     DuplicateResponseExisting,
+		AllowedSchemes:        []string{"http", "https"},
+		NonHTTPBehavior:       NonHTTPBehaviorLanding,
+		CORSMaxAge:            10 * time.Minute,
+		TitleFetchTimeout:     3 * time.Second,
+		StrictJSON:            true,
+		WSMaxSubscribers:      100,
+	}
+}
+
+func LoadConfig(port string) Config {
+	config := DefaultConfig()
+
+	if renderURL := os.Getenv("RENDER_EXTERNAL_URL"); renderURL != "" {
+		config.BaseURL = renderURL
+	} else if appURL := os.Getenv("APP_URL"); appURL != "" {
+		config.BaseURL = appURL
+	} else {
+		config.BaseURL = "http://localhost:" + port
+	}
+
+	config.CodeLength = envInt("CODE_LENGTH", config.CodeLength)
+	config.MinCodeLength = envInt("MIN_CODE_LENGTH", config.MinCodeLength)
+	config.MaxCodeLength = envInt("MAX_CODE_LENGTH", config.MaxCodeLength)
+	config.CaseInsensitiveCodes = envBool("CASE_INSENSITIVE_CODES", config.CaseInsensitiveCodes)
+	config.APIKeys = envKeyMap("API_KEYS")
+	config.AdminAPIKeys = envKeyMap("ADMIN_API_KEYS")
+	config.RequestTimeout = envDuration("REQUEST_TIMEOUT", config.RequestTimeout)
+	config.MaxConcurrent = envInt("MAX_CONCURRENT", config.MaxConcurrent)
+	config.GlobalRateLimit = envFloat("GLOBAL_RATE_LIMIT", config.GlobalRateLimit)
+	config.GlobalRateBurst = envInt("GLOBAL_RATE_BURST", config.GlobalRateBurst)
+	config.GlobalRateWritesOnly = envBool("GLOBAL_RATE_WRITES_ONLY", config.GlobalRateWritesOnly)
+	config.WSMaxSubscribers = envInt("WS_MAX_SUBSCRIBERS", config.WSMaxSubscribers)
+	config.ReservedCodes = envList("RESERVED_CODES", config.ReservedCodes)
+	config.ReservedCodesFile = os.Getenv("RESERVED_CODES_FILE")
+	config.ReservedPaths = envList("RESERVED_PATHS", config.ReservedPaths)
+	config.RobotsFile = os.Getenv("ROBOTS_FILE")
+	config.PrettyJSON = envBool("PRETTY_JSON", config.PrettyJSON)
+	config.StrictJSON = envBool("STRICT_JSON", config.StrictJSON)
+	config.StripFragment = envBool("STRIP_FRAGMENT", config.StripFragment)
+	config.StripTrackingParams = envBool("STRIP_TRACKING_PARAMS", config.StripTrackingParams)
+	config.TrackingParams = envList("TRACKING_PARAMS", config.TrackingParams)
+	config.GzipMinSize = envInt("GZIP_MIN_SIZE", config.GzipMinSize)
+	config.IdempotencyKeyTTL = envDuration("IDEMPOTENCY_KEY_TTL", config.IdempotencyKeyTTL)
+	config.PreviewTTL = envDuration("PREVIEW_TTL", config.PreviewTTL)
+	config.IdempotencyCacheSize = envInt("IDEMPOTENCY_CACHE_SIZE", config.IdempotencyCacheSize)
+	config.SnapshotPath = os.Getenv("SNAPSHOT_PATH")
+	config.LogSampleRate = envFloat("LOG_SAMPLE_RATE", config.LogSampleRate)
+	config.StorageRetryAttempts = envInt("STORAGE_RETRY_ATTEMPTS", config.StorageRetryAttempts)
+	config.StorageRetryBaseDelay = envDuration("STORAGE_RETRY_BASE_DELAY", config.StorageRetryBaseDelay)
+	config.StorageRetryMaxDelay = envDuration("STORAGE_RETRY_MAX_DELAY", config.StorageRetryMaxDelay)
+	config.DestinationAllowlist = envList("DESTINATION_ALLOWLIST", config.DestinationAllowlist)
+	config.MinTTL = envDuration("MIN_TTL", config.MinTTL)
+	config.MaxTTL = envDuration("MAX_TTL", config.MaxTTL)
+	config.TitleSlugs = envBool("TITLE_SLUGS", config.TitleSlugs)
+	config.TitleFetchTimeout = envDuration("TITLE_FETCH_TIMEOUT", config.TitleFetchTimeout)
+	config.FollowRedirects = envBool("FOLLOW_REDIRECTS", config.FollowRedirects)
+	config.MaxRedirectHops = envInt("MAX_REDIRECT_HOPS", config.MaxRedirectHops)
+	config.FollowTimeout = envDuration("FOLLOW_REDIRECTS_TIMEOUT", config.FollowTimeout)
+	config.CORSMaxAge = envDuration("CORS_MAX_AGE", config.CORSMaxAge)
+	config.AllowedSchemes = envList("ALLOWED_SCHEMES", config.AllowedSchemes)
+	if behavior := os.Getenv("NON_HTTP_BEHAVIOR"); behavior != "" {
+		config.NonHTTPBehavior = strings.ToLower(behavior)
+	}
+	if aliasStats := os.Getenv("ALIAS_STATS"); aliasStats != "" {
+		config.AliasStats = strings.ToLower(aliasStats)
+	}
+	if scheme := os.Getenv("CODE_SCHEME"); scheme != "" {
+		config.CodeScheme = strings.ToLower(scheme)
+	}
+	config.CodeSalt = os.Getenv("CODE_SALT")
+	if naming := os.Getenv("JSON_NAMING"); naming != "" {
+		config.JSONNaming = strings.ToLower(naming)
+	}
+	if duplicateResponse := os.Getenv("DUPLICATE_RESPONSE"); duplicateResponse != "" {
+		config.DuplicateResponse = strings.ToLower(duplicateResponse)
+	}
+	config.TrustProxyHeaders = envBool("TRUST_PROXY_HEADERS", config.TrustProxyHeaders)
+	config.RequireHTTPS = envBool("REQUIRE_HTTPS", config.RequireHTTPS)
+	config.SoftDelete = envBool("SOFT_DELETE", config.SoftDelete)
+	config.SoftDeleteGracePeriod = envDuration("SOFT_DELETE_GRACE_PERIOD", config.SoftDeleteGracePeriod)
+	config.SweepInterval = envDuration("SWEEP_INTERVAL", config.SweepInterval)
+	config.CountFlushInterval = envDuration("COUNT_FLUSH_INTERVAL", config.CountFlushInterval)
+	config.CountFlushThreshold = envInt("COUNT_FLUSH_THRESHOLD", config.CountFlushThreshold)
+	config.CounterShards = envInt("COUNTER_SHARDS", config.CounterShards)
+	config.TombstoneRetention = envDuration("TOMBSTONE_RETENTION", config.TombstoneRetention)
+	config.QRCacheMaxAge = envDuration("QR_CACHE_MAX_AGE", config.QRCacheMaxAge)
+	config.VisitorSalt = os.Getenv("VISITOR_SALT")
+	config.UniqueVisitorWindow = envDuration("UNIQUE_VISITOR_WINDOW", config.UniqueVisitorWindow)
+	config.MaxTrackedVisitors = envInt("MAX_TRACKED_VISITORS", config.MaxTrackedVisitors)
+	config.ClickHistoryRetention = HistoryRetention{
+		MaxEvents: envInt("CLICK_HISTORY_MAX_EVENTS", config.ClickHistoryRetention.MaxEvents),
+		MaxAge:    envDuration("CLICK_HISTORY_MAX_AGE", config.ClickHistoryRetention.MaxAge),
+	}
+	if behavior := os.Getenv("ROOT_BEHAVIOR"); behavior != "" {
+		config.RootBehavior = strings.ToLower(behavior)
+	}
+	config.RootRedirectURL = os.Getenv("ROOT_REDIRECT_URL")
+	config.ExpiredRedirectURL = os.Getenv("EXPIRED_REDIRECT_URL")
+	config.NotFoundRedirectURL = os.Getenv("NOT_FOUND_REDIRECT_URL")
+	config.AnalyticsFile = os.Getenv("ANALYTICS_FILE")
+	config.ReloadFile = os.Getenv("RELOAD_FILE")
+	config.UIBasicAuth = BasicAuth{
+		Username:     os.Getenv("UI_BASIC_AUTH_USER"),
+		PasswordHash: os.Getenv("UI_BASIC_AUTH_HASH"),
+	}
+	config.CountBots = envBool("COUNT_BOTS", config.CountBots)
+	config.StatsPrecision = envInt("STATS_PRECISION", config.StatsPrecision)
+	config.MetricsLinkRates = envBool("METRICS_LINK_RATES", config.MetricsLinkRates)
+	if botUserAgents := os.Getenv("BOT_USER_AGENTS"); botUserAgents != "" {
+		config.BotUserAgents = botUserAgents
+	}
+	config.MaxBatchSize = envInt("MAX_BATCH_SIZE", config.MaxBatchSize)
+	config.GeoIPDatabasePath = os.Getenv("GEOIP_DATABASE_PATH")
+	config.MaxLinks = envInt("MAX_LINKS", config.MaxLinks)
+	config.EvictWhenFull = envBool("EVICT_WHEN_FULL", config.EvictWhenFull)
+	if policy := os.Getenv("EVICTION_POLICY"); policy != "" {
+		config.EvictionPolicy = strings.ToLower(policy)
+	}
+	if scheme := os.Getenv("DEFAULT_SCHEME"); scheme != "" {
+		config.DefaultScheme = strings.ToLower(scheme)
+	}
+	if mode := os.Getenv("LOG_URL_MODE"); mode != "" {
+		config.LogURLMode = mode
+	}
+
+	return config
+}
+
+func (c *Config) Validate() {
+	if c.DefaultScheme != "http" && c.DefaultScheme != "https" {
+		log.Printf("Warning: unsupported DEFAULT_SCHEME '%s', using 'http'", c.DefaultScheme)
+		c.DefaultScheme = "http"
+	}
+	if c.EvictionPolicy != EvictionPolicyLRU && c.EvictionPolicy != EvictionPolicyOldest {
+		log.Printf("Warning: unknown EVICTION_POLICY '%s', using '%s'", c.EvictionPolicy, EvictionPolicyLRU)
+		c.EvictionPolicy = EvictionPolicyLRU
+	}
+	for i, scheme := range c.AllowedSchemes {
+		c.AllowedSchemes[i] = strings.ToLower(strings.TrimSuffix(scheme, ":"))
+	}
+	for i, pattern := range c.DestinationAllowlist {
+		c.DestinationAllowlist[i] = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	}
+	switch c.RootBehavior {
+	case RootBehaviorUI, RootBehaviorJSON:
+	case RootBehaviorRedirect:
+		if !validateURL(c.RootRedirectURL, c.DefaultScheme, c.AllowedSchemes) {
+			log.Printf("Warning: ROOT_BEHAVIOR=redirect needs a valid ROOT_REDIRECT_URL, serving the UI instead")
+			c.RootBehavior = RootBehaviorUI
+		} else {
+			c.RootRedirectURL = normalizeURL(c.RootRedirectURL, c.DefaultScheme, false)
+		}
+	default:
+		log.Printf("Warning: unknown ROOT_BEHAVIOR '%s', using '%s'", c.RootBehavior, RootBehaviorUI)
+		c.RootBehavior = RootBehaviorUI
+	}
+	if c.ExpiredRedirectURL != "" {
+		if !validateURL(c.ExpiredRedirectURL, c.DefaultScheme, c.AllowedSchemes) {
+			log.Printf("Warning: invalid EXPIRED_REDIRECT_URL '%s', expired links will return 410", c.ExpiredRedirectURL)
+			c.ExpiredRedirectURL = ""
+		} else {
+			c.ExpiredRedirectURL = normalizeURL(c.ExpiredRedirectURL, c.DefaultScheme, false)
+		}
+	}
+	if c.NotFoundRedirectURL != "" {
+		if !validateURL(c.NotFoundRedirectURL, c.DefaultScheme, c.AllowedSchemes) {
+			log.Printf("Warning: invalid NOT_FOUND_REDIRECT_URL '%s', unknown codes will return 404", c.NotFoundRedirectURL)
+			c.NotFoundRedirectURL = ""
+		} else {
+			c.NotFoundRedirectURL = normalizeURL(c.NotFoundRedirectURL, c.DefaultScheme, false)
+		}
+	}
+	if c.UIBasicAuth.enabled() && (c.UIBasicAuth.Username == "" || !isValidUIPasswordHash(c.UIBasicAuth.PasswordHash)) {
+		log.Printf("Warning: UI_BASIC_AUTH_USER and UI_BASIC_AUTH_HASH (a bcrypt hash) must both be set; the web UI will reject every login")
+	}
+	if !isValidLogURLMode(c.LogURLMode) {
+		log.Printf("Warning: unknown LOG_URL_MODE '%s', using '%s'", c.LogURLMode, LogURLModeFull)
+		c.LogURLMode = LogURLModeFull
+	}
+	if c.AliasStats != AliasStatsShared && c.AliasStats != AliasStatsSeparate {
+		log.Printf("Warning: unknown ALIAS_STATS '%s', using '%s'", c.AliasStats, AliasStatsShared)
+		c.AliasStats = AliasStatsShared
+	}
+	if c.CodeScheme != CodeSchemeRandom && c.CodeScheme != CodeSchemeWords && c.CodeScheme != CodeSchemeDeterministic {
+		log.Printf("Warning: unknown CODE_SCHEME '%s', using '%s'", c.CodeScheme, CodeSchemeRandom)
+		c.CodeScheme = CodeSchemeRandom
+	}
+	if c.CodeScheme == CodeSchemeDeterministic && c.CodeSalt == "" {
+		log.Printf("Warning: CODE_SCHEME is '%s' but CODE_SALT is not set, so short codes can be guessed from URLs", CodeSchemeDeterministic)
+	}
+	if c.JSONNaming != JSONNamingSnake && c.JSONNaming != JSONNamingCamel {
+		log.Printf("Warning: unknown JSON_NAMING '%s', using '%s'", c.JSONNaming, JSONNamingSnake)
+		c.JSONNaming = JSONNamingSnake
+	}
+	if c.DuplicateResponse != DuplicateResponseExisting && c.DuplicateResponse != DuplicateResponseConflict {
+		log.Printf("Warning: unknown DUPLICATE_RESPONSE '%s', using '%s'", c.DuplicateResponse, DuplicateResponseExisting)
+		c.DuplicateResponse = DuplicateResponseExisting
+	}
+	if c.NonHTTPBehavior != NonHTTPBehaviorLanding && c.NonHTTPBehavior != NonHTTPBehaviorRedirect {
+		log.Printf("Warning: unknown NON_HTTP_BEHAVIOR '%s', using '%s'", c.NonHTTPBehavior, NonHTTPBehaviorLanding)
+		c.NonHTTPBehavior = NonHTTPBehaviorLanding
+	}
+	if c.MaxRedirectHops < 1 {
+		log.Printf("Warning: MAX_REDIRECT_HOPS must be at least 1, using %d", DefaultConfig().MaxRedirectHops)
+		c.MaxRedirectHops = DefaultConfig().MaxRedirectHops
+	}
+	if c.MinTTL > 0 && c.MaxTTL > 0 && c.MinTTL > c.MaxTTL {
+		log.Printf("Warning: MIN_TTL (%s) is greater than MAX_TTL (%s), ignoring MIN_TTL", c.MinTTL, c.MaxTTL)
+		c.MinTTL = 0
+	}
+	if c.CounterShards > 0 && (c.CountFlushInterval > 0 || c.CountFlushThreshold > 0) {
+		log.Printf("Warning: COUNTER_SHARDS has no effect while access counts are buffered")
+	}
+	if c.ClickHistoryRetention.MaxEvents < 0 || c.ClickHistoryRetention.MaxAge < 0 {
+		log.Printf("Warning: CLICK_HISTORY_MAX_EVENTS and CLICK_HISTORY_MAX_AGE must not be negative, keeping no click history")
+		c.ClickHistoryRetention = HistoryRetention{}
+	}
+	if c.GlobalRateLimit < 0 {
+		log.Printf("Warning: GLOBAL_RATE_LIMIT must not be negative (got %g), disabling the global rate limit", c.GlobalRateLimit)
+		c.GlobalRateLimit = 0
+	}
+	if c.LogSampleRate < 0 || c.LogSampleRate > 1 {
+		log.Printf("Warning: LOG_SAMPLE_RATE must be between 0 and 1 (got %g), logging every request", c.LogSampleRate)
+		c.LogSampleRate = 1
+	}
+
+	if c.MaxCodeLength < minShortCodeLength || c.MaxCodeLength > maxShortCodeLength {
+		log.Printf("Warning: MAX_CODE_LENGTH must be between %d and %d, using %d", minShortCodeLength, maxShortCodeLength, maxShortCodeLength)
+		c.MaxCodeLength = maxShortCodeLength
+	}
+	if c.MinCodeLength < minShortCodeLength || c.MinCodeLength > c.MaxCodeLength {
+		log.Printf("Warning: MIN_CODE_LENGTH must be between %d and MAX_CODE_LENGTH (%d), using %d", minShortCodeLength, c.MaxCodeLength, DefaultConfig().MinCodeLength)
+		c.MinCodeLength = min(DefaultConfig().MinCodeLength, c.MaxCodeLength)
+	}
+	if c.CodeLength < c.MinCodeLength || c.CodeLength > c.MaxCodeLength {
+		log.Printf("Warning: CODE_LENGTH %d is outside %d-%d, using %d", c.CodeLength, c.MinCodeLength, c.MaxCodeLength, max(c.MinCodeLength, min(c.CodeLength, c.MaxCodeLength)))
+		c.CodeLength = max(c.MinCodeLength, min(c.CodeLength, c.MaxCodeLength))
+	}
+	if c.CaseInsensitiveCodes && c.CodeLength < 7 {
+		log.Printf("Warning: CASE_INSENSITIVE_CODES shrinks the code space to 36 characters; consider CODE_LENGTH >= 7 (currently %d)", c.CodeLength)
+	}
+}
+
+func envBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid boolean for %s: '%s', using default %v", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer for %s: '%s', using default %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid number for %s: '%s', using default %g", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration for %s: '%s', using default %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func envList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envKeyMap parses "key:owner,key2:owner2" into a key to owner map.
+func envKeyMap(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		apiKey, owner, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || apiKey == "" || owner == "" {
+			log.Printf("Warning: ignoring malformed entry in %s", key)
+			continue
+		}
+		result[apiKey] = owner
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxConfirmTokenLength = 128
+	confirmCookieName     = "ql_confirmed"
+)
+
+// confirmTemplate asks for a link's confirmation token before redirecting.
+// The form posts back to the short URL itself.
+var confirmTemplate = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Confirm this link</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<main class="container">
+<h1>This link needs a confirmation token</h1>
+<p>Enter the token you were given to continue.</p>
+{{if .Invalid}}<p class="error">That token is not correct.</p>{{end}}
+<form method="post">
+<input type="password" name="token" autocomplete="off" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+type confirmPage struct {
+	Invalid bool
+}
+
+// hashConfirmToken returns the hex SHA-256 of token as stored on mappings,
+// or "" for no token.
+func hashConfirmToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateConfirmToken(token string) error {
+	if len(token) > maxConfirmTokenLength {
+		return errorf(ErrInvalidToken, "confirm_token must be at most %d characters", maxConfirmTokenLength)
+	}
+	return nil
+}
+
+// confirmCookieValue is what a visitor's cookie holds once they have entered
+// mapping's token. It is keyed by the server's visitor salt so it cannot be
+// forged, and stops matching if the token changes.
+func (us *URLShortener) confirmCookieValue(mapping *URLMapping) string {
+	mac := hmac.New(sha256.New, []byte(us.config.VisitorSalt))
+	mac.Write([]byte(mapping.ID + "|" + mapping.ConfirmTokenHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// needsConfirmation reports whether r must enter mapping's token before being
+// redirected.
+func (us *URLShortener) needsConfirmation(r *http.Request, mapping *URLMapping) bool {
+	if mapping.ConfirmTokenHash == "" {
+		return false
+	}
+	return !hasConfirmCookie(r, us.confirmCookieValue(mapping))
+}
+
+// hasConfirmCookie reports whether r carries a confirmation cookie worth
+// want. Several links can share a cookie path prefix, so every cookie of the
+// name is checked.
+func hasConfirmCookie(r *http.Request, want string) bool {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == confirmCookieName && hmac.Equal([]byte(cookie.Value), []byte(want)) {
+			return true
+		}
+	}
+	return false
+}
+
+// revealsDestination reports whether r may see where mapping points. A
+// guarded link's destination is kept from anyone who has not entered its
+// token, except its owner and admins.
+func (us *URLShortener) revealsDestination(r *http.Request, mapping *URLMapping) bool {
+	return !us.needsConfirmation(r, mapping) || callerOwns(r, mapping.Owner)
+}
+
+func serveConfirm(w http.ResponseWriter, r *http.Request, status int, page confirmPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	confirmTemplate.Execute(w, page)
+}
+
+// confirmVisitHandler takes the token posted from the confirmation page. A
+// correct token sets a session cookie scoped to the short URL's path and sends
+// the visitor back to it with a 303, where the redirect now goes through.
+func (us *URLShortener) confirmVisitHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mapping, err := us.LookupURL(storageKey(vars["namespace"], vars["shortCode"]))
+	if err != nil {
+		http.Error(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+
+	if mapping.ConfirmTokenHash != "" {
+		got := hashConfirmToken(r.PostFormValue("token"))
+		if subtle.ConstantTimeCompare([]byte(got), []byte(mapping.ConfirmTokenHash)) != 1 {
+			serveConfirm(w, r, http.StatusForbidden, confirmPage{Invalid: true})
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     confirmCookieName,
+			Value:    us.confirmCookieValue(mapping),
+			Path:     r.URL.Path,
+			HttpOnly: true,
+			Secure:   us.config.RequireHTTPS,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// postToken submits token from the confirmation page of path.
+func postToken(us *URLShortener, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	us.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfirmTokenRequiredBeforeRedirect(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mapping := mustCreate(t, us, "https://example.com/vault", CreateOptions{CustomName: "vault", ConfirmToken: "open-sesame"})
+	if mapping.ConfirmTokenHash == "" || mapping.ConfirmTokenHash == "open-sesame" {
+		t.Fatalf("ConfirmTokenHash = %q, want a hash of the token", mapping.ConfirmTokenHash)
+	}
+
+	rec := serve(t, us, http.MethodGet, "/vault", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Location") != "" || !strings.Contains(rec.Body.String(), `name="token"`) {
+		t.Fatalf("unconfirmed visit was not shown the token form")
+	}
+
+	expectStatus(t, postToken(us, "/vault", "wrong"), http.StatusForbidden)
+	if n := accessCount(t, us, "vault"); n != 0 {
+		t.Fatalf("count before confirming = %d, want 0", n)
+	}
+}
+
+func TestConfirmTokenCookieSkipsForm(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/vault", CreateOptions{CustomName: "vault", ConfirmToken: "open-sesame"})
+	mustCreate(t, us, "https://example.com/other", CreateOptions{CustomName: "other", ConfirmToken: "open-sesame"})
+
+	rec := postToken(us, "/vault", "open-sesame")
+	expectStatus(t, rec, http.StatusSeeOther)
+	if got := rec.Header().Get("Location"); got != "/vault" {
+		t.Fatalf("Location = %q, want /vault", got)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/vault" || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %v, want one HttpOnly cookie scoped to /vault", cookies)
+	}
+	cookie := cookies[0].Name + "=" + cookies[0].Value
+
+	for i := 0; i < 2; i++ {
+		rec = serve(t, us, http.MethodGet, "/vault", "", "Cookie", cookie)
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != "https://example.com/vault" {
+			t.Fatalf("confirmed visit redirected to %q", got)
+		}
+	}
+	if n := accessCount(t, us, "vault"); n != 2 {
+		t.Fatalf("count after confirmed visits = %d, want 2", n)
+	}
+
+	// The cookie only vouches for the link it was issued for.
+	expectStatus(t, serve(t, us, http.MethodGet, "/other", "", "Cookie", cookie), http.StatusOK)
+}
+
+func TestConfirmTokenNotDedupedWithPlainLink(t *testing.T) {
+	us := newTestShortener(t, nil)
+	plain := mustCreate(t, us, "https://example.com/same", CreateOptions{})
+	guarded := mustCreate(t, us, "https://example.com/same", CreateOptions{ConfirmToken: "secret"})
+	if plain.ID == guarded.ID {
+		t.Fatalf("token link reused the unguarded link %s", plain.ID)
+	}
+}
+
+func TestConfirmTokenHidesDestination(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/vault", CreateOptions{CustomName: "vault", ConfirmToken: "open-sesame"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/resolve/vault", ""), http.StatusForbidden)
+	for _, target := range []string{"/api/stats/vault", "/vault+"} {
+		rec := serve(t, us, http.MethodGet, target, "")
+		expectStatus(t, rec, http.StatusOK)
+		if strings.Contains(rec.Body.String(), "example.com/vault") {
+			t.Fatalf("%s gave the guarded destination away: %s", target, rec.Body)
+		}
+	}
+
+	// Auth is off in tests, so this caller is an admin: it sees the link but
+	// never the token hash.
+	rec := serve(t, us, http.MethodGet, "/api/urls/vault", "")
+	expectStatus(t, rec, http.StatusOK)
+	if strings.Contains(rec.Body.String(), "confirm_token_hash") {
+		t.Fatalf("response includes the token hash: %s", rec.Body)
+	}
+}
+
+func TestConfirmTokenHidesDestinationInEvents(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.APIKeys = map[string]string{"alice-key": "alice"} })
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+	mustCreate(t, us, "https://example.com/vault", CreateOptions{CustomName: "vault", ConfirmToken: "open-sesame", Owner: "alice"})
+	cookie := postToken(us, "/vault", "open-sesame").Result().Cookies()[0]
+
+	anonymous, _ := dialWS(t, server)
+	unlocked, _ := dialWS(t, server, "Cookie", cookie.Name+"="+cookie.Value)
+	owner, _ := dialWS(t, server, "X-API-Key", "alice-key")
+	waitForSubscribers(us, 3)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/vault", "", "Cookie", cookie.Name+"="+cookie.Value), http.StatusMovedPermanently)
+
+	for _, tt := range []struct {
+		name string
+		conn *websocket.Conn
+		want string
+	}{
+		{"anonymous", anonymous, ""},
+		{"unlocked", unlocked, "https://example.com/vault"},
+		{"owner", owner, "https://example.com/vault"},
+	} {
+		if event := readWSEvent(t, tt.conn); event.OriginalURL != tt.want {
+			t.Errorf("%s subscriber sees destination %q, want %q", tt.name, event.OriginalURL, tt.want)
+		}
+	}
+}
+
+func TestConfirmTokenSurvivesSnapshot(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mapping := mustCreate(t, us, "https://example.com/vault", CreateOptions{CustomName: "vault", ConfirmToken: "open-sesame"})
+
+	path := filepath.Join(t.TempDir(), "links.json")
+	if err := us.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestShortener(t, nil)
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := restored.LookupURL("vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ConfirmTokenHash != mapping.ConfirmTokenHash {
+		t.Fatalf("restored ConfirmTokenHash = %q, want %q", got.ConfirmTokenHash, mapping.ConfirmTokenHash)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPreflightAdvertisesRouteMethods(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodOptions, "/api/shorten", "")
+	expectStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "POST, OPTIONS" {
+		t.Fatalf("Allow-Methods = %q, want POST, OPTIONS", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("Max-Age = %q, want 600", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatal("preflight lacks Access-Control-Allow-Origin")
+	}
+
+	rec = serve(t, us, http.MethodOptions, "/api/health", "")
+	expectStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Allow"); got != "GET, OPTIONS" {
+		t.Fatalf("Allow = %q for a GET-only route", got)
+	}
+}
+
+func TestPreflightUnknownPathIs404(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for _, path := range []string{"/api/no/such/thing", "/a/b/c/d"} {
+		rec := serve(t, us, http.MethodOptions, path, "")
+		expectStatus(t, rec, http.StatusNotFound)
+		if rec.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("%s: 404 preflight advertised methods", path)
+		}
+	}
+}
+
+func TestAPIPathsNotTakenForNamespacedCodes(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/shorten", ""), http.StatusMethodNotAllowed)
+
+	rec := serve(t, us, http.MethodOptions, "/api/urls/abc123", "")
+	expectStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Allow"); got != "GET, PATCH, DELETE, OPTIONS" {
+		t.Fatalf("Allow = %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// countBuffer holds access-count increments that have not been written back
+// to their mappings yet, so a burst of redirects costs one write per link
+// per flush instead of one per visit. Deltas are keyed by mapping pointer,
+// which stays stable when a link is rotated to a new code.
+type countBuffer struct {
+	mu      sync.Mutex
+	deltas  map[*URLMapping]int64
+	pending int
+}
+
+func newCountBuffer() *countBuffer {
+	return &countBuffer{deltas: make(map[*URLMapping]int64)}
+}
+
+// add records one access to mapping and returns its pending delta and the
+// number of increments pending across all links.
+func (b *countBuffer) add(mapping *URLMapping) (delta int64, pending int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.deltas[mapping]++
+	b.pending++
+	return b.deltas[mapping], b.pending
+}
+
+func (b *countBuffer) get(mapping *URLMapping) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.deltas[mapping]
+}
+
+// drain empties the buffer and returns what it held.
+func (b *countBuffer) drain() map[*URLMapping]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deltas := b.deltas
+	b.deltas = make(map[*URLMapping]int64)
+	b.pending = 0
+	return deltas
+}
+
+// counterShard is padded to a cache line so that shards updated from
+// different CPUs do not contend.
+type counterShard struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// shardedCounter spreads the visits to one hot link over several shards,
+// summed when read, and remembers when the latest of them happened.
+type shardedCounter struct {
+	shards []counterShard
+	// last is the UnixNano time of the latest visit counted here.
+	last atomic.Int64
+}
+
+func newShardedCounter(n int) *shardedCounter {
+	return &shardedCounter{shards: make([]counterShard, max(n, 1))}
+}
+
+// add counts one visit at now on a shard picked at random. The runtime's
+// per-thread random source is the cheapest hint Go offers for spreading
+// load by CPU.
+func (c *shardedCounter) add(now time.Time) {
+	c.shards[rand.Uint32()%uint32(len(c.shards))].n.Add(1)
+	for at := now.UnixNano(); ; {
+		last := c.last.Load()
+		if last >= at || c.last.CompareAndSwap(last, at) {
+			return
+		}
+	}
+}
+
+func (c *shardedCounter) sum() int64 {
+	if c == nil {
+		return 0
+	}
+	var total int64
+	for i := range c.shards {
+		total += c.shards[i].n.Load()
+	}
+	return total
+}
+
+// drain zeroes the counts and returns what they held.
+func (c *shardedCounter) drain() int64 {
+	var total int64
+	for i := range c.shards {
+		total += c.shards[i].n.Swap(0)
+	}
+	return total
+}
+
+// shardedCounting reports whether visits go to per-link sharded counters.
+func (us *URLShortener) shardedCounting() bool {
+	return us.counts == nil && us.config.CounterShards > 0
+}
+
+// recordShardedAccess counts a visit to shortCode under the read lock only,
+// which is what lets redirects of a hot link run side by side. It declines,
+// returning ok false, for links whose shards are not set up yet or that keep
+// a click history, which needs the write lock; recordAccess then takes it.
+func (us *URLShortener) recordShardedAccess(shortCode string) (mapping *URLMapping, accessCount int64, ok bool, err error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	mapping, err = us.resolveLocked(shortCode)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if mapping.shards == nil || mapping.hits == nil || us.keepsClickHistory(mapping) {
+		return nil, 0, false, nil
+	}
+
+	now := time.Now()
+	mapping.shards.add(now)
+	mapping.hits.record(now)
+	return mapping, mapping.AccessCount + mapping.shards.sum(), true, nil
+}
+
+// lastAccessed returns when mapping was last visited, counting visits still
+// held in its shards. Callers must hold the lock.
+func lastAccessed(mapping *URLMapping) *time.Time {
+	if mapping.shards == nil {
+		return mapping.LastAccessedAt
+	}
+	last := mapping.shards.last.Load()
+	if last == 0 || (mapping.LastAccessedAt != nil && mapping.LastAccessedAt.UnixNano() >= last) {
+		return mapping.LastAccessedAt
+	}
+	at := time.Unix(0, last)
+	return &at
+}
+
+// countAccessLocked records one visit to mapping and returns its access count
+// including that visit. Without buffering the count is written immediately,
+// or to the link's sharded counter with CounterShards; with buffering, the
+// visit is buffered until the next flush, which happens now if
+// CountFlushThreshold increments are pending. Callers must hold the write
+// lock.
+func (us *URLShortener) countAccessLocked(mapping *URLMapping, now time.Time) int64 {
+	if us.shardedCounting() {
+		if mapping.shards == nil {
+			mapping.shards = newShardedCounter(us.config.CounterShards)
+		}
+		mapping.shards.add(now)
+		return mapping.AccessCount + mapping.shards.sum()
+	}
+	if us.counts == nil {
+		mapping.AccessCount++
+		return mapping.AccessCount
+	}
+
+	delta, pending := us.counts.add(mapping)
+	if us.config.CountFlushThreshold > 0 && pending >= us.config.CountFlushThreshold {
+		us.flushCountsLocked()
+		return mapping.AccessCount
+	}
+	return mapping.AccessCount + delta
+}
+
+// pendingAccesses returns the visits to mapping that are buffered or
+// sharded but not yet written to its AccessCount. Reads add it so counts are
+// exact while buffering.
+func (us *URLShortener) pendingAccesses(mapping *URLMapping) int64 {
+	if us.counts == nil {
+		return mapping.shards.sum()
+	}
+	return us.counts.get(mapping)
+}
+
+// FlushCounts writes every buffered or sharded access-count delta to its
+// mapping.
+func (us *URLShortener) FlushCounts() {
+	if us.counts == nil && !us.shardedCounting() {
+		return
+	}
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	us.flushCountsLocked()
+}
+
+// flushCountsLocked is FlushCounts for callers that hold the write lock.
+func (us *URLShortener) flushCountsLocked() {
+	if us.counts == nil {
+		for _, mapping := range us.storage {
+			if mapping.shards != nil {
+				mapping.AccessCount += mapping.shards.drain()
+				mapping.LastAccessedAt = lastAccessed(mapping)
+			}
+		}
+		return
+	}
+	for mapping, delta := range us.counts.drain() {
+		mapping.AccessCount += delta
+	}
+}
+
+// StartCountFlusher flushes buffered access counts every interval.
+func (us *URLShortener) StartCountFlusher(interval time.Duration) {
+	if us.counts == nil || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			us.FlushCounts()
+		}
+	}()
+	log.Printf("Buffering access counts, flushing every %s", interval)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// storedCount reads code's written-back count, without buffered visits.
+func storedCount(us *URLShortener, code string) int64 {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+	return us.storage[code].AccessCount
+}
+
+func TestBufferedCountsAreExactBeforeFlush(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CountFlushInterval = time.Hour })
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "busy"})
+
+	for i := 0; i < 3; i++ {
+		expectStatus(t, serve(t, us, http.MethodGet, "/busy", ""), http.StatusMovedPermanently)
+	}
+	if n := storedCount(us, "busy"); n != 0 {
+		t.Fatalf("stored count before flush = %d, want 0", n)
+	}
+	if n := accessCount(t, us, "busy"); n != 3 {
+		t.Fatalf("reported count before flush = %d, want 3", n)
+	}
+
+	us.FlushCounts()
+	if n := storedCount(us, "busy"); n != 3 {
+		t.Fatalf("stored count after flush = %d, want 3", n)
+	}
+	if n := accessCount(t, us, "busy"); n != 3 {
+		t.Fatalf("reported count after flush = %d, want 3", n)
+	}
+}
+
+func TestBufferedCountsFlushAtThreshold(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CountFlushThreshold = 4 })
+	mustCreate(t, us, "https://example.com/a", CreateOptions{CustomName: "first"})
+	mustCreate(t, us, "https://example.com/b", CreateOptions{CustomName: "second"})
+
+	for _, code := range []string{"first", "second", "first"} {
+		expectStatus(t, serve(t, us, http.MethodGet, "/"+code, ""), http.StatusMovedPermanently)
+	}
+	if n := storedCount(us, "first"); n != 0 {
+		t.Fatalf("stored count below threshold = %d, want 0", n)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/second", ""), http.StatusMovedPermanently)
+	if a, b := storedCount(us, "first"), storedCount(us, "second"); a != 2 || b != 2 {
+		t.Fatalf("stored counts at threshold = %d, %d, want 2, 2", a, b)
+	}
+}
+
+func TestBufferedCountsSurviveRotationAndSnapshot(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CountFlushInterval = time.Hour })
+	mustCreate(t, us, "https://example.com/moving", CreateOptions{CustomName: "moving"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/moving", ""), http.StatusMovedPermanently)
+
+	rotated := rotate(t, us, "moving")
+	if n := accessCount(t, us, rotated.ShortCode); n != 1 {
+		t.Fatalf("count after rotation = %d, want 1", n)
+	}
+
+	path := filepath.Join(t.TempDir(), "links.json")
+	if err := us.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestShortener(t, nil)
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	if n := storedCount(restored, rotated.ShortCode); n != 1 {
+		t.Fatalf("snapshot count = %d, want 1", n)
+	}
+}
+
+func TestShardedCountsSumAllIncrements(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CounterShards = 8 })
+	mustCreate(t, us, "https://example.com/hot", CreateOptions{CustomName: "hot"})
+
+	const visitors, visits = 8, 25
+	var wg sync.WaitGroup
+	for i := 0; i < visitors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < visits; j++ {
+				if _, err := us.GetOriginalURL("hot"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := storedCount(us, "hot"); n != 0 {
+		t.Fatalf("stored count before flush = %d, want 0 with sharded counters", n)
+	}
+	if n := accessCount(t, us, "hot"); n != visitors*visits {
+		t.Fatalf("reported count = %d, want %d", n, visitors*visits)
+	}
+	us.FlushCounts()
+	if n := storedCount(us, "hot"); n != visitors*visits {
+		t.Fatalf("stored count after flush = %d, want %d", n, visitors*visits)
+	}
+	if n := accessCount(t, us, "hot"); n != visitors*visits {
+		t.Fatalf("reported count after flush = %d, want %d", n, visitors*visits)
+	}
+	if stats := linkStats(t, us, "hot"); stats.LastAccessedAt == nil {
+		t.Fatal("last_accessed_at not set from sharded visits")
+	}
+}
+
+func TestShardedVisitsSkipWriteLock(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CounterShards = 4 })
+	mustCreate(t, us, "https://example.com/hot", CreateOptions{CustomName: "hot"})
+	// The first visit sets the link's shards up under the write lock.
+	if _, err := us.GetOriginalURL("hot"); err != nil {
+		t.Fatal(err)
+	}
+
+	// With a reader holding the lock, a visit that needed the write lock
+	// would block until the reader lets go.
+	us.mutex.RLock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := us.GetOriginalURL("hot")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		us.mutex.RUnlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		us.mutex.RUnlock()
+		t.Fatal("sharded visit waited for the write lock")
+	}
+	if n := accessCount(t, us, "hot"); n != 2 {
+		t.Fatalf("access count = %d, want 2", n)
+	}
+}
+
+func BenchmarkCounterSingle(b *testing.B) {
+	var counter atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			counter.Add(1)
+		}
+	})
+	if counter.Load() != int64(b.N) {
+		b.Fatalf("count = %d, want %d", counter.Load(), b.N)
+	}
+}
+
+func BenchmarkCounterSharded(b *testing.B) {
+	counter := newShardedCounter(runtime.GOMAXPROCS(0))
+	now := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			counter.add(now)
+		}
+	})
+	if counter.sum() != int64(b.N) {
+		b.Fatalf("count = %d, want %d", counter.sum(), b.N)
+	}
+}
+
+// benchmarkHotLinkVisits visits one link from every CPU at once, which is
+// where taking the write lock per visit hurts.
+func benchmarkHotLinkVisits(b *testing.B, shards int) {
+	us := newTestShortener(b, func(c *Config) { c.CounterShards = shards })
+	mustCreate(b, us, "https://example.com/hot", CreateOptions{CustomName: "hot"})
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			us.GetOriginalURL("hot")
+		}
+	})
+}
+
+func BenchmarkHotLinkVisitsSingle(b *testing.B) { benchmarkHotLinkVisits(b, 0) }
+
+func BenchmarkHotLinkVisitsSharded(b *testing.B) {
+	benchmarkHotLinkVisits(b, runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestShortenReturns201WithLocation(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/new","custom_name":"fresh"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Location"); got != "/api/urls/fresh" {
+		t.Fatalf("Location = %q, want /api/urls/fresh", got)
+	}
+	var body struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeBody(t, rec, &body)
+	if body.ShortCode != "fresh" {
+		t.Fatalf("short_code = %q, want fresh", body.ShortCode)
+	}
+}
+
+func TestShortenDuplicateReturns200WithoutLocation(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/dup"}`), http.StatusCreated)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/dup"}`)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Location"); got != "" {
+		t.Fatalf("Location = %q on a duplicate, want none", got)
+	}
+}
+
+func TestShortenDuplicateConflictMode(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.DuplicateResponse = DuplicateResponseConflict })
+	first := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/dup"}`)
+	expectStatus(t, first, http.StatusCreated)
+	var created struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeBody(t, first, &created)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/dup"}`)
+	expectStatus(t, rec, http.StatusConflict)
+	var existing struct {
+		ShortCode string `json:"short_code"`
+	}
+	decodeBody(t, rec, &existing)
+	if existing.ShortCode != created.ShortCode {
+		t.Fatalf("409 body names %q, want the existing code %q", existing.ShortCode, created.ShortCode)
+	}
+	if n := countLinksTo(us, "https://example.com/dup"); n != 1 {
+		t.Fatalf("%d links stored, want 1", n)
+	}
+}
+
+func TestConcurrentCreatesOfSameURLShareOneCode(t *testing.T) {
+	us := newTestShortener(t, nil)
+	const workers = 32
+
+	codes := make(chan string, workers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			mapping, _, err := us.CreateShortURL("https://example.com/race", CreateOptions{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			codes <- mapping.ShortCode
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	seen := make(map[string]bool)
+	for code := range codes {
+		seen[code] = true
+	}
+	if len(seen) != 1 {
+		t.Fatalf("concurrent creates produced %d codes: %v", len(seen), seen)
+	}
+	if n := countLinksTo(us, "https://example.com/race"); n != 1 {
+		t.Fatalf("%d links stored, want 1", n)
+	}
+}
+
+func TestRequestedCodeLength(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxCodeLength = 12 })
+
+	for _, length := range []int{4, 6, 12} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", fmt.Sprintf(`{"url":"https://example.com/len%d","code_length":%d}`, length, length))
+		expectStatus(t, rec, http.StatusCreated)
+		var body struct {
+			ShortCode string `json:"short_code"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.ShortCode) != length {
+			t.Fatalf("code_length %d produced %q", length, body.ShortCode)
+		}
+		expectStatus(t, serve(t, us, http.MethodGet, "/"+body.ShortCode, ""), http.StatusMovedPermanently)
+	}
+
+	for _, length := range []int{3, 13, -1} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", fmt.Sprintf(`{"url":"https://example.com/bad","code_length":%d}`, length))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("code_length %d: status = %d, want 400", length, rec.Code)
+		}
+	}
+}
+
+func TestCreateRejectsUnknownFieldsWhenStrict(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"ur":"https://example.com/typo"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeInvalidJSON || !strings.Contains(body.Error, `"ur"`) {
+		t.Fatalf("error = %+v, want INVALID_JSON naming the unknown field", body)
+	}
+}
+
+func TestCreateIgnoresUnknownFieldsWhenLenient(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.StrictJSON = false })
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/later","added_later":true}`)
+	expectStatus(t, rec, http.StatusCreated)
+}
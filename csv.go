@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"short_code", "original_url", "created_at", "access_count"}
+
+// wantsCSV reports whether the client asked for CSV, via ?format=csv or an
+// Accept header that lists text/csv ahead of JSON.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/csv":
+			return true
+		case "application/json", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+// writeURLsCSV writes one row per mapping.
+func writeURLsCSV(w http.ResponseWriter, urls []*URLMapping) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="urls.csv"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+	for _, mapping := range urls {
+		writer.Write([]string{
+			mapping.path(),
+			mapping.OriginalURL,
+			mapping.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(mapping.AccessCount, 10),
+		})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListURLsAsCSV(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mapping := mustCreate(t, us, "https://example.com/a,b?x=\"q\"", CreateOptions{CustomName: "csvrow"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/csvrow", ""), http.StatusMovedPermanently)
+
+	for _, headers := range [][]string{{"Accept", "text/csv"}, nil} {
+		target := "/api/urls"
+		if headers == nil {
+			target += "?format=csv"
+		}
+		rec := serve(t, us, http.MethodGet, target, "", headers...)
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+			t.Fatalf("%s: Content-Type = %q", target, got)
+		}
+
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || strings.Join(rows[0], ",") != "short_code,original_url,created_at,access_count" {
+			t.Fatalf("rows = %q", rows)
+		}
+		want := []string{"csvrow", "https://example.com/a,b?x=\"q\"", mapping.CreatedAt.UTC().Format(time.RFC3339), "1"}
+		if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+			t.Fatalf("data row = %q, want %q", rows[1], want)
+		}
+	}
+}
+
+func TestListURLsDefaultsToJSON(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/json", CreateOptions{})
+	for _, accept := range []string{"", "application/json", "application/json, text/csv"} {
+		rec := serve(t, us, http.MethodGet, "/api/urls", "", "Accept", accept)
+		expectStatus(t, rec, http.StatusOK)
+		var urls []URLMapping
+		decodeBody(t, rec, &urls)
+		if len(urls) != 1 {
+			t.Fatalf("Accept %q: %d urls", accept, len(urls))
+		}
+	}
+}
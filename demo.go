@@ -11,6 +11,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"url-shortener/api"
 )
 
 type DemoClient struct {
@@ -18,23 +20,6 @@ type DemoClient struct {
 	client  *http.Client
 }
 
-type CreateURLRequestDemo struct {
-	URL string `json:"url"`
-}
-
-type CreateURLResponseDemo struct {
-	ShortCode   string `json:"short_code"`
-	OriginalURL string `json:"original_url"`
-	ShortURL    string `json:"short_url"`
-}
-
-type URLStatsDemo struct {
-	ShortCode   string    `json:"short_code"`
-	OriginalURL string    `json:"original_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	AccessCount int64     `json:"access_count"`
-}
-
 func NewDemoClient(baseURL string) *DemoClient {
 	return &DemoClient{
 		baseURL: baseURL,
@@ -44,8 +29,8 @@ func NewDemoClient(baseURL string) *DemoClient {
 	}
 }
 
-func (c *DemoClient) CreateShortURL(originalURL string) (*CreateURLResponseDemo, error) {
-	reqBody := CreateURLRequestDemo{URL: originalURL}
+func (c *DemoClient) CreateShortURL(originalURL string) (*api.CreateURLResponse, error) {
+	reqBody := api.CreateURLRequest{URL: originalURL}
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, err
@@ -57,12 +42,12 @@ func (c *DemoClient) CreateShortURL(originalURL string) (*CreateURLResponseDemo,
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(body))
 	}
 
-	var response CreateURLResponseDemo
+	var response api.CreateURLResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, err
 	}
@@ -70,7 +55,7 @@ func (c *DemoClient) CreateShortURL(originalURL string) (*CreateURLResponseDemo,
 	return &response, nil
 }
 
-func (c *DemoClient) GetStats(shortCode string) (*URLStatsDemo, error) {
+func (c *DemoClient) GetStats(shortCode string) (*api.URLStats, error) {
 	resp, err := c.client.Get(c.baseURL + "/api/stats/" + shortCode)
 	if err != nil {
 		return nil, err
@@ -82,7 +67,7 @@ func (c *DemoClient) GetStats(shortCode string) (*URLStatsDemo, error) {
 		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(body))
 	}
 
-	var stats URLStatsDemo
+	var stats api.URLStats
 	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxDescriptionLength = 500
+
+// sanitizeDescription strips control characters and surrounding whitespace
+// from a link note and enforces the length limit.
+func sanitizeDescription(description string) (string, error) {
+	description = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, description))
+
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return "", errorf(ErrInvalidDescription, "description must be at most %d characters", maxDescriptionLength)
+	}
+	return description, nil
+}
+
+func (us *URLShortener) SetDescription(shortCode, description string) error {
+	description, err := sanitizeDescription(description)
+	if err != nil {
+		return err
+	}
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[us.canonicalCode(shortCode)]
+	if !exists || mapping.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	mapping.Description = description
+	return nil
+}
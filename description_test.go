@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateWithDescription(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/q3","custom_name":"q3cta","description":"  Q3\u0007 newsletter\nCTA  "}`)
+	expectStatus(t, rec, http.StatusCreated)
+
+	rec = serve(t, us, http.MethodGet, "/api/stats/q3cta", "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats struct {
+		Description string `json:"description"`
+	}
+	decodeBody(t, rec, &stats)
+	if stats.Description != "Q3 newsletterCTA" {
+		t.Fatalf("description = %q, want control characters stripped", stats.Description)
+	}
+
+	rec = serve(t, us, http.MethodGet, "/api/urls", "")
+	var urls []URLMapping
+	decodeBody(t, rec, &urls)
+	if len(urls) != 1 || urls[0].Description != "Q3 newsletterCTA" {
+		t.Fatalf("listing = %+v", urls)
+	}
+}
+
+func TestCreateRejectsLongDescription(t *testing.T) {
+	us := newTestShortener(t, nil)
+	body := `{"url":"https://example.com/long","description":"` + strings.Repeat("é", maxDescriptionLength+1) + `"}`
+	rec := serve(t, us, http.MethodPost, "/api/shorten", body)
+	expectStatus(t, rec, http.StatusBadRequest)
+	if !strings.Contains(rec.Body.String(), "description must be at most") {
+		t.Fatalf("body = %s", rec.Body.String())
+	}
+
+	body = `{"url":"https://example.com/long","description":"` + strings.Repeat("é", maxDescriptionLength) + `"}`
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", body), http.StatusCreated)
+}
+
+func TestPatchDescription(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/edit", CreateOptions{CustomName: "editme", Description: "draft"})
+
+	rec := serve(t, us, http.MethodPatch, "/api/urls/editme", `{"description":"final\tcopy"}`)
+	expectStatus(t, rec, http.StatusOK)
+	var mapping URLMapping
+	decodeBody(t, rec, &mapping)
+	if mapping.Description != "finalcopy" {
+		t.Fatalf("patched description = %q", mapping.Description)
+	}
+
+	// Leaving description out of the patch keeps the existing note.
+	expectStatus(t, serve(t, us, http.MethodPatch, "/api/urls/editme", `{"enabled":true}`), http.StatusOK)
+	if got, _ := us.GetStats("editme"); got.Description != "finalcopy" {
+		t.Fatalf("description after unrelated patch = %q", got.Description)
+	}
+
+	rec = serve(t, us, http.MethodPatch, "/api/urls/editme", `{"description":"`+strings.Repeat("x", maxDescriptionLength+1)+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	if got, _ := us.GetStats("editme"); got.Description != "finalcopy" {
+		t.Fatalf("rejected patch changed description to %q", got.Description)
+	}
+
+	rec = serve(t, us, http.MethodPatch, "/api/urls/editme", `{"enabled":false,"description":"`+strings.Repeat("x", maxDescriptionLength+1)+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	if got, _ := us.GetStats("editme"); !got.Enabled {
+		t.Fatal("rejected patch still disabled the link")
+	}
+
+	expectStatus(t, serve(t, us, http.MethodPatch, "/api/urls/missing", `{"description":"x"}`), http.StatusNotFound)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Device classes a link's DeviceRules can send elsewhere.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+var (
+	tabletUserAgents = regexp.MustCompile(`(?i)(ipad|tablet|kindle|silk/|playbook)`)
+	mobileUserAgents = regexp.MustCompile(`(?i)(mobi|iphone|ipod|windows phone|blackberry|opera mini)`)
+)
+
+// classifyDevice sorts a User-Agent into mobile, tablet or desktop by the
+// tokens browsers conventionally send. Android tablets omit "Mobile", so an
+// Android agent without it counts as a tablet. Anything unrecognised,
+// including an empty agent, is a desktop.
+func classifyDevice(userAgent string) string {
+	switch {
+	case tabletUserAgents.MatchString(userAgent):
+		return DeviceTablet
+	case mobileUserAgents.MatchString(userAgent):
+		return DeviceMobile
+	case strings.Contains(strings.ToLower(userAgent), "android"):
+		return DeviceTablet
+	default:
+		return DeviceDesktop
+	}
+}
+
+// normalizeDeviceRules validates rules, keyed by device class, and returns
+// them with lowercase keys and normalized destinations. Each destination
+// must pass the same checks as the link's own URL.
+func (us *URLShortener) normalizeDeviceRules(rules map[string]string) (map[string]string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(rules))
+	for device, target := range rules {
+		device = strings.ToLower(device)
+		if device != DeviceMobile && device != DeviceTablet && device != DeviceDesktop {
+			return nil, errorf(ErrInvalidDeviceRules, "unknown device '%s': must be %s, %s or %s", device, DeviceMobile, DeviceTablet, DeviceDesktop)
+		}
+		if _, duplicate := normalized[device]; duplicate {
+			return nil, errorf(ErrInvalidDeviceRules, "device '%s' is given more than once", device)
+		}
+		target, err := us.normalizeRuleDestination(device, target)
+		if err != nil {
+			return nil, err
+		}
+		normalized[device] = target
+	}
+	return normalized, nil
+}
+
+// normalizeRuleDestination checks a device or geo rule's destination the
+// way CreateShortURL checks the link's own URL. label names the rule in
+// errors.
+func (us *URLShortener) normalizeRuleDestination(label, target string) (string, error) {
+	if hasUnsafeScheme(target) {
+		return "", errorf(ErrUnsafeScheme, "%s destination: javascript:, data: and vbscript: URLs cannot be shortened", label)
+	}
+	if !validateURL(target, us.config.DefaultScheme, us.config.AllowedSchemes) {
+		return "", fmt.Errorf("%w: %s destination: %s", ErrInvalidURL, label, us.logURL(target))
+	}
+	target = us.normalizeDestination(target)
+	if !us.isDestinationAllowed(target) {
+		return "", errorf(ErrHostNotAllowed, "%s destination host is not on the allowlist: %s", label, us.logURL(target))
+	}
+	return target, nil
+}
+
+// destinationFor returns where mapping sends a visitor from country using
+// userAgent: a matching geo rule, else a matching device rule, else
+// OriginalURL. Geo rules win because they tend to carry legal requirements.
+func (mapping *URLMapping) destinationFor(country, userAgent string) string {
+	if target, ok := mapping.geoDestination(country); ok {
+		return target
+	}
+	if target, ok := mapping.DeviceRules[classifyDevice(userAgent)]; ok {
+		return target
+	}
+	return mapping.OriginalURL
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+func TestClassifyDevice(t *testing.T) {
+	for _, tt := range []struct {
+		userAgent, want string
+	}{
+		{iPhoneUA, DeviceMobile},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", DeviceMobile},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", DeviceTablet},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", DeviceTablet},
+		{desktopUA, DeviceDesktop},
+		{"", DeviceDesktop},
+	} {
+		if got := classifyDevice(tt.userAgent); got != tt.want {
+			t.Errorf("classifyDevice(%q) = %s, want %s", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceRulesRedirect(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mapping := mustCreate(t, us, "https://example.com/app", CreateOptions{
+		CustomName:  "app",
+		DeviceRules: map[string]string{"Mobile": "https://apps.example.com/store"},
+	})
+	if got := mapping.DeviceRules[DeviceMobile]; got != "https://apps.example.com/store" {
+		t.Fatalf("DeviceRules = %v, want the mobile rule under %q", mapping.DeviceRules, DeviceMobile)
+	}
+
+	for _, tt := range []struct {
+		userAgent, want string
+	}{
+		{iPhoneUA, "https://apps.example.com/store"},
+		{desktopUA, "https://example.com/app"},
+	} {
+		rec := serve(t, us, http.MethodGet, "/app", "", "User-Agent", tt.userAgent)
+		expectStatus(t, rec, http.StatusFound)
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("Location for %q = %s, want %s", tt.userAgent, got, tt.want)
+		}
+		if got := rec.Header().Get("Vary"); got != "User-Agent" {
+			t.Errorf("Vary = %q, want User-Agent", got)
+		}
+	}
+}
+
+func TestSeparateAliasKeepsDeviceRules(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.AliasStats = AliasStatsSeparate })
+	mustCreate(t, us, "https://example.com/app", CreateOptions{
+		CustomName:  "app",
+		DeviceRules: map[string]string{DeviceMobile: "https://apps.example.com/store"},
+	})
+	addAlias(t, us, "app", "get-app", http.StatusCreated)
+
+	rec := serve(t, us, http.MethodGet, "/get-app", "", "User-Agent", iPhoneUA)
+	expectStatus(t, rec, http.StatusFound)
+	if got := rec.Header().Get("Location"); got != "https://apps.example.com/store" {
+		t.Errorf("alias Location = %s, want the mobile destination", got)
+	}
+}
+
+func TestDeviceRulesValidated(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	for _, tt := range []struct {
+		name, body, code string
+	}{
+		{"unknown device", `{"url":"https://example.com","device_rules":{"watch":"https://example.com/w"}}`, CodeInvalidDeviceRules},
+		{"invalid target", `{"url":"https://example.com","device_rules":{"mobile":"not a url"}}`, CodeInvalidURL},
+		{"unsafe target", `{"url":"https://example.com","device_rules":{"desktop":"javascript:alert(1)"}}`, CodeUnsafeScheme},
+	} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", tt.body)
+		expectStatus(t, rec, http.StatusBadRequest)
+		var body api.ErrorResponse
+		decodeBody(t, rec, &body)
+		if body.ErrorCode != tt.code {
+			t.Errorf("%s: error code = %s, want %s", tt.name, body.ErrorCode, tt.code)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound           = errors.New("short URL not found")
+	ErrGone               = errors.New("short URL has been deleted")
+	ErrExpired            = errors.New("short URL has expired")
+	ErrInvalidURL         = errors.New("invalid URL provided")
+	ErrDisabled           = errors.New("short URL is disabled")
+	ErrStoreFull          = errors.New("link storage is full")
+	ErrEmptyURL           = errors.New("URL is required")
+	ErrInvalidCustomName  = errors.New("invalid custom name")
+	ErrCodeTaken          = errors.New("short code is already taken")
+	ErrReservedCode       = errors.New("short code is reserved")
+	ErrInvalidParams      = errors.New("invalid append params")
+	ErrInvalidCampaign    = errors.New("invalid campaign")
+	ErrCampaignNotFound   = errors.New("campaign not found")
+	ErrHostNotAllowed     = errors.New("destination host is not allowed")
+	ErrInvalidCodeLength  = errors.New("invalid code length")
+	ErrInvalidDescription = errors.New("invalid description")
+	ErrInvalidDelay       = errors.New("invalid delay")
+	ErrInvalidNamespace   = errors.New("invalid namespace")
+	ErrInvalidExpiry      = errors.New("invalid expiry")
+	ErrEmptyCode          = errors.New("short code is required")
+	ErrInvalidToken       = errors.New("invalid confirm token")
+	ErrUnsafeScheme       = errors.New("URL scheme is not allowed")
+	ErrInvalidHeaders     = errors.New("invalid redirect headers")
+	ErrInvalidJSON        = errors.New("invalid JSON")
+	ErrRateLimited        = errors.New("rate limit exceeded")
+	ErrInvalidPreview     = errors.New("invalid preview token")
+	ErrInvalidDeviceRules = errors.New("invalid device rules")
+	ErrInvalidGeoRules    = errors.New("invalid geo rules")
+	ErrCodesExhausted     = errors.New("no free short code")
+	ErrInvalidTimestamp   = errors.New("invalid timestamp")
+	ErrAdminRequired      = errors.New("admin API key required")
+)
+
+const (
+	CodeEmptyURL           = "EMPTY_URL"
+	CodeInvalidURL         = "INVALID_URL"
+	CodeInvalidCustomName  = "INVALID_CUSTOM_NAME"
+	CodeCodeTaken          = "CODE_TAKEN"
+	CodeReservedCode       = "RESERVED_CODE"
+	CodeInvalidParams      = "INVALID_PARAMS"
+	CodeInvalidCampaign    = "INVALID_CAMPAIGN"
+	CodeCampaignNotFound   = "CAMPAIGN_NOT_FOUND"
+	CodeHostNotAllowed     = "HOST_NOT_ALLOWED"
+	CodeInvalidCodeLength  = "INVALID_CODE_LENGTH"
+	CodeInvalidDescription = "INVALID_DESCRIPTION"
+	CodeInvalidDelay       = "INVALID_DELAY"
+	CodeInvalidNamespace   = "INVALID_NAMESPACE"
+	CodeInvalidExpiry      = "INVALID_EXPIRY"
+	CodeEmptyCode          = "EMPTY_CODE"
+	CodeInvalidToken       = "INVALID_TOKEN"
+	CodeUnsafeScheme       = "UNSAFE_SCHEME"
+	CodeInvalidHeaders     = "INVALID_HEADERS"
+	CodeInvalidJSON        = "INVALID_JSON"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeInvalidPreview     = "INVALID_PREVIEW"
+	CodeInvalidDeviceRules = "INVALID_DEVICE_RULES"
+	CodeInvalidGeoRules    = "INVALID_GEO_RULES"
+	CodeCodesExhausted     = "CODES_EXHAUSTED"
+	CodeInvalidTimestamp   = "INVALID_TIMESTAMP"
+	CodeAdminRequired      = "ADMIN_REQUIRED"
+	CodeStoreFull          = "STORE_FULL"
+	CodeNotFound           = "NOT_FOUND"
+	CodeGone               = "GONE"
+	CodeExpired            = "EXPIRED"
+	CodeDisabled           = "DISABLED"
+	CodeInternal           = "INTERNAL_ERROR"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrEmptyURL, CodeEmptyURL},
+	{ErrInvalidURL, CodeInvalidURL},
+	{ErrInvalidCustomName, CodeInvalidCustomName},
+	{ErrCodeTaken, CodeCodeTaken},
+	{ErrReservedCode, CodeReservedCode},
+	{ErrInvalidParams, CodeInvalidParams},
+	{ErrInvalidCampaign, CodeInvalidCampaign},
+	{ErrCampaignNotFound, CodeCampaignNotFound},
+	{ErrHostNotAllowed, CodeHostNotAllowed},
+	{ErrInvalidCodeLength, CodeInvalidCodeLength},
+	{ErrInvalidDescription, CodeInvalidDescription},
+	{ErrInvalidDelay, CodeInvalidDelay},
+	{ErrInvalidNamespace, CodeInvalidNamespace},
+	{ErrInvalidExpiry, CodeInvalidExpiry},
+	{ErrEmptyCode, CodeEmptyCode},
+	{ErrInvalidToken, CodeInvalidToken},
+	{ErrUnsafeScheme, CodeUnsafeScheme},
+	{ErrInvalidHeaders, CodeInvalidHeaders},
+	{ErrInvalidJSON, CodeInvalidJSON},
+	{ErrRateLimited, CodeRateLimited},
+	{ErrInvalidPreview, CodeInvalidPreview},
+	{ErrInvalidDeviceRules, CodeInvalidDeviceRules},
+	{ErrInvalidGeoRules, CodeInvalidGeoRules},
+	{ErrCodesExhausted, CodeCodesExhausted},
+	{ErrInvalidTimestamp, CodeInvalidTimestamp},
+	{ErrAdminRequired, CodeAdminRequired},
+	{ErrStoreFull, CodeStoreFull},
+	{ErrNotFound, CodeNotFound},
+	{ErrGone, CodeGone},
+	{ErrExpired, CodeExpired},
+	{ErrDisabled, CodeDisabled},
+}
+
+func errorCode(err error) string {
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+	return CodeInternal
+}
+
+// kindError carries a user-facing message while still matching its sentinel
+// kind via errors.Is.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+func errorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
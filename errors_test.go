@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestErrorResponsesCarryCodes(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxLinks = 2 })
+	mustCreate(t, us, "https://example.com/taken", CreateOptions{CustomName: "taken"})
+
+	for _, tt := range []struct {
+		name, method, target, body string
+		status                     int
+		code                       string
+	}{
+		{"invalid URL", http.MethodPost, "/api/shorten", `{"url":"not a url"}`, http.StatusBadRequest, CodeInvalidURL},
+		{"unsafe scheme", http.MethodPost, "/api/shorten", `{"url":"javascript:alert(1)"}`, http.StatusBadRequest, CodeUnsafeScheme},
+		{"code taken", http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","custom_name":"taken"}`, http.StatusConflict, CodeCodeTaken},
+		{"reserved", http.MethodPost, "/api/shorten", `{"url":"https://example.com/c","custom_name":"admin"}`, http.StatusBadRequest, CodeReservedCode},
+		{"bad JSON", http.MethodPost, "/api/shorten", `{"url":`, http.StatusBadRequest, CodeInvalidJSON},
+		{"not found", http.MethodGet, "/api/stats/missing", "", http.StatusNotFound, CodeNotFound},
+		{"no sentinel", http.MethodPost, "/api/stats/batch", `{"short_codes":[]}`, http.StatusBadRequest, "BAD_REQUEST"},
+	} {
+		rec := serve(t, us, tt.method, tt.target, tt.body)
+		expectStatus(t, rec, tt.status)
+		var body api.ErrorResponse
+		decodeBody(t, rec, &body)
+		if body.ErrorCode != tt.code || body.Error == "" {
+			t.Errorf("%s: error = %+v, want code %s and a message", tt.name, body, tt.code)
+		}
+	}
+
+	mustCreate(t, us, "https://example.com/full", CreateOptions{})
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/overflow"}`)
+	expectStatus(t, rec, http.StatusInsufficientStorage)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeStoreFull {
+		t.Fatalf("store full: error = %+v, want %s", body, CodeStoreFull)
+	}
+}
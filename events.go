@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	subscriberBufferSize = 64
+	sseKeepAliveInterval = 30 * time.Second
+)
+
+// Event types carried in ClickEvent.Type.
+const (
+	EventTypeClick   = "click"
+	EventTypeCreated = "created"
+)
+
+type ClickEvent struct {
+	Type        string    `json:"type"`
+	ShortCode   string    `json:"short_code"`
+	Namespace   string    `json:"namespace,omitempty"`
+	OriginalURL string    `json:"original_url"`
+	AccessCount int64     `json:"access_count"`
+	Timestamp   time.Time `json:"timestamp"`
+
+	// owner is the link's owner, which decides who sees the exact count.
+	owner string
+	// confirmCookie is the cookie value that reveals a guarded link's
+	// destination; empty for links without a confirm token.
+	confirmCookie string
+}
+
+type eventBroker struct {
+	mutex       sync.Mutex
+	subscribers map[<-chan ClickEvent]chan ClickEvent
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subscribers: make(map[<-chan ClickEvent]chan ClickEvent),
+	}
+}
+
+func (b *eventBroker) subscribe() <-chan ClickEvent {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ch := make(chan ClickEvent, subscriberBufferSize)
+	b.subscribers[ch] = ch
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch <-chan ClickEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if sub, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(sub)
+	}
+}
+
+func (b *eventBroker) count() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers)
+}
+
+// publish never blocks: a subscriber whose buffer is full misses the event.
+func (b *eventBroker) publish(event ClickEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, sub := range b.subscribers {
+		select {
+		case sub <- event:
+		default:
+		}
+	}
+}
+
+func (us *URLShortener) Subscribe() <-chan ClickEvent {
+	return us.events.subscribe()
+}
+
+func (us *URLShortener) Unsubscribe(ch <-chan ClickEvent) {
+	us.events.unsubscribe(ch)
+}
+
+// publishClick announces a visit to mapping. accessCount must be read when
+// the visit is recorded, not from mapping afterwards.
+func (us *URLShortener) publishClick(mapping *URLMapping, accessCount int64) {
+	us.events.publish(ClickEvent{
+		Type:          EventTypeClick,
+		ShortCode:     mapping.ShortCode,
+		Namespace:     mapping.Namespace,
+		OriginalURL:   mapping.OriginalURL,
+		AccessCount:   accessCount,
+		Timestamp:     time.Now(),
+		owner:         mapping.Owner,
+		confirmCookie: us.eventConfirmCookie(mapping),
+	})
+}
+
+// publishCreated announces a newly created link.
+func (us *URLShortener) publishCreated(mapping *URLMapping) {
+	us.events.publish(ClickEvent{
+		Type:          EventTypeCreated,
+		ShortCode:     mapping.ShortCode,
+		Namespace:     mapping.Namespace,
+		OriginalURL:   mapping.OriginalURL,
+		Timestamp:     mapping.CreatedAt,
+		owner:         mapping.Owner,
+		confirmCookie: us.eventConfirmCookie(mapping),
+	})
+}
+
+// eventVisibleTo reports whether the caller of r may receive event. Creation
+// events announce links nobody has shared yet, so with API keys configured
+// only admins get them.
+func (us *URLShortener) eventVisibleTo(r *http.Request, event ClickEvent) bool {
+	if event.Type != EventTypeCreated || !us.authEnabled() {
+		return true
+	}
+	principal, ok := principalFromContext(r.Context())
+	return ok && principal.Admin
+}
+
+// eventConfirmCookie is the confirmation cookie that unlocks mapping's
+// destination, or "" when the link has no confirm token.
+func (us *URLShortener) eventConfirmCookie(mapping *URLMapping) string {
+	if mapping.ConfirmTokenHash == "" {
+		return ""
+	}
+	return us.confirmCookieValue(mapping)
+}
+
+// eventFor returns event as the caller of r may see it, with the count
+// rounded to StatsPrecision unless they own the link or are an admin, and
+// without the destination of a guarded link they have not unlocked.
+func (us *URLShortener) eventFor(r *http.Request, event ClickEvent) ClickEvent {
+	if !us.seesExactCountsOf(r, event.owner) {
+		event.AccessCount, _ = roundCount(event.AccessCount, us.config.StatsPrecision)
+	}
+	if event.confirmCookie != "" && !callerOwns(r, event.owner) && !hasConfirmCookie(r, event.confirmCookie) {
+		event.OriginalURL = ""
+	}
+	return event
+}
+
+func (us *URLShortener) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Warning: could not clear write deadline for event stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events := us.Subscribe()
+	defer us.Unsubscribe(events)
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !us.eventVisibleTo(r, event) {
+				continue
+			}
+			data, err := json.Marshal(us.eventFor(r, event))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeReceivesClicks(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/clicked", CreateOptions{CustomName: "clicked"})
+	events := us.Subscribe()
+	defer us.Unsubscribe(events)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/clicked", ""), http.StatusMovedPermanently)
+	select {
+	case event := <-events:
+		if event.ShortCode != "clicked" || event.AccessCount != 1 {
+			t.Fatalf("event = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no click event after a redirect")
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockRedirects(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "busy"})
+	events := us.Subscribe()
+	defer us.Unsubscribe(events)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBufferSize*2; i++ {
+			serve(t, us, http.MethodGet, "/busy", "")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("redirects blocked on a subscriber that never reads")
+	}
+	if n := len(events); n != subscriberBufferSize {
+		t.Fatalf("%d events buffered, want %d with the rest dropped", n, subscriberBufferSize)
+	}
+}
+
+func TestClickEventsCountConcurrentRedirects(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "busy"})
+	events := us.Subscribe()
+	defer us.Unsubscribe(events)
+
+	const redirects = 40
+	var wg sync.WaitGroup
+	for i := 0; i < redirects; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(t, us, http.MethodGet, "/busy", "")
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for i := 0; i < redirects; i++ {
+		event := <-events
+		if event.AccessCount < 1 || event.AccessCount > redirects || seen[event.AccessCount] {
+			t.Fatalf("event %d has access count %d (seen %v)", i, event.AccessCount, seen)
+		}
+		seen[event.AccessCount] = true
+	}
+	if !seen[redirects] {
+		t.Fatalf("no event reported the final count %d", redirects)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	EvictionPolicyLRU    = "lru"
+	EvictionPolicyOldest = "oldest"
+)
+
+// ensureCapacityLocked makes room for one more mapping when MaxLinks is set,
+// evicting according to EvictionPolicy if allowed. Callers must hold the write lock.
+func (us *URLShortener) ensureCapacityLocked() error {
+	if us.config.MaxLinks <= 0 || len(us.storage) < us.config.MaxLinks {
+		return nil
+	}
+
+	// Expired links no longer redirect, so they make room before any live
+	// link is refused or evicted.
+	if purged := us.purgeExpiredLocked(time.Now()); purged > 0 {
+		log.Printf("Purged %d expired URL(s) to make room for a new link", purged)
+		if len(us.storage) < us.config.MaxLinks {
+			return nil
+		}
+	}
+
+	if !us.config.EvictWhenFull {
+		return ErrStoreFull
+	}
+
+	for len(us.storage) >= us.config.MaxLinks {
+		victim := us.evictionCandidateLocked()
+		if victim == "" {
+			return ErrStoreFull
+		}
+		evicted := us.storage[victim]
+		for _, alias := range evicted.Aliases {
+			delete(us.aliases, storageKey(evicted.Namespace, alias))
+		}
+		delete(us.storage, victim)
+		log.Printf("Evicted short code '%s' (%s policy) to stay within %d links", victim, us.config.EvictionPolicy, us.config.MaxLinks)
+	}
+	return nil
+}
+
+func (us *URLShortener) evictionCandidateLocked() string {
+	var (
+		victim     string
+		victimTime time.Time
+	)
+	for code, mapping := range us.storage {
+		candidateTime := mapping.CreatedAt
+		if last := lastAccessed(mapping); us.config.EvictionPolicy == EvictionPolicyLRU && last != nil {
+			candidateTime = *last
+		}
+		if victim == "" || candidateTime.Before(victimTime) {
+			victim, victimTime = code, candidateTime
+		}
+	}
+	return victim
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreateRejectedWhenStoreFull(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxLinks = 1 })
+	mustCreate(t, us, "https://example.com/first", CreateOptions{})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/second"}`)
+	expectStatus(t, rec, http.StatusInsufficientStorage)
+	if len(us.storage) != 1 {
+		t.Fatalf("%d links stored, want 1", len(us.storage))
+	}
+}
+
+func TestExpiredLinksMakeRoomWhenStoreFull(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxLinks = 2 })
+	past := time.Now().Add(-time.Minute)
+	for _, code := range []string{"aaa", "bbb"} {
+		mustCreate(t, us, "https://example.com/"+code, CreateOptions{CustomName: code}).ExpiresAt = &past
+	}
+
+	mustCreate(t, us, "https://example.com/c", CreateOptions{CustomName: "ccc"})
+	if len(us.storage) != 1 {
+		t.Fatalf("%d links stored, want only the new one", len(us.storage))
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/aaa", ""), http.StatusGone)
+}
+
+func TestEvictWhenFull(t *testing.T) {
+	for _, tt := range []struct {
+		policy, evicted string
+	}{
+		{EvictionPolicyLRU, "bbb"},
+		{EvictionPolicyOldest, "aaa"},
+	} {
+		us := newTestShortener(t, func(c *Config) {
+			c.MaxLinks = 2
+			c.EvictWhenFull = true
+			c.EvictionPolicy = tt.policy
+		})
+		first := mustCreate(t, us, "https://example.com/a", CreateOptions{CustomName: "aaa"})
+		second := mustCreate(t, us, "https://example.com/b", CreateOptions{CustomName: "bbb"})
+		first.CreatedAt = time.Now().Add(-2 * time.Hour)
+		second.CreatedAt = time.Now().Add(-time.Hour)
+
+		// aaa is the oldest link but the most recently used.
+		expectStatus(t, serve(t, us, http.MethodGet, "/aaa", ""), http.StatusMovedPermanently)
+		mustCreate(t, us, "https://example.com/c", CreateOptions{CustomName: "ccc"})
+
+		if _, exists := us.storage[tt.evicted]; exists || len(us.storage) != 2 {
+			t.Errorf("%s: %s still stored (%d links)", tt.policy, tt.evicted, len(us.storage))
+		}
+		if _, exists := us.storage["ccc"]; !exists {
+			t.Errorf("%s: new link not stored", tt.policy)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatJSON   = "json"
+	ExportFormatNDJSON = "ndjson"
+)
+
+const (
+	// exportChunkSize is how many links are encoded per read lock.
+	exportChunkSize = 500
+	// exportWriteTimeout bounds each chunk's write, so a stalled client
+	// cannot hold the request open forever.
+	exportWriteTimeout = 10 * time.Second
+)
+
+// exportHandler serves GET /api/export[?format=ndjson]. The codes to export
+// are listed up front; links are then encoded in chunks under the read lock,
+// and each chunk is written once the lock is released, so neither writes nor
+// counted redirects wait on a slow client. Links deleted after the listing
+// are skipped. The path is exempt from the request timeout, which would
+// otherwise buffer the whole body.
+func (us *URLShortener) exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ExportFormatJSON
+	}
+	switch format {
+	case ExportFormatJSON:
+		w.Header().Set("Content-Type", "application/json")
+	case ExportFormatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		http.Error(w, "format must be json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	write := func(chunk []byte) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+
+	camel := us.jsonOptions(r).camel
+	array := format == ExportFormatJSON
+	codes := us.exportCodes()
+
+	var buf bytes.Buffer
+	if array {
+		buf.WriteString("[\n")
+	}
+	written := 0
+	var err error
+	for start := 0; start < len(codes) && err == nil; start += exportChunkSize {
+		if err = r.Context().Err(); err != nil {
+			break
+		}
+		end := min(start+exportChunkSize, len(codes))
+		var n int
+		if n, err = us.encodeExportChunk(&buf, codes[start:end], camel, array, written); err == nil {
+			written += n
+			err = write(buf.Bytes())
+		}
+		buf.Reset()
+	}
+	if err == nil {
+		if array {
+			buf.WriteString("]\n")
+		}
+		err = write(buf.Bytes())
+	}
+	if err != nil {
+		log.Printf("Export stopped after %d link(s): %v", written, err)
+	}
+}
+
+// exportCodes lists the storage keys of every live link.
+func (us *URLShortener) exportCodes() []string {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	codes := make([]string, 0, len(us.storage))
+	for code, mapping := range us.storage {
+		if mapping.DeletedAt == nil {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// encodeExportChunk appends the links stored under codes to buf under the
+// read lock, skipping any deleted since they were listed, and returns how
+// many it wrote. prior is how many earlier chunks wrote, so array elements
+// get their commas.
+func (us *URLShortener) encodeExportChunk(buf *bytes.Buffer, codes []string, camel, array bool, prior int) (int, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	encoder := json.NewEncoder(buf)
+	written := 0
+	for _, code := range codes {
+		mapping, ok := us.storage[code]
+		if !ok || mapping.DeletedAt != nil {
+			continue
+		}
+		if array && prior+written > 0 {
+			buf.WriteString(",")
+		}
+		view := *mapping
+		view.AccessCount += us.pendingAccesses(mapping)
+		view.LastAccessedAt = lastAccessed(mapping)
+		var v interface{} = &view
+		if camel {
+			v = camelCaseValue(&view)
+		}
+		if err := encoder.Encode(v); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const exportedLinks = exportChunkSize + 3
+
+func newExportTestShortener(t *testing.T) *URLShortener {
+	t.Helper()
+	us := newAdminTestShortener(t)
+	for i := 0; i < exportedLinks; i++ {
+		mustCreate(t, us, fmt.Sprintf("https://example.com/%d", i), CreateOptions{CustomName: fmt.Sprintf("link%d", i)})
+	}
+	return us
+}
+
+func checkExportedCodes(t *testing.T, mappings []URLMapping) {
+	t.Helper()
+	if len(mappings) != exportedLinks {
+		t.Fatalf("exported %d links, want %d", len(mappings), exportedLinks)
+	}
+	codes := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		codes = append(codes, mapping.ShortCode)
+	}
+	sort.Strings(codes)
+	for i := 1; i < len(codes); i++ {
+		if codes[i] == codes[i-1] {
+			t.Fatalf("link %s exported twice", codes[i])
+		}
+	}
+}
+
+func TestExportStreamsJSONArray(t *testing.T) {
+	us := newExportTestShortener(t)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/export", ""), http.StatusUnauthorized)
+
+	rec := serve(t, us, http.MethodGet, "/api/export", "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+	var mappings []URLMapping
+	decodeBody(t, rec, &mappings)
+	checkExportedCodes(t, mappings)
+}
+
+func TestExportStreamsNDJSON(t *testing.T) {
+	us := newExportTestShortener(t)
+
+	rec := serve(t, us, http.MethodGet, "/api/export?format=ndjson", "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Fatalf("Content-Type = %q, want application/x-ndjson", got)
+	}
+
+	var mappings []URLMapping
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		var mapping URLMapping
+		if err := json.Unmarshal(scanner.Bytes(), &mapping); err != nil {
+			t.Fatalf("line %d is not a JSON object: %v", len(mappings)+1, err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	checkExportedCodes(t, mappings)
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	us := newAdminTestShortener(t)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/export?format=xml", "", "X-API-Key", "admin-key"), http.StatusBadRequest)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// finalDestination follows destination's redirect chain, following at most
+// MaxRedirectHops redirects, and returns the URL it ends at. It uses the same
+// private-address guard as title fetching.
+func (us *URLShortener) finalDestination(ctx context.Context, destination string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, us.config.FollowTimeout)
+	defer cancel()
+
+	return followChain(ctx, us.followClient, destination)
+}
+
+// followRedirectsClient is the client finalDestination uses: the title
+// fetcher's guarded client, stopping after MaxRedirectHops redirects.
+func followRedirectsClient(config Config) *http.Client {
+	client := titleFetchClient(config.FollowTimeout)
+	client.CheckRedirect = limitRedirects(config.MaxRedirectHops)
+	return client
+}
+
+// followChain requests destination with client, which decides how redirects
+// are followed, and returns the URL of the final response.
+func followChain(ctx context.Context, client *http.Client, destination string) (string, error) {
+	// Some servers reject HEAD, so retry those with GET.
+	resp, err := requestFinal(ctx, client, http.MethodHead, destination)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = requestFinal(ctx, client, http.MethodGet, destination)
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("destination returned %s", resp.Status)
+	}
+	return resp.Request.URL.String(), nil
+}
+
+// limitRedirects is a CheckRedirect policy that follows at most maxHops
+// redirects. via holds every request sent so far, so following the Nth
+// redirect is checked with N entries in via.
+func limitRedirects(maxHops int) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxHops {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+}
+
+func requestFinal(ctx context.Context, client *http.Client, method, destination string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, destination, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// resolvedDestination returns the final URL for destination, or "" when the
+// chain cannot be followed so the submitted URL is stored as is.
+func (us *URLShortener) resolvedDestination(ctx context.Context, destination string) string {
+	if !isWebScheme(destination) {
+		return ""
+	}
+
+	final, err := us.finalDestination(ctx, destination)
+	if err != nil {
+		log.Printf("Could not follow redirects for %s, storing it as submitted: %v", us.logURL(destination), err)
+		return ""
+	}
+	return final
+}
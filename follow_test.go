@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func TestLimitRedirectsStopsAtMaxHops(t *testing.T) {
+	check := limitRedirects(3)
+	var via []*http.Request
+	for hop := 1; hop <= 4; hop++ {
+		via = append(via, &http.Request{})
+		err := check(&http.Request{}, via)
+		if hop <= 3 && err != nil {
+			t.Fatalf("redirect %d refused: %v", hop, err)
+		}
+		if hop == 4 && !errors.Is(err, errTooManyRedirects) {
+			t.Fatal("redirect 4 followed with MaxRedirectHops=3")
+		}
+	}
+
+	// The minimum setting still follows one redirect.
+	if err := limitRedirects(1)(&http.Request{}, []*http.Request{{}}); err != nil {
+		t.Fatalf("MaxRedirectHops=1 refused the first redirect: %v", err)
+	}
+}
+
+func TestCreateStopsLookupsWhenClientGoesAway(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.FollowRedirects = true
+		c.FollowTimeout = 30 * time.Second
+		c.TitleSlugs = true
+		c.TitleFetchTimeout = 30 * time.Second
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(io.Discard)
+
+	start := time.Now()
+	// 203.0.113.0/24 is reserved for documentation and never answers.
+	mapping, _, err := us.createFromRequest(ctx, api.CreateURLRequest{
+		URL:             "http://203.0.113.7/slow",
+		FollowRedirects: true,
+		SlugFromTitle:   true,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("create took %s after the client went away", elapsed)
+	}
+	if mapping.OriginalURL != "http://203.0.113.7/slow" {
+		t.Fatalf("destination = %s, want the submitted URL", mapping.OriginalURL)
+	}
+	if n := strings.Count(logs.String(), context.Canceled.Error()); n != 2 {
+		t.Fatalf("want both lookups to stop on the canceled context, logs:\n%s", logs.String())
+	}
+}
+
+// newRedirectChain serves /hop/N, which redirects to /hop/N-1, down to /hop/0,
+// which answers 200. HEAD is refused when rejectHead is set.
+func newRedirectChain(t *testing.T, rejectHead bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rejectHead && r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFollowChainResolvesFinalURL(t *testing.T) {
+	for _, rejectHead := range []bool{false, true} {
+		srv := newRedirectChain(t, rejectHead)
+		client := srv.Client()
+		client.CheckRedirect = limitRedirects(5)
+
+		final, err := followChain(context.Background(), client, srv.URL+"/hop/3")
+		if err != nil {
+			t.Fatalf("rejectHead=%v: %v", rejectHead, err)
+		}
+		if final != srv.URL+"/hop/0" {
+			t.Fatalf("rejectHead=%v: final = %s", rejectHead, final)
+		}
+	}
+}
+
+func TestFollowChainErrors(t *testing.T) {
+	srv := newRedirectChain(t, false)
+	client := srv.Client()
+	client.CheckRedirect = limitRedirects(3)
+
+	if _, err := followChain(context.Background(), client, srv.URL+"/hop/3"); err != nil {
+		t.Fatalf("chain of exactly the hop limit: %v", err)
+	}
+	if _, err := followChain(context.Background(), client, srv.URL+"/hop/4"); !errors.Is(err, errTooManyRedirects) {
+		t.Fatalf("chain longer than the hop limit: err = %v", err)
+	}
+	if _, err := followChain(context.Background(), client, srv.URL+"/missing"); err == nil {
+		t.Fatal("a 404 destination was accepted")
+	}
+}
+
+func TestFollowRedirectsRefusesPrivateHosts(t *testing.T) {
+	srv := newRedirectChain(t, false)
+	us := newTestShortener(t, func(c *Config) {
+		c.FollowRedirects = true
+		c.FollowTimeout = 5 * time.Second
+	})
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"`+srv.URL+`/hop/2","follow_redirects":true}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var resp api.CreateURLResponse
+	decodeBody(t, rec, &resp)
+	if resp.OriginalURL != srv.URL+"/hop/2" {
+		t.Fatalf("destination = %s, want the submitted loopback URL kept as is", resp.OriginalURL)
+	}
+}
+
+func TestFollowRedirectsStoresFinalURL(t *testing.T) {
+	srv := newRedirectChain(t, false)
+	us := newTestShortener(t, func(c *Config) {
+		c.FollowRedirects = true
+		c.FollowTimeout = 5 * time.Second
+		c.MaxRedirectHops = 5
+	})
+	// The test server is on loopback, which the default client refuses.
+	us.followClient = srv.Client()
+	us.followClient.CheckRedirect = limitRedirects(us.config.MaxRedirectHops)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"`+srv.URL+`/hop/3","follow_redirects":true}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var resp api.CreateURLResponse
+	decodeBody(t, rec, &resp)
+	if resp.OriginalURL != srv.URL+"/hop/0" {
+		t.Fatalf("destination = %s, want the end of the chain", resp.OriginalURL)
+	}
+
+	mapping, err := us.GetStats(resp.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.OriginalURL != srv.URL+"/hop/0" || mapping.SubmittedURL != srv.URL+"/hop/3" {
+		t.Fatalf("stored %s submitted as %s, want %s submitted as %s", mapping.OriginalURL, mapping.SubmittedURL, srv.URL+"/hop/0", srv.URL+"/hop/3")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestForEachStopsEarly(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for i := 0; i < 10; i++ {
+		mustCreate(t, us, fmt.Sprintf("https://example.com/%d", i), CreateOptions{})
+	}
+
+	visited := 0
+	us.ForEach(func(*URLMapping) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Fatalf("visited %d links, want iteration to stop after 3", visited)
+	}
+
+	visited = 0
+	us.ForEach(func(*URLMapping) bool {
+		visited++
+		return true
+	})
+	if visited != 10 {
+		t.Fatalf("visited %d links, want all 10", visited)
+	}
+}
+
+func TestForEachPassesCopies(t *testing.T) {
+	us := newSoftDeleteShortener(t)
+	mustCreate(t, us, "https://example.com/keep", CreateOptions{CustomName: "keeper"})
+	mustCreate(t, us, "https://example.com/gone", CreateOptions{CustomName: "goner"})
+	if err := us.DeleteURL("goner", Principal{Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+	us.GetOriginalURL("keeper")
+
+	var seen []string
+	us.ForEach(func(mapping *URLMapping) bool {
+		seen = append(seen, mapping.ShortCode)
+		if mapping.AccessCount != 1 {
+			t.Errorf("%s view has access count %d, want buffered visits included", mapping.ShortCode, mapping.AccessCount)
+		}
+		mapping.OriginalURL = "https://evil.example"
+		return true
+	})
+	if len(seen) != 1 || seen[0] != "keeper" {
+		t.Fatalf("visited %v, want only the live link", seen)
+	}
+
+	stored, err := us.GetStats("keeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.OriginalURL != "https://example.com/keep" {
+		t.Fatalf("callback changed the stored mapping to %s", stored.OriginalURL)
+	}
+}
+
+func TestForEachCopiesShareNoMaps(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/geo", CreateOptions{CustomName: "geo"})
+	us.countClickCountry("geo", "GB")
+
+	us.ForEach(func(mapping *URLMapping) bool {
+		mapping.ClicksByCountry["GB"] = 100
+		return true
+	})
+	if clicks, _ := us.GetClicksByCountry("geo"); clicks["GB"] != 1 {
+		t.Fatalf("stored GB clicks = %d, want 1", clicks["GB"])
+	}
+}
+
+func TestListingWhileCountingCountries(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/geo", CreateOptions{CustomName: "geo"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			us.countClickCountry("geo", fmt.Sprintf("C%d", i%20))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			serve(t, us, http.MethodGet, "/api/urls", "")
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+const unknownCountry = "unknown"
+
+// geoRegions are the groups of countries a geo rule may name in place of a
+// single country code.
+var geoRegions = map[string][]string{
+	"EU": {"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU", "IE", "IT",
+		"LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK"},
+}
+
+type GeoResolver interface {
+	Country(ip net.IP) (string, error)
+}
+
+type maxMindResolver struct {
+	reader *geoip2.Reader
+}
+
+func NewMaxMindResolver(path string) (GeoResolver, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &maxMindResolver{reader: reader}, nil
+}
+
+func (m *maxMindResolver) Country(ip net.IP) (string, error) {
+	record, err := m.reader.Country(ip)
+	if err != nil {
+		return "", err
+	}
+	return record.Country.IsoCode, nil
+}
+
+func (us *URLShortener) lookupCountry(ip net.IP) string {
+	if us.geo == nil || ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() {
+		return unknownCountry
+	}
+
+	country, err := us.geo.Country(ip)
+	if err != nil || country == "" {
+		return unknownCountry
+	}
+	return country
+}
+
+// recordClickCountry is run off the redirect path so a slow or broken
+// database never delays or fails the redirect itself. It returns the country
+// it counted the click for.
+func (us *URLShortener) recordClickCountry(shortCode string, ip net.IP) string {
+	country := us.lookupCountry(ip)
+	us.countClickCountry(shortCode, country)
+	return country
+}
+
+// countClickCountry counts a click from country, for redirects that have
+// looked it up already.
+func (us *URLShortener) countClickCountry(shortCode, country string) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[shortCode]
+	if !exists {
+		return
+	}
+	if mapping.ClicksByCountry == nil {
+		mapping.ClicksByCountry = make(map[string]int64)
+	}
+	mapping.ClicksByCountry[country]++
+}
+
+func (us *URLShortener) GetClicksByCountry(shortCode string) (map[string]int64, error) {
+	clicks, _, err := us.clicksByCountry(shortCode)
+	return clicks, err
+}
+
+// publicClicksByCountry is GetClicksByCountry with the counts rounded to
+// StatsPrecision for callers who may not see exact values.
+func (us *URLShortener) publicClicksByCountry(r *http.Request, shortCode string) (map[string]int64, error) {
+	clicks, owner, err := us.clicksByCountry(shortCode)
+	if err != nil || us.seesExactCountsOf(r, owner) {
+		return clicks, err
+	}
+	for country, count := range clicks {
+		clicks[country], _ = roundCount(count, us.config.StatsPrecision)
+	}
+	return clicks, nil
+}
+
+// clicksByCountry returns a copy of shortCode's per-country counts and the
+// link's owner.
+func (us *URLShortener) clicksByCountry(shortCode string) (map[string]int64, string, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	mapping, exists := us.storage[us.followAliasLocked(us.canonicalCode(shortCode))]
+	if !exists || mapping.DeletedAt != nil {
+		return nil, "", ErrNotFound
+	}
+
+	clicks := make(map[string]int64, len(mapping.ClicksByCountry))
+	for country, count := range mapping.ClicksByCountry {
+		clicks[country] = count
+	}
+	return clicks, mapping.Owner, nil
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, char := range code {
+		if char < 'A' || char > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeGeoRules validates rules, keyed by ISO 3166-1 alpha-2 country
+// code or a geoRegions name, and returns them with uppercase keys and
+// normalized destinations.
+func (us *URLShortener) normalizeGeoRules(rules map[string]string) (map[string]string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(rules))
+	for country, target := range rules {
+		country = strings.ToUpper(country)
+		if _, region := geoRegions[country]; !region && !isCountryCode(country) {
+			return nil, errorf(ErrInvalidGeoRules, "invalid country '%s': must be a two-letter country code or EU", country)
+		}
+		if _, duplicate := normalized[country]; duplicate {
+			return nil, errorf(ErrInvalidGeoRules, "country '%s' is given more than once", country)
+		}
+		target, err := us.normalizeRuleDestination(country, target)
+		if err != nil {
+			return nil, err
+		}
+		normalized[country] = target
+	}
+	return normalized, nil
+}
+
+// geoDestination returns the geo rule for country, preferring a rule for the
+// country itself over one for a region containing it.
+func (mapping *URLMapping) geoDestination(country string) (string, bool) {
+	if len(mapping.GeoRules) == 0 || country == unknownCountry {
+		return "", false
+	}
+	if target, ok := mapping.GeoRules[country]; ok {
+		return target, true
+	}
+	for region, members := range geoRegions {
+		if target, ok := mapping.GeoRules[region]; ok && slices.Contains(members, country) {
+			return target, true
+		}
+	}
+	return "", false
+}
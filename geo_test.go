@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+// fakeGeo resolves the IPs in its map and fails for any other.
+type fakeGeo map[string]string
+
+func (g fakeGeo) Country(ip net.IP) (string, error) {
+	if country, ok := g[ip.String()]; ok {
+		return country, nil
+	}
+	return "", errors.New("not in database")
+}
+
+// countingGeo is fakeGeo that counts its lookups.
+type countingGeo struct {
+	fakeGeo
+	lookups atomic.Int64
+}
+
+func (g *countingGeo) Country(ip net.IP) (string, error) {
+	g.lookups.Add(1)
+	return g.fakeGeo.Country(ip)
+}
+
+func newGeoTestShortener(t *testing.T) *URLShortener {
+	us := newTestShortener(t, func(c *Config) { c.TrustProxyHeaders = true })
+	us.geo = fakeGeo{"81.2.69.142": "GB", "216.160.83.56": "US"}
+	return us
+}
+
+func TestClicksByCountryBuckets(t *testing.T) {
+	us := newGeoTestShortener(t)
+	mustCreate(t, us, "https://example.com/geo", CreateOptions{CustomName: "geo"})
+
+	for _, ip := range []string{"81.2.69.142", "81.2.69.142", "216.160.83.56", "10.0.0.1", "198.51.100.9"} {
+		us.recordClickCountry("geo", net.ParseIP(ip))
+	}
+	us.recordClickCountry("geo", nil)
+
+	clicks, err := us.GetClicksByCountry("geo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"GB": 2, "US": 1, unknownCountry: 3}
+	for country, count := range want {
+		if clicks[country] != count {
+			t.Errorf("%s = %d, want %d (all: %v)", country, clicks[country], count, clicks)
+		}
+	}
+}
+
+func TestRedirectRecordsCountryInBackground(t *testing.T) {
+	us := newGeoTestShortener(t)
+	mustCreate(t, us, "https://example.com/geo", CreateOptions{CustomName: "geo"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/geo", "", "X-Forwarded-For", "81.2.69.142"), http.StatusMovedPermanently)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec := serve(t, us, http.MethodGet, "/api/stats/geo/geo", "")
+		expectStatus(t, rec, http.StatusOK)
+		var body struct {
+			ClicksByCountry map[string]int64 `json:"clicks_by_country"`
+		}
+		decodeBody(t, rec, &body)
+		if body.ClicksByCountry["GB"] == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("clicks_by_country = %v, want GB: 1", body.ClicksByCountry)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGeoRulesRedirect(t *testing.T) {
+	us := newGeoTestShortener(t)
+	us.geo = fakeGeo{"81.2.69.142": "GB", "216.160.83.56": "US", "5.9.0.1": "DE"}
+	mapping := mustCreate(t, us, "https://example.com/offer", CreateOptions{
+		CustomName: "offer",
+		GeoRules: map[string]string{
+			"gb": "https://example.co.uk/offer",
+			"EU": "https://example.eu/offer",
+		},
+		DeviceRules: map[string]string{DeviceMobile: "https://apps.example.com/offer"},
+	})
+	if _, ok := mapping.GeoRules["GB"]; !ok {
+		t.Fatalf("GeoRules = %v, want country codes in upper case", mapping.GeoRules)
+	}
+
+	for _, tt := range []struct {
+		name, ip, userAgent, want string
+	}{
+		{"country rule", "81.2.69.142", "", "https://example.co.uk/offer"},
+		{"region rule", "5.9.0.1", "", "https://example.eu/offer"},
+		{"geo rule before device rule", "5.9.0.1", iPhoneUA, "https://example.eu/offer"},
+		{"unmatched country", "216.160.83.56", "", "https://example.com/offer"},
+		{"unknown country", "198.51.100.9", "", "https://example.com/offer"},
+		{"unknown country, device rule", "198.51.100.9", iPhoneUA, "https://apps.example.com/offer"},
+	} {
+		rec := serve(t, us, http.MethodGet, "/offer", "", "X-Forwarded-For", tt.ip, "User-Agent", tt.userAgent)
+		expectStatus(t, rec, http.StatusFound)
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s: Location = %s, want %s", tt.name, got, tt.want)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "private" {
+			t.Errorf("%s: Cache-Control = %q, want private", tt.name, got)
+		}
+	}
+}
+
+func TestGeoRoutedRedirectLooksUpCountryOnce(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.TrustProxyHeaders = true
+		c.AliasStats = AliasStatsSeparate
+	})
+	geo := &countingGeo{fakeGeo: fakeGeo{"81.2.69.142": "GB"}}
+	us.geo = geo
+	mustCreate(t, us, "https://example.com/offer", CreateOptions{
+		CustomName: "offer",
+		GeoRules:   map[string]string{"GB": "https://example.co.uk/offer"},
+	})
+	addAlias(t, us, "offer", "deal", http.StatusCreated)
+
+	rec := serve(t, us, http.MethodGet, "/deal", "", "X-Forwarded-For", "81.2.69.142")
+	expectStatus(t, rec, http.StatusFound)
+	if got := rec.Header().Get("Location"); got != "https://example.co.uk/offer" {
+		t.Fatalf("alias Location = %s, want the GB destination", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		clicks, err := us.GetClicksByCountry("deal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clicks["GB"] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("clicks_by_country = %v, want GB: 1", clicks)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := geo.lookups.Load(); n != 1 {
+		t.Fatalf("GeoIP lookups = %d, want 1", n)
+	}
+}
+
+func TestGeoRulesValidated(t *testing.T) {
+	us := newGeoTestShortener(t)
+
+	for _, tt := range []struct {
+		name, body, code string
+	}{
+		{"invalid country", `{"url":"https://example.com","geo_rules":{"GBR":"https://example.co.uk"}}`, CodeInvalidGeoRules},
+		{"invalid target", `{"url":"https://example.com","geo_rules":{"GB":"not a url"}}`, CodeInvalidURL},
+		{"unsafe target", `{"url":"https://example.com","geo_rules":{"US":"javascript:alert(1)"}}`, CodeUnsafeScheme},
+	} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", tt.body)
+		expectStatus(t, rec, http.StatusBadRequest)
+		var body api.ErrorResponse
+		decodeBody(t, rec, &body)
+		if body.ErrorCode != tt.code {
+			t.Errorf("%s: error code = %s, want %s", tt.name, body.ErrorCode, tt.code)
+		}
+	}
+}
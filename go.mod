@@ -2,4 +2,15 @@ module url-shortener
 
 go 1.21
 
-require github.com/gorilla/mux v1.8.0
+require (
+	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/oschwald/geoip2-golang v1.9.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/crypto v0.21.0
+)
+
+require (
+	github.com/oschwald/maxminddb-golang v1.11.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+)
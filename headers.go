@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	maxRedirectHeaders     = 10
+	maxRedirectHeadersSize = 2048
+)
+
+// protectedRedirectHeaders are set by the server itself or change how the
+// response is framed, so links may not supply them.
+var protectedRedirectHeaders = map[string]bool{
+	"Cache-Control":     true,
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Link":              true,
+	"Location":          true,
+	"Set-Cookie":        true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// isHeaderToken reports whether name is a valid HTTP header field name
+// (RFC 9110 token).
+func isHeaderToken(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, char := range name {
+		if !((char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') ||
+			strings.ContainsRune("!#$%&'*+-.^_`|~", char)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isHeaderValue reports whether value can be sent as a header value without
+// starting a new header or line: visible ASCII, spaces and tabs only.
+func isHeaderValue(value string) bool {
+	for _, char := range value {
+		if char != '\t' && (char < ' ' || char > '~') {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeRedirectHeaders validates headers and returns them keyed by
+// canonical name.
+func normalizeRedirectHeaders(headers map[string]string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if len(headers) > maxRedirectHeaders {
+		return nil, errorf(ErrInvalidHeaders, "at most %d redirect headers are allowed", maxRedirectHeaders)
+	}
+
+	normalized := make(map[string]string, len(headers))
+	size := 0
+	for name, value := range headers {
+		if !isHeaderToken(name) {
+			return nil, errorf(ErrInvalidHeaders, "invalid redirect header name '%s'", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if protectedRedirectHeaders[canonical] {
+			return nil, errorf(ErrInvalidHeaders, "redirect header '%s' is set by the server", canonical)
+		}
+		if _, duplicate := normalized[canonical]; duplicate {
+			return nil, errorf(ErrInvalidHeaders, "redirect header '%s' is given more than once", canonical)
+		}
+		if !isHeaderValue(value) {
+			return nil, errorf(ErrInvalidHeaders, "value for redirect header '%s' contains control or non-ASCII characters", canonical)
+		}
+		size += len(canonical) + len(value)
+		normalized[canonical] = value
+	}
+	if size > maxRedirectHeadersSize {
+		return nil, errorf(ErrInvalidHeaders, "redirect headers total more than %d bytes", maxRedirectHeadersSize)
+	}
+	return normalized, nil
+}
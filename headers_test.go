@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedirectHeadersSentWithRedirect(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/promo","custom_name":"promo","redirect_headers":{"x-campaign-id":"spring-24","X-Partner":"acme"}}`)
+	expectStatus(t, rec, http.StatusCreated)
+
+	rec = serve(t, us, http.MethodGet, "/promo", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("X-Campaign-Id"); got != "spring-24" {
+		t.Fatalf("X-Campaign-ID = %q, want spring-24", got)
+	}
+	if got := rec.Header().Get("X-Partner"); got != "acme" {
+		t.Fatalf("X-Partner = %q, want acme", got)
+	}
+	if got := rec.Header().Get("Location"); got != "https://example.com/promo" {
+		t.Fatalf("Location = %q", got)
+	}
+}
+
+func TestRedirectHeadersRejected(t *testing.T) {
+	us := newTestShortener(t, nil)
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxRedirectHeaders; i++ {
+		tooMany["X-H"+strings.Repeat("x", i)] = "v"
+	}
+
+	for name, headers := range map[string]map[string]string{
+		"CRLF in value":    {"X-Tag": "ok\r\nSet-Cookie: stolen=1"},
+		"newline in value": {"X-Tag": "ok\nX-Evil: 1"},
+		"CRLF in name":     {"X-Tag\r\nX-Evil": "1"},
+		"space in name":    {"X Tag": "1"},
+		"protected name":   {"location": "https://evil.example"},
+		"duplicate names":  {"X-Tag": "a", "x-tag": "b"},
+		"too many":         tooMany,
+		"too large":        {"X-Big": strings.Repeat("a", maxRedirectHeadersSize)},
+	} {
+		_, _, err := us.CreateShortURL("https://example.com/"+strings.ReplaceAll(name, " ", "-"), CreateOptions{RedirectHeaders: headers})
+		if !errors.Is(err, ErrInvalidHeaders) {
+			t.Errorf("%s: error = %v, want ErrInvalidHeaders", name, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// markReady flags startup as complete; until then the readiness probe fails
+// while liveness keeps reporting the process as up.
+func (us *URLShortener) markReady() {
+	us.ready.Store(true)
+}
+
+// storageReachable reports whether the store can serve requests. The
+// in-memory map is always reachable once constructed.
+func (us *URLShortener) storageReachable() bool {
+	return us.storage != nil
+}
+
+func (us *URLShortener) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]string{
+		"status":  "alive",
+		"service": "URL Shortener",
+		"time":    time.Now().Format(time.RFC3339),
+	}
+	writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+}
+
+// readinessHandler also serves /api/health for backward compatibility.
+func (us *URLShortener) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]string{
+		"status":  "healthy",
+		"service": "URL Shortener",
+		"time":    time.Now().Format(time.RFC3339),
+	}
+
+	status := http.StatusOK
+	if !us.ready.Load() || !us.storageReachable() {
+		response["status"] = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response, us.jsonOptions(r))
+}
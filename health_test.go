@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReadinessWaitsForStartup(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/health/live", ""), http.StatusOK)
+	for _, path := range []string{"/api/health/ready", "/api/health"} {
+		rec := serve(t, us, http.MethodGet, path, "")
+		expectStatus(t, rec, http.StatusServiceUnavailable)
+		var body map[string]string
+		decodeBody(t, rec, &body)
+		if body["status"] != "not ready" {
+			t.Fatalf("%s status = %q before startup finished", path, body["status"])
+		}
+	}
+
+	us.markReady()
+	for _, path := range []string{"/api/health/live", "/api/health/ready", "/api/health"} {
+		expectStatus(t, serve(t, us, http.MethodGet, path, ""), http.StatusOK)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+const testBaseURL = "http://short.test"
+
+// newTestShortener returns a shortener built from the default config, after
+// configure (if any) has adjusted it.
+func newTestShortener(t testing.TB, configure func(*Config)) *URLShortener {
+	t.Helper()
+	config := DefaultConfig()
+	config.BaseURL = testBaseURL
+	if configure != nil {
+		configure(&config)
+	}
+	return NewURLShortener(config)
+}
+
+// serve sends a request through the full router. Headers are given as
+// name, value pairs.
+func serve(t *testing.T, us *URLShortener, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	us.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+// mustCreate creates a link for url with opts, failing the test on error.
+func mustCreate(t testing.TB, us *URLShortener, url string, opts CreateOptions) *URLMapping {
+	t.Helper()
+	mapping, _, err := us.CreateShortURL(url, opts)
+	if err != nil {
+		t.Fatalf("CreateShortURL(%q): %v", url, err)
+	}
+	return mapping
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"time"
+
+	"url-shortener/api"
+)
+
+// HistoryRetention bounds the click history kept for each link: at most
+// MaxEvents clicks, none older than MaxAge. A zero limit is no limit, and
+// with both zero no history is kept, only the counters.
+type HistoryRetention struct {
+	MaxEvents int
+	MaxAge    time.Duration
+}
+
+func (h HistoryRetention) enabled() bool {
+	return h.MaxEvents > 0 || h.MaxAge > 0
+}
+
+// trim drops the clicks outside the retention window from the front of
+// clicks, reusing its backing array so the history never outgrows MaxEvents.
+func (h HistoryRetention) trim(clicks []time.Time, now time.Time) []time.Time {
+	drop := 0
+	if h.MaxAge > 0 {
+		cutoff := now.Add(-h.MaxAge)
+		for drop < len(clicks) && !clicks[drop].After(cutoff) {
+			drop++
+		}
+	}
+	if h.MaxEvents > 0 && len(clicks)-drop > h.MaxEvents {
+		drop = len(clicks) - h.MaxEvents
+	}
+	if drop == 0 {
+		return clicks
+	}
+	return clicks[:copy(clicks, clicks[drop:])]
+}
+
+// recordClickLocked adds a click at now to mapping's history.
+func (us *URLShortener) recordClickLocked(mapping *URLMapping, now time.Time) {
+	if !us.keepsClickHistory(mapping) {
+		return
+	}
+	mapping.clicks = us.config.ClickHistoryRetention.trim(append(mapping.clicks, now), now)
+}
+
+// keepsClickHistory reports whether visits to mapping are added to its click
+// history.
+func (us *URLShortener) keepsClickHistory(mapping *URLMapping) bool {
+	return us.config.ClickHistoryRetention.enabled() && !mapping.CountsOnly
+}
+
+// trimClickHistory applies MaxAge to links that have not been clicked
+// lately, whose history recordClickLocked has not had a chance to trim.
+func (us *URLShortener) trimClickHistory(now time.Time) {
+	retention := us.config.ClickHistoryRetention
+	if retention.MaxAge <= 0 {
+		return
+	}
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	for _, mapping := range us.storage {
+		if len(mapping.clicks) == 0 {
+			continue
+		}
+		if mapping.clicks = retention.trim(mapping.clicks, now); len(mapping.clicks) == 0 {
+			mapping.clicks = nil
+		}
+	}
+}
+
+// historyRetention reports the retention that applies to mapping, or nil
+// when no click history is kept at all.
+func (us *URLShortener) historyRetention(mapping *URLMapping) *api.HistoryRetention {
+	if mapping.CountsOnly {
+		return &api.HistoryRetention{CountsOnly: true}
+	}
+	retention := us.config.ClickHistoryRetention
+	if !retention.enabled() {
+		return nil
+	}
+	return &api.HistoryRetention{
+		MaxEvents:     retention.MaxEvents,
+		MaxAgeSeconds: int64(retention.MaxAge / time.Second),
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func linkStats(t *testing.T, us *URLShortener, code string) api.URLStats {
+	t.Helper()
+	rec := serve(t, us, http.MethodGet, "/api/stats/"+code, "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.URLStats
+	decodeBody(t, rec, &stats)
+	return stats
+}
+
+func TestClickHistoryTrimmedByCount(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ClickHistoryRetention = HistoryRetention{MaxEvents: 3} })
+	mapping := mustCreate(t, us, "https://example.com/a", CreateOptions{})
+
+	for i := 0; i < 5; i++ {
+		us.GetOriginalURL(mapping.ShortCode)
+	}
+
+	stats := linkStats(t, us, mapping.ShortCode)
+	if len(stats.RecentClicks) != 3 || stats.AccessCount != 5 {
+		t.Errorf("history = %d click(s), access count = %d; want 3 and 5", len(stats.RecentClicks), stats.AccessCount)
+	}
+	if stats.Retention == nil || stats.Retention.MaxEvents != 3 {
+		t.Errorf("retention = %+v, want max_events 3", stats.Retention)
+	}
+}
+
+func TestClickHistoryTrimmedByAge(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ClickHistoryRetention = HistoryRetention{MaxAge: time.Hour} })
+	mapping := mustCreate(t, us, "https://example.com/a", CreateOptions{})
+
+	us.GetOriginalURL(mapping.ShortCode)
+	us.GetOriginalURL(mapping.ShortCode)
+	if stats := linkStats(t, us, mapping.ShortCode); len(stats.RecentClicks) != 2 {
+		t.Fatalf("history = %d click(s), want 2", len(stats.RecentClicks))
+	}
+
+	us.sweep(time.Now().Add(2 * time.Hour))
+
+	stats := linkStats(t, us, mapping.ShortCode)
+	if len(stats.RecentClicks) != 0 || stats.AccessCount != 2 {
+		t.Errorf("after the window: history = %d click(s), access count = %d; want 0 and 2", len(stats.RecentClicks), stats.AccessCount)
+	}
+	if stats.Retention == nil || stats.Retention.MaxAgeSeconds != 3600 {
+		t.Errorf("retention = %+v, want max_age_seconds 3600", stats.Retention)
+	}
+}
+
+func TestCountsOnlyLinkKeepsNoHistory(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ClickHistoryRetention = HistoryRetention{MaxEvents: 10} })
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/a","counts_only":true}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var created api.CreateURLDetails
+	decodeBody(t, rec, &created)
+
+	us.GetOriginalURL(created.ShortCode)
+
+	stats := linkStats(t, us, created.ShortCode)
+	if len(stats.RecentClicks) != 0 || stats.AccessCount != 1 {
+		t.Errorf("history = %d click(s), access count = %d; want 0 and 1", len(stats.RecentClicks), stats.AccessCount)
+	}
+	if stats.Retention == nil || !stats.Retention.CountsOnly {
+		t.Errorf("retention = %+v, want counts_only", stats.Retention)
+	}
+}
+
+func TestSeparateAliasOfCountsOnlyLinkKeepsNoHistory(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.ClickHistoryRetention = HistoryRetention{MaxEvents: 10}
+		c.AliasStats = AliasStatsSeparate
+	})
+	mustCreate(t, us, "https://example.com/a", CreateOptions{CustomName: "quiet", CountsOnly: true})
+	addAlias(t, us, "quiet", "hushed", http.StatusCreated)
+
+	us.GetOriginalURL("hushed")
+
+	if stats := linkStats(t, us, "hushed"); len(stats.RecentClicks) != 0 || stats.Retention == nil || !stats.Retention.CountsOnly {
+		t.Errorf("alias history = %d click(s), retention = %+v; want none and counts_only", len(stats.RecentClicks), stats.Retention)
+	}
+}
+
+func TestClickHistoryReadWhileRedirecting(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ClickHistoryRetention = HistoryRetention{MaxEvents: 10} })
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "busy"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			us.GetOriginalURL("busy")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			serve(t, us, http.MethodGet, "/api/stats/busy", "")
+		}
+	}()
+	wg.Wait()
+
+	if stats := linkStats(t, us, "busy"); len(stats.RecentClicks) != 10 {
+		t.Errorf("history = %d click(s), want 10", len(stats.RecentClicks))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"url-shortener/api"
+)
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	errIdempotencyKeyReused    = errors.New("idempotency key was already used with a different request")
+	errIdempotencyInProgress   = errors.New("a request with this idempotency key is still in progress")
+	errIdempotencyKeyMalformed = errors.New("idempotency key is too long")
+)
+
+type idempotentResponse struct {
+	fingerprint string
+	expiresAt   time.Time
+	pending     bool
+
+	status   int
+	location string
+	details  api.CreateURLDetails
+}
+
+// idempotencyCache remembers create responses by Idempotency-Key so retried
+// requests replay the original result instead of creating another link.
+type idempotencyCache struct {
+	mutex      sync.Mutex
+	entries    map[string]*idempotentResponse
+	ttl        time.Duration
+	maxEntries int
+}
+
+func newIdempotencyCache(ttl time.Duration, maxEntries int) *idempotencyCache {
+	return &idempotencyCache{
+		entries:    make(map[string]*idempotentResponse),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// begin returns the cached response for key, or reserves key for a new request
+// and returns nil. A key reused with a different request, or one whose first
+// request has not finished yet, is an error.
+func (c *idempotencyCache) begin(key, fingerprint string, now time.Time) (*idempotentResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, exists := c.entries[key]; exists && now.Before(entry.expiresAt) {
+		if entry.fingerprint != fingerprint {
+			return nil, errIdempotencyKeyReused
+		}
+		if entry.pending {
+			return nil, errIdempotencyInProgress
+		}
+		return entry, nil
+	}
+
+	c.makeRoomLocked(now)
+	c.entries[key] = &idempotentResponse{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(c.ttl),
+		pending:     true,
+	}
+	return nil, nil
+}
+
+func (c *idempotencyCache) complete(key string, status int, location string, details api.CreateURLDetails) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, exists := c.entries[key]; exists {
+		entry.pending = false
+		entry.status, entry.location, entry.details = status, location, details
+	}
+}
+
+// abandon releases a reserved key after a failed request so it can be retried.
+func (c *idempotencyCache) abandon(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
+
+func (c *idempotencyCache) makeRoomLocked(now time.Time) {
+	if c.maxEntries <= 0 || len(c.entries) < c.maxEntries {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	for len(c.entries) >= c.maxEntries {
+		var (
+			oldest     string
+			oldestTime time.Time
+		)
+		for key, entry := range c.entries {
+			if oldest == "" || entry.expiresAt.Before(oldestTime) {
+				oldest, oldestTime = key, entry.expiresAt
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+func requestFingerprint(req api.CreateURLRequest) string {
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyKey reads the Idempotency-Key header, scoped to the caller so
+// different owners never share cached responses.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return "", nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", errIdempotencyKeyMalformed
+	}
+	return ownerFromRequest(r) + "\x00" + key, nil
+}
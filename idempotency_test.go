@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIdempotencyKeyReplaysCreate(t *testing.T) {
+	us := newTestShortener(t, nil)
+	body := `{"url":"https://example.com/retry","custom_name":"retried"}`
+
+	first := serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "req-1")
+	expectStatus(t, first, http.StatusCreated)
+
+	// Without the key a retry only finds the existing link; with it the
+	// original response is replayed exactly.
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", body), http.StatusOK)
+	retry := serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "req-1")
+	expectStatus(t, retry, http.StatusCreated)
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("retry was not marked as replayed")
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Fatalf("replay differs:\n%s\n%s", first.Body.String(), retry.Body.String())
+	}
+	if n := countLinksTo(us, "https://example.com/retry"); n != 1 {
+		t.Fatalf("%d links created, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyReusedForDifferentRequest(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/one"}`, "Idempotency-Key", "k"), http.StatusCreated)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/two"}`, "Idempotency-Key", "k"), http.StatusUnprocessableEntity)
+}
+
+func TestIdempotencyKeyReleasedAfterFailure(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/taken", CreateOptions{CustomName: "taken"})
+	body := `{"url":"https://example.com/other","custom_name":"taken"}`
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "k"), http.StatusConflict)
+
+	if err := us.DeleteURL("taken", Principal{Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "k"), http.StatusCreated)
+}
+
+type panickingGenerator struct{}
+
+func (panickingGenerator) Generate() string { panic("generator failed") }
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.CodeGenerator = panickingGenerator{} })
+	body := `{"url":"https://example.com/panic"}`
+
+	func() {
+		defer func() { recover() }()
+		serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "k")
+	}()
+
+	us.codeGenerator = RandomCodeGenerator{Length: 6, Charset: lowerCaseCharset}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", body, "Idempotency-Key", "k"), http.StatusCreated)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	NonHTTPBehaviorLanding  = "landing"
+	NonHTTPBehaviorRedirect = "redirect"
+)
+
+// landingTemplate is served instead of a 301 for destinations such as ftp://
+// that browsers will not follow from an HTTP redirect.
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Continue to {{.Scheme}} link</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<main class="container">
+<h1>This link opens outside the web</h1>
+<p>The short link points to a <strong>{{.Scheme}}</strong> address, which your browser may hand to another application.</p>
+<p><a href="{{.Destination}}" rel="noopener noreferrer">{{.Destination}}</a></p>
+</main>
+</body>
+</html>
+`))
+
+const maxDelaySeconds = 30
+
+// countdownTemplate shows a countdown before continuing to the destination.
+// The meta refresh covers clients without JavaScript.
+var countdownTemplate = template.Must(template.New("countdown").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="{{.Delay}};url={{.Destination}}">
+<title>Redirecting in {{.Delay}} seconds</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<main class="container">
+<h1>You will be redirected in <span id="countdown">{{.Delay}}</span> seconds</h1>
+<p><a href="{{.Destination}}" rel="noopener noreferrer">Continue now to {{.Destination}}</a></p>
+</main>
+<script>
+(function () {
+  var remaining = {{.Delay}};
+  var destination = {{.Destination}};
+  var counter = document.getElementById("countdown");
+  var timer = setInterval(function () {
+    remaining -= 1;
+    counter.textContent = Math.max(remaining, 0);
+    if (remaining <= 0) {
+      clearInterval(timer);
+      window.location.replace(destination);
+    }
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+type countdownPage struct {
+	Delay       int
+	Destination string
+}
+
+func validateDelay(delay int) error {
+	if delay < 0 || delay > maxDelaySeconds {
+		return errorf(ErrInvalidDelay, "delay_seconds must be between 0 and %d", maxDelaySeconds)
+	}
+	return nil
+}
+
+// serveCountdown renders the countdown page for a link with DelaySeconds set.
+func serveCountdown(w http.ResponseWriter, r *http.Request, destination string, delay int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	countdownTemplate.Execute(w, countdownPage{Delay: delay, Destination: destination})
+}
+
+type landingPage struct {
+	Scheme      string
+	Destination template.URL
+}
+
+func isWebScheme(destination string) bool {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// serveLanding renders the landing page for a non-HTTP destination. The
+// destination has already been validated against AllowedSchemes, so it is
+// safe to emit as a link.
+func serveLanding(w http.ResponseWriter, r *http.Request, destination string) {
+	u, _ := url.Parse(destination)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	landingTemplate.Execute(w, landingPage{
+		Scheme:      strings.ToLower(u.Scheme),
+		Destination: template.URL(destination),
+	})
+}
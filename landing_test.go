@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newFTPTestShortener(t *testing.T, behavior string) *URLShortener {
+	us := newTestShortener(t, func(c *Config) {
+		c.AllowedSchemes = []string{"http", "https", "ftp"}
+		c.NonHTTPBehavior = behavior
+	})
+	mustCreate(t, us, "ftp://files.example.com/pub/readme.txt", CreateOptions{CustomName: "ftplink"})
+	mustCreate(t, us, "https://example.com/web", CreateOptions{CustomName: "weblink"})
+	return us
+}
+
+func TestFTPLinkServesLandingPage(t *testing.T) {
+	us := newFTPTestShortener(t, NonHTTPBehaviorLanding)
+
+	rec := serve(t, us, http.MethodGet, "/ftplink", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Location") != "" {
+		t.Fatal("landing page also sent a Location header")
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `href="ftp://files.example.com/pub/readme.txt"`) || !strings.Contains(body, "<strong>ftp</strong>") {
+		t.Fatalf("landing page lacks the ftp link:\n%s", body)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/weblink", ""), http.StatusMovedPermanently)
+}
+
+func TestFTPLinkRedirectMode(t *testing.T) {
+	us := newFTPTestShortener(t, NonHTTPBehaviorRedirect)
+	rec := serve(t, us, http.MethodGet, "/ftplink", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("Location"); got != "ftp://files.example.com/pub/readme.txt" {
+		t.Fatalf("Location = %q", got)
+	}
+}
+
+func TestFTPRejectedByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	if _, _, err := us.CreateShortURL("ftp://files.example.com/pub", CreateOptions{}); err == nil {
+		t.Fatal("ftp accepted with the default AllowedSchemes")
+	}
+}
+
+func TestDelayedLinkServesCountdown(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/promo?a=1&b=2", CreateOptions{CustomName: "waitup", DelaySeconds: 5})
+
+	rec := serve(t, us, http.MethodGet, "/waitup", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Location") != "" {
+		t.Fatal("countdown page also sent a Location header")
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`content="5;url=https://example.com/promo?a=1&amp;b=2"`,
+		`href="https://example.com/promo?a=1&amp;b=2"`,
+		`<span id="countdown">5</span>`,
+		`var remaining =  5 ;`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("countdown page lacks %s:\n%s", want, body)
+		}
+	}
+	if got := accessCount(t, us, "waitup"); got != 1 {
+		t.Fatalf("access count = %d, want 1", got)
+	}
+}
+
+func TestDelayZeroRedirectsImmediately(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/now", CreateOptions{CustomName: "nowait"})
+
+	rec := serve(t, us, http.MethodGet, "/nowait", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("Location"); got != "https://example.com/now" {
+		t.Fatalf("Location = %q", got)
+	}
+}
+
+func TestDelayOutOfRangeRejected(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for _, delay := range []string{"-1", "31"} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/slow","delay_seconds":`+delay+`}`)
+		expectStatus(t, rec, http.StatusBadRequest)
+		if !strings.Contains(rec.Body.String(), "delay_seconds must be between") {
+			t.Fatalf("delay %s: body = %s", delay, rec.Body.String())
+		}
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/slow","delay_seconds":30}`), http.StatusCreated)
+}
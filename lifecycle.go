@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"url-shortener/api"
+)
+
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+	StatusDeleted = "deleted"
+)
+
+type tombstone struct {
+	status    string
+	removedAt time.Time
+}
+
+func (m *URLMapping) isExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+func (m *URLMapping) status(now time.Time) string {
+	switch {
+	case m.DeletedAt != nil:
+		return StatusDeleted
+	case m.isExpired(now):
+		return StatusExpired
+	default:
+		return StatusActive
+	}
+}
+
+// removeLocked drops a mapping from storage and remembers why, so lookups of
+// the old code can report it as gone rather than never having existed.
+// Callers must hold the write lock.
+func (us *URLShortener) removeLocked(shortCode, status string, now time.Time) {
+	if mapping, exists := us.storage[shortCode]; exists {
+		us.dropAliasesLocked(mapping, status, now)
+	}
+	delete(us.storage, shortCode)
+	us.tombstones[shortCode] = tombstone{status: status, removedAt: now}
+}
+
+func (us *URLShortener) purgeTombstones(now time.Time) int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	purged := 0
+	for code, stone := range us.tombstones {
+		if now.Sub(stone.removedAt) > us.config.TombstoneRetention {
+			delete(us.tombstones, code)
+			purged++
+		}
+	}
+	return purged
+}
+
+func statusError(status string) error {
+	if status == StatusExpired {
+		return ErrExpired
+	}
+	return ErrGone
+}
+
+// checkTTLBounds enforces the MinTTL/MaxTTL policy on a requested expiry. A
+// link that never expires is only allowed when MaxTTL is unset.
+func (us *URLShortener) checkTTLBounds(expiresAt *time.Time, now time.Time) error {
+	if expiresAt == nil {
+		if us.config.MaxTTL > 0 {
+			return errorf(ErrInvalidExpiry, "links must expire within %s; set expires_in_seconds or expires_at", us.config.MaxTTL)
+		}
+		return nil
+	}
+
+	ttl := expiresAt.Sub(now)
+	if us.config.MinTTL > 0 && ttl < us.config.MinTTL {
+		return errorf(ErrInvalidExpiry, "expiry must be at least %s in the future", us.config.MinTTL)
+	}
+	if us.config.MaxTTL > 0 && ttl > us.config.MaxTTL {
+		return errorf(ErrInvalidExpiry, "expiry must be at most %s in the future", us.config.MaxTTL)
+	}
+	return nil
+}
+
+// parseExpiry accepts either a relative expires_in_seconds or an absolute
+// RFC3339 expires_at, but not both. A nil result means the link never expires.
+func parseExpiry(req api.CreateURLRequest, now time.Time) (*time.Time, error) {
+	if req.ExpiresInSeconds != 0 && req.ExpiresAt != "" {
+		return nil, errorf(ErrInvalidExpiry, "expires_in_seconds and expires_at cannot both be set")
+	}
+
+	if req.ExpiresInSeconds < 0 {
+		return nil, errorf(ErrInvalidExpiry, "expires_in_seconds must be positive")
+	}
+	if req.ExpiresInSeconds > math.MaxInt64/int64(time.Second) {
+		return nil, errorf(ErrInvalidExpiry, "expires_in_seconds must be at most %d", math.MaxInt64/int64(time.Second))
+	}
+	if req.ExpiresInSeconds > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInSeconds) * time.Second)
+		return &expiresAt, nil
+	}
+
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, errorf(ErrInvalidExpiry, "expires_at must be an RFC3339 timestamp, e.g. 2025-12-31T23:59:59Z")
+		}
+		if !expiresAt.After(now) {
+			return nil, errorf(ErrInvalidExpiry, "expires_at must be in the future")
+		}
+		return &expiresAt, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSoftDeleteShortener(t *testing.T) *URLShortener {
+	return newTestShortener(t, func(c *Config) {
+		c.SoftDelete = true
+		c.SoftDeleteGracePeriod = time.Hour
+	})
+}
+
+func TestSoftDeleteHidesAndRestores(t *testing.T) {
+	us := newSoftDeleteShortener(t)
+	mustCreate(t, us, "https://example.com/oops", CreateOptions{CustomName: "oops"})
+
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/oops", ""), http.StatusNoContent)
+	expectStatus(t, serve(t, us, http.MethodGet, "/oops", ""), http.StatusGone)
+
+	rec := serve(t, us, http.MethodGet, "/api/urls", "")
+	var urls []URLMapping
+	decodeBody(t, rec, &urls)
+	if len(urls) != 0 {
+		t.Fatalf("deleted link still listed: %+v", urls)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/oops/restore", ""), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodGet, "/oops", ""), http.StatusMovedPermanently)
+}
+
+func TestLinkResponsesWhileCountingCountries(t *testing.T) {
+	us := newSoftDeleteShortener(t)
+	mustCreate(t, us, "https://example.com/geo", CreateOptions{CustomName: "geo"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			us.countClickCountry("geo", fmt.Sprintf("C%d", i%20))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 30; i++ {
+			serve(t, us, http.MethodGet, "/api/urls/geo", "")
+			serve(t, us, http.MethodPatch, "/api/urls/geo", `{"description":"busy"}`)
+			serve(t, us, http.MethodDelete, "/api/urls/geo", "")
+			serve(t, us, http.MethodPost, "/api/urls/geo/restore", "")
+		}
+	}()
+	wg.Wait()
+
+	rec := serve(t, us, http.MethodGet, "/api/urls/geo", "")
+	expectStatus(t, rec, http.StatusOK)
+	var mapping URLMapping
+	decodeBody(t, rec, &mapping)
+	if mapping.Description != "busy" {
+		t.Fatalf("description = %q, want busy", mapping.Description)
+	}
+}
+
+func TestSoftDeletedLinkPurgedAfterGracePeriod(t *testing.T) {
+	us := newSoftDeleteShortener(t)
+	mustCreate(t, us, "https://example.com/gone", CreateOptions{CustomName: "gone"})
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/gone", ""), http.StatusNoContent)
+
+	if purged := us.purgeDeleted(time.Now()); purged != 0 {
+		t.Fatalf("purged %d links inside the grace period", purged)
+	}
+	if purged := us.purgeDeleted(time.Now().Add(2 * time.Hour)); purged != 1 {
+		t.Fatalf("purged %d links after the grace period, want 1", purged)
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/gone/restore", ""), http.StatusNotFound)
+	expectStatus(t, serve(t, us, http.MethodGet, "/gone", ""), http.StatusGone)
+}
+
+func TestHardDeleteByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/bye", CreateOptions{CustomName: "byebye"})
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/byebye", ""), http.StatusNoContent)
+
+	if _, exists := us.storage["byebye"]; exists {
+		t.Fatal("link still stored after a hard delete")
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/byebye/restore", ""), http.StatusNotFound)
+}
+
+func TestDisabledLinkKeepsStatsAndCanBeReenabled(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/paused", CreateOptions{CustomName: "paused"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/paused", ""), http.StatusMovedPermanently)
+
+	expectStatus(t, serve(t, us, http.MethodPatch, "/api/urls/paused", `{"enabled":false}`), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodGet, "/paused", ""), http.StatusForbidden)
+
+	rec := serve(t, us, http.MethodGet, "/api/stats/paused", "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats struct {
+		AccessCount int64 `json:"access_count"`
+	}
+	decodeBody(t, rec, &stats)
+	if stats.AccessCount != 1 {
+		t.Fatalf("access count = %d while disabled, want 1", stats.AccessCount)
+	}
+
+	if err := us.SetEnabled("paused", true); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/paused", ""), http.StatusMovedPermanently)
+}
+
+func TestStatsDistinguishGoneFromUnknown(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/live", CreateOptions{CustomName: "alive"})
+	expired := mustCreate(t, us, "https://example.com/old", CreateOptions{CustomName: "stale"})
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+
+	for _, tt := range []struct {
+		code       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"alive", http.StatusOK, StatusActive},
+		{"stale", http.StatusGone, StatusExpired},
+	} {
+		rec := serve(t, us, http.MethodGet, "/api/stats/"+tt.code, "")
+		expectStatus(t, rec, tt.wantCode)
+		var stats struct {
+			Status string `json:"status"`
+		}
+		decodeBody(t, rec, &stats)
+		if stats.Status != tt.wantStatus {
+			t.Errorf("%s: status = %q, want %q", tt.code, stats.Status, tt.wantStatus)
+		}
+	}
+
+	expectStatus(t, serve(t, us, http.MethodDelete, "/api/urls/alive", ""), http.StatusNoContent)
+	rec := serve(t, us, http.MethodGet, "/api/stats/alive", "")
+	expectStatus(t, rec, http.StatusGone)
+	var stats struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, rec, &stats)
+	if stats.Status != StatusDeleted {
+		t.Errorf("deleted: status = %q, want deleted", stats.Status)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/stats/neverwas", ""), http.StatusNotFound)
+}
+
+func TestCreateWithExpiresAt(t *testing.T) {
+	us := newTestShortener(t, nil)
+	future := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/dated","custom_name":"dated","expires_at":"`+future.Format(time.RFC3339)+`"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	mapping, err := us.GetStats("dated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.ExpiresAt == nil || !mapping.ExpiresAt.Equal(future) {
+		t.Fatalf("ExpiresAt = %v, want %v", mapping.ExpiresAt, future)
+	}
+}
+
+func TestCreateRejectsBadExpiresAt(t *testing.T) {
+	us := newTestShortener(t, nil)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	for name, body := range map[string]string{
+		"past":      `{"url":"https://example.com/x","expires_at":"` + past + `"}`,
+		"malformed": `{"url":"https://example.com/x","expires_at":"next tuesday"}`,
+		"both":      `{"url":"https://example.com/x","expires_in_seconds":60,"expires_at":"` + future + `"}`,
+		"overflow":  `{"url":"https://example.com/x","expires_in_seconds":9223372036854775807}`,
+	} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if n := countLinksTo(us, "https://example.com/x"); n != 0 {
+		t.Fatalf("%d links stored from rejected requests", n)
+	}
+}
+
+func TestTTLBounds(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.MinTTL = time.Minute
+		c.MaxTTL = 90 * 24 * time.Hour
+	})
+	tooLate := time.Now().Add(100 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+	for name, tt := range map[string]struct {
+		body string
+		want int
+	}{
+		"under min":     {`{"url":"https://example.com/ttl","expires_in_seconds":30}`, http.StatusBadRequest},
+		"over max":      {`{"url":"https://example.com/ttl","expires_at":"` + tooLate + `"}`, http.StatusBadRequest},
+		"never expires": {`{"url":"https://example.com/ttl"}`, http.StatusBadRequest},
+		"within bounds": {`{"url":"https://example.com/ttl","expires_in_seconds":3600}`, http.StatusCreated},
+	} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/other","expires_in_seconds":30}`)
+	if body := rec.Body.String(); !strings.Contains(body, "at least 1m0s") {
+		t.Errorf("under-min error does not name the bound: %s", body)
+	}
+}
+
+func TestExpiredLinkRedirectsToConfiguredPage(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ExpiredRedirectURL = "https://example.com/expired?lang=en" })
+	expired := mustCreate(t, us, "https://example.com/old", CreateOptions{CustomName: "stale"})
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+	mustCreate(t, us, "https://example.com/paused", CreateOptions{CustomName: "paused"})
+	expectStatus(t, serve(t, us, http.MethodPatch, "/api/urls/paused", `{"enabled":false}`), http.StatusOK)
+
+	for code, want := range map[string]string{
+		"stale":  "https://example.com/expired?code=stale&lang=en&reason=expired",
+		"paused": "https://example.com/expired?code=paused&lang=en&reason=disabled",
+	} {
+		rec := serve(t, us, http.MethodGet, "/"+code, "")
+		expectStatus(t, rec, http.StatusFound)
+		if got := rec.Header().Get("Location"); got != want {
+			t.Fatalf("%s redirected to %q, want %q", code, got, want)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Fatalf("%s Cache-Control = %q", code, got)
+		}
+	}
+
+	// Codes that never existed are not covered by the expired page.
+	expectStatus(t, serve(t, us, http.MethodGet, "/nosuch", ""), http.StatusNotFound)
+}
+
+func TestExpiredLinkReturnsGoneByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expired := mustCreate(t, us, "https://example.com/old", CreateOptions{CustomName: "stale"})
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+
+	rec := serve(t, us, http.MethodGet, "/stale", "")
+	expectStatus(t, rec, http.StatusGone)
+	if rec.Header().Get("Location") != "" {
+		t.Fatal("expired link sent a Location header")
+	}
+}
+
+func countLinksTo(us *URLShortener, url string) int {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+	n := 0
+	for _, mapping := range us.storage {
+		if mapping.OriginalURL == url {
+			n++
+		}
+	}
+	return n
+}
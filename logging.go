@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// redirectRouteName names the short code routes so access logging can sample them.
+const redirectRouteName = "redirect"
+
+const (
+	LogURLModeFull     = "full"
+	LogURLModeHostOnly = "host-only"
+	LogURLModeHashed   = "hashed"
+)
+
+func isValidLogURLMode(mode string) bool {
+	switch mode {
+	case LogURLModeFull, LogURLModeHostOnly, LogURLModeHashed:
+		return true
+	}
+	return false
+}
+
+// logURL renders a destination URL for log output according to LogURLMode.
+// Stored mappings always keep the full URL.
+func (us *URLShortener) logURL(rawURL string) string {
+	switch us.config.LogURLMode {
+	case LogURLModeHostOnly:
+		u, err := url.Parse(normalizeURL(rawURL, us.config.DefaultScheme, false))
+		if err != nil || u.Host == "" {
+			return "[unparseable]"
+		}
+		return u.Scheme + "://" + u.Host
+	case LogURLModeHashed:
+		sum := sha256.Sum256([]byte(rawURL))
+		return "sha256:" + hex.EncodeToString(sum[:])[:16]
+	default:
+		return rawURL
+	}
+}
+
+// accessLogMiddleware logs one line per request. Successful redirects are
+// sampled at LogSampleRate to keep volume down on busy links; every other
+// request, and any redirect that fails, is always logged.
+func (us *URLShortener) accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if !us.shouldLogAccess(r, status) {
+			return
+		}
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, status, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+func (us *URLShortener) shouldLogAccess(r *http.Request, status int) bool {
+	if status < 200 || status >= 400 {
+		return true
+	}
+	route := mux.CurrentRoute(r)
+	if route == nil || route.GetName() != redirectRouteName {
+		return true
+	}
+	rate := us.config.LogSampleRate
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
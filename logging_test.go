@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// captureLogs returns everything logged while fn runs.
+func captureLogs(t *testing.T, fn func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(io.Discard)
+	fn()
+	return buf.String()
+}
+
+func TestLogURLModes(t *testing.T) {
+	const destination = "https://secret.example/account?token=abc"
+	for _, tt := range []struct {
+		mode, want string
+	}{
+		{LogURLModeFull, destination},
+		{LogURLModeHostOnly, "https://secret.example"},
+		{LogURLModeHashed, "sha256:"},
+	} {
+		us := newTestShortener(t, func(c *Config) { c.LogURLMode = tt.mode })
+		got := us.logURL(destination)
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: logURL = %q, want prefix %q", tt.mode, got, tt.want)
+		}
+		if tt.mode == LogURLModeHashed && len(got) != len("sha256:")+16 {
+			t.Errorf("hashed: logURL = %q, want a 16 digit prefix", got)
+		}
+	}
+}
+
+func TestCreateLogsRespectLogURLMode(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.LogURLMode = LogURLModeHashed })
+	var mapping *URLMapping
+	logs := captureLogs(t, func() {
+		mapping = mustCreate(t, us, "https://secret.example/account?token=abc", CreateOptions{})
+	})
+	if strings.Contains(logs, "secret.example") || strings.Contains(logs, "token=abc") {
+		t.Fatalf("logs leak the destination:\n%s", logs)
+	}
+	if !strings.Contains(logs, us.logURL(mapping.OriginalURL)) {
+		t.Fatalf("logs do not include the hashed URL:\n%s", logs)
+	}
+	if mapping.OriginalURL != "https://secret.example/account?token=abc" {
+		t.Fatalf("stored URL = %q, want it unchanged", mapping.OriginalURL)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.LogSampleRate = 0 })
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "sampled"})
+
+	logs := captureLogs(t, func() {
+		for i := 0; i < 5; i++ {
+			serve(t, us, http.MethodGet, "/sampled", "")
+		}
+		serve(t, us, http.MethodGet, "/missing", "")
+		serve(t, us, http.MethodGet, "/api/stats/sampled", "")
+	})
+	if strings.Contains(logs, "GET /sampled 301") {
+		t.Errorf("successful redirect logged at rate 0:\n%s", logs)
+	}
+	for _, want := range []string{"GET /missing 404", "GET /api/stats/sampled 200"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("missing %q in logs:\n%s", want, logs)
+		}
+	}
+
+	us = newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "sampled"})
+	if logs := captureLogs(t, func() { serve(t, us, http.MethodGet, "/sampled", "") }); !strings.Contains(logs, "GET /sampled 301") {
+		t.Errorf("redirect not logged at the default rate:\n%s", logs)
+	}
+}
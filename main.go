@@ -1,76 +1,277 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math/big"
+	"maps"
 	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode"
+
+	"url-shortener/api"
+	"url-shortener/version"
 
 	"github.com/gorilla/mux"
 )
 
 type URLMapping struct {
-	ID          string    `json:"id"`
-	ShortCode   string    `json:"short_code"`
-	OriginalURL string    `json:"original_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	AccessCount int64     `json:"access_count"`
+	ID          string     `json:"id"`
+	ShortCode   string     `json:"short_code"`
+	Namespace   string     `json:"namespace,omitempty"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AccessCount int64      `json:"access_count"`
+	Owner       string     `json:"owner,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	Description string     `json:"description,omitempty"`
+
+	LastAccessedAt  *time.Time       `json:"last_accessed_at,omitempty"`
+	UniqueClicks    int64            `json:"unique_clicks"`
+	ClicksByCountry map[string]int64 `json:"clicks_by_country,omitempty"`
+
+	AppendParams    map[string]string `json:"append_params,omitempty"`
+	OverwriteParams bool              `json:"overwrite_params,omitempty"`
+	CampaignID      string            `json:"campaign_id,omitempty"`
+	DelaySeconds    int               `json:"delay_seconds,omitempty"`
+	SubmittedURL    string            `json:"submitted_url,omitempty"`
+	Aliases         []string          `json:"aliases,omitempty"`
+	AliasOf         string            `json:"alias_of,omitempty"`
+	RedirectHeaders map[string]string `json:"redirect_headers,omitempty"`
+	// DeviceRules send visitors on some devices (mobile, tablet, desktop)
+	// somewhere other than OriginalURL.
+	DeviceRules map[string]string `json:"device_rules,omitempty"`
+	// GeoRules send visitors from some countries, keyed by ISO country code
+	// or region, somewhere other than OriginalURL.
+	GeoRules map[string]string `json:"geo_rules,omitempty"`
+
+	// ConfirmTokenHash, when set, is the SHA-256 of a token visitors must
+	// enter once per browser session before the link redirects. It is only
+	// ever written to snapshots.
+	ConfirmTokenHash string `json:"-"`
+
+	// CountsOnly links keep their counters but no click history.
+	CountsOnly bool `json:"counts_only,omitempty"`
+
+	recentVisitors map[string]time.Time
+	hits           *hitRate
+	// shards holds visits not yet folded into AccessCount when counters
+	// are sharded.
+	shards *shardedCounter
+	// clicks is the click history, oldest first, trimmed to
+	// ClickHistoryRetention.
+	clicks []time.Time
 }
 
-type CreateURLRequest struct {
-	URL        string `json:"url"`
-	CustomName string `json:"custom_name,omitempty"`
+type CreateOptions struct {
+	CustomName string
+	Namespace  string
+	Owner      string
+	ExpiresAt  *time.Time
+
+	AppendParams    map[string]string
+	OverwriteParams bool
+	// RedirectHeaders are extra response headers sent with every redirect.
+	RedirectHeaders map[string]string
+	CampaignID      string
+	CodeLength      int
+	Description     string
+	DelaySeconds    int
+
+	// DeviceRules map device classes, and GeoRules country codes, to
+	// destinations used in place of the link's URL.
+	DeviceRules map[string]string
+	GeoRules    map[string]string
+
+	// TitleSlug, when set, is tried (with a numeric suffix on collision)
+	// before falling back to a random code.
+	TitleSlug string
+	// ResolvedURL, when set, is stored as the destination in place of the
+	// submitted URL, which is kept as SubmittedURL.
+	ResolvedURL string
+	// ConfirmToken, when set, must be entered before the first redirect in
+	// each browser session. Only its hash is stored.
+	ConfirmToken string
+
+	// Preview makes CreateShortURL choose the code and hold it under
+	// PreviewToken instead of storing the link. Without Preview, a
+	// PreviewToken creates the link with the code held under it.
+	Preview      bool
+	PreviewToken string
+	// CountsOnly keeps no click history for the link.
+	CountsOnly bool
 }
 
-type CreateURLResponse struct {
-	ShortCode   string `json:"short_code"`
-	OriginalURL string `json:"original_url"`
-	ShortURL    string `json:"short_url"`
+type URLShortener struct {
+	storage    map[string]*URLMapping
+	tombstones map[string]tombstone
+	campaigns  map[string]*Campaign
+	aliases    map[string]string
+	mutex      sync.RWMutex
+	baseURL    atomic.Value // string; replaced at runtime by SetBaseURL
+	config     Config
+	geo        GeoResolver
+	events     *eventBroker
+	reserved   map[string]bool
+	// reservedPaths are first path segments the redirect routes never claim.
+	reservedPaths map[string]bool
+	botPattern    *regexp.Regexp
+	metrics       *latencyMetrics
+	ready         atomic.Bool
+	codeGenerator CodeGenerator
+	// counts buffers access-count increments; nil when buffering is off.
+	counts        *countBuffer
+	analytics     AnalyticsSink
+	wsSubscribers atomic.Int64
+	// rateLimit is the global token bucket; nil when GlobalRateLimit is off.
+	rateLimit *tokenBucket
+	// previews are codes held for ?preview=1 requests, by storage key.
+	previews map[string]codePreview
+	// previewTokens maps each preview token to the key it holds.
+	previewTokens map[string]string
+	// titleClient fetches destination pages for title slugs.
+	titleClient *http.Client
+	// followClient resolves redirect chains for follow_redirects.
+	followClient *http.Client
+
+	idempotency *idempotencyCache
 }
 
-type URLStats struct {
-	ShortCode   string    `json:"short_code"`
-	OriginalURL string    `json:"original_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	AccessCount int64     `json:"access_count"`
-}
+func NewURLShortener(config Config) *URLShortener {
+	if config.CodeLength <= 0 {
+		config.CodeLength = DefaultConfig().CodeLength
+	}
+	if config.MinCodeLength <= 0 || config.MaxCodeLength <= 0 {
+		config.MinCodeLength, config.MaxCodeLength = DefaultConfig().MinCodeLength, DefaultConfig().MaxCodeLength
+	}
+	if config.DefaultScheme == "" {
+		config.DefaultScheme = DefaultConfig().DefaultScheme
+	}
+	if len(config.AllowedSchemes) == 0 {
+		config.AllowedSchemes = DefaultConfig().AllowedSchemes
+	}
+	if config.VisitorSalt == "" {
+		config.VisitorSalt = randomSalt()
+	}
+	us := &URLShortener{
+		storage:    make(map[string]*URLMapping),
+		tombstones: make(map[string]tombstone),
+		campaigns:  make(map[string]*Campaign),
+		aliases:    make(map[string]string),
+		previews:   make(map[string]codePreview),
+		config:     config,
+		events:     newEventBroker(),
+		metrics:    newLatencyMetrics(),
+
+		previewTokens: make(map[string]string),
+		idempotency:   newIdempotencyCache(config.IdempotencyKeyTTL, config.IdempotencyCacheSize),
+	}
 
-type URLShortener struct {
-	storage map[string]*URLMapping
-	mutex   sync.RWMutex
-	baseURL string
+	reservedCodes := config.ReservedCodes
+	if config.ReservedCodesFile != "" {
+		fileCodes, err := loadReservedCodesFile(config.ReservedCodesFile)
+		if err != nil {
+			log.Printf("Warning: could not load reserved codes from %s: %v", config.ReservedCodesFile, err)
+		}
+		reservedCodes = append(append([]string{}, reservedCodes...), fileCodes...)
+	}
+	us.reserved = buildReservedSet(append(append([]string{}, reservedCodes...), config.ReservedPaths...))
+	us.reservedPaths = buildReservedPathSet(config.ReservedPaths)
+	us.botPattern = compileBotPattern(config.BotUserAgents)
+	us.baseURL.Store(config.BaseURL)
+	us.codeGenerator = newCodeGenerator(config)
+	us.analytics = newAnalyticsSink(config)
+	us.titleClient = titleFetchClient(config.TitleFetchTimeout)
+	us.followClient = followRedirectsClient(config)
+	if config.CountFlushInterval > 0 || config.CountFlushThreshold > 0 {
+		us.counts = newCountBuffer()
+	}
+	if config.GlobalRateLimit > 0 {
+		us.rateLimit = newTokenBucket(config.GlobalRateLimit, config.GlobalRateBurst, time.Now())
+	}
+
+	if config.GeoIPDatabasePath != "" {
+		resolver, err := NewMaxMindResolver(config.GeoIPDatabasePath)
+		if err != nil {
+			log.Printf("Warning: GeoIP database unavailable, country breakdown disabled: %v", err)
+		} else {
+			us.geo = resolver
+		}
+	}
+
+	return us
 }
 
-func NewURLShortener(baseURL string) *URLShortener {
-	return &URLShortener{
-		storage: make(map[string]*URLMapping),
-		baseURL: baseURL,
+func (us *URLShortener) canonicalCode(code string) string {
+	if us.config.CaseInsensitiveCodes {
+		return strings.ToLower(code)
 	}
+	return code
 }
 
-func (us *URLShortener) generateShortCode() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 6
+// Bounds on short code length. They must agree with shortCodeRoute, which
+// decides which paths are treated as short links.
+const (
+	minShortCodeLength = 3
+	maxShortCodeLength = 20
+	shortCodeRoute     = "{shortCode:[a-zA-Z0-9_-]{3,20}}"
+)
 
-	result := make([]byte, length)
-	for i := range result {
-		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
-		result[i] = charset[num.Int64()]
+// generateShortCode returns a candidate code from the configured generator,
+// or a random code of exactly length characters when length is non-zero.
+func (us *URLShortener) generateShortCode(length int) string {
+	if length == 0 {
+		return us.canonicalCode(us.codeGenerator.Generate())
 	}
-	return string(result)
+	return RandomCodeGenerator{Length: length, Charset: codeCharset(us.config.CaseInsensitiveCodes)}.Generate()
 }
 
-func isValidURL(str string) bool {
-	if str == "" {
+// unsafeSchemes can run script when a link is followed or rendered, so they
+// are never accepted, whatever AllowedSchemes says.
+var unsafeSchemes = []string{"javascript:", "data:", "vbscript:"}
+
+// hasUnsafeScheme reports whether str uses one of unsafeSchemes. Browsers
+// ignore leading spaces and control characters and any tabs or newlines in a
+// URL, so those are dropped before comparing.
+func hasUnsafeScheme(str string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, strings.TrimLeftFunc(str, func(r rune) bool { return r <= ' ' }))
+	for _, scheme := range unsafeSchemes {
+		if strings.HasPrefix(cleaned, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateURL reports whether str is a usable destination whose scheme is in
+// allowedSchemes. Web schemes need a real host; other schemes (mailto:, tel:,
+// app links) only need something after the scheme. unsafeSchemes are always
+// rejected.
+func validateURL(str, defaultScheme string, allowedSchemes []string) bool {
+	if str == "" || hasUnsafeScheme(str) {
 		return false
 	}
 
@@ -78,16 +279,22 @@ func isValidURL(str string) bool {
 		return false
 	}
 
-	if !strings.HasPrefix(str, "http://") && !strings.HasPrefix(str, "https://") && !strings.HasPrefix(str, "ftp://") {
-		str = "http://" + str
+	u, err := url.Parse(normalizeURL(str, defaultScheme, false))
+	if err != nil {
+		return false
 	}
 
-	u, err := url.Parse(str)
-	if err != nil {
+	if u.Scheme == "" || !slices.Contains(allowedSchemes, strings.ToLower(u.Scheme)) {
 		return false
 	}
 
-	if u.Scheme == "" || u.Host == "" {
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "ftp":
+	default:
+		return u.Opaque != "" || u.Host != "" || u.Path != ""
+	}
+
+	if u.Host == "" {
 		return false
 	}
 
@@ -102,13 +309,40 @@ func isValidURL(str string) bool {
 	return true
 }
 
-func normalizeURL(str string) string {
-	if !strings.HasPrefix(str, "http://") && !strings.HasPrefix(str, "https://") && !strings.HasPrefix(str, "ftp://") {
-		return "http://" + str
+// normalizeURL adds the default scheme when none is given. With stripFragment
+// the "#..." suffix is dropped, since fragments are never sent to the server;
+// an encoded %23 in the path or query is left alone.
+func normalizeURL(str, defaultScheme string, stripFragment bool) string {
+	if stripFragment {
+		str, _, _ = strings.Cut(str, "#")
+	}
+	if !hasURLScheme(str) {
+		return defaultScheme + "://" + str
 	}
 	return str
 }
 
+// normalizeDestination is normalizeURL as applied to URLs about to be stored,
+// with the configured fragment and tracking parameter stripping.
+func (us *URLShortener) normalizeDestination(str string) string {
+	str = normalizeURL(str, us.config.DefaultScheme, us.config.StripFragment)
+	if us.config.StripTrackingParams {
+		str = stripTrackingParams(str, us.config.TrackingParams)
+	}
+	return str
+}
+
+var (
+	hierarchicalSchemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	opaqueSchemePattern       = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+-]*:[^0-9]`)
+)
+
+// hasURLScheme tells "mailto:a@b.com" (a scheme) apart from "example.com:8080"
+// (a host and port that still needs the default scheme).
+func hasURLScheme(str string) bool {
+	return hierarchicalSchemePattern.MatchString(str) || opaqueSchemePattern.MatchString(str)
+}
+
 func isValidCustomName(name string) bool {
 	if len(name) < 3 || len(name) > 20 {
 		return false
@@ -126,219 +360,1090 @@ func isValidCustomName(name string) bool {
 	return true
 }
 
-func (us *URLShortener) CreateShortURL(originalURL, customName string) (*URLMapping, error) {
-	if !isValidURL(originalURL) {
-		return nil, fmt.Errorf("invalid URL provided: %s", originalURL)
+func (us *URLShortener) CreateShortURL(originalURL string, opts CreateOptions) (*URLMapping, bool, error) {
+	customName, owner, namespace := opts.CustomName, opts.Owner, opts.Namespace
+	if hasUnsafeScheme(originalURL) {
+		return nil, false, errorf(ErrUnsafeScheme, "javascript:, data: and vbscript: URLs cannot be shortened")
+	}
+	if !validateURL(originalURL, us.config.DefaultScheme, us.config.AllowedSchemes) {
+		return nil, false, fmt.Errorf("%w: %s", ErrInvalidURL, originalURL)
+	}
+
+	if namespace != "" && (!isValidNamespace(namespace) || us.reservedPaths[namespace]) {
+		return nil, false, errorf(ErrInvalidNamespace, "invalid namespace '%s': must be 1-20 lowercase letters, numbers, or hyphens and not reserved", namespace)
 	}
 
-	normalizedURL := normalizeURL(originalURL)
-	log.Printf("Creating short URL for: %s, with custom name: '%s'", normalizedURL, customName)
+	if err := validateAppendParams(opts.AppendParams); err != nil {
+		return nil, false, err
+	}
+
+	redirectHeaders, err := normalizeRedirectHeaders(opts.RedirectHeaders)
+	if err != nil {
+		return nil, false, err
+	}
+
+	deviceRules, err := us.normalizeDeviceRules(opts.DeviceRules)
+	if err != nil {
+		return nil, false, err
+	}
+
+	geoRules, err := us.normalizeGeoRules(opts.GeoRules)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := us.checkTTLBounds(opts.ExpiresAt, time.Now()); err != nil {
+		return nil, false, err
+	}
+
+	description, err := sanitizeDescription(opts.Description)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := validateDelay(opts.DelaySeconds); err != nil {
+		return nil, false, err
+	}
+
+	if err := validateConfirmToken(opts.ConfirmToken); err != nil {
+		return nil, false, err
+	}
+	confirmTokenHash := hashConfirmToken(opts.ConfirmToken)
+
+	if opts.CodeLength != 0 && (opts.CodeLength < us.config.MinCodeLength || opts.CodeLength > us.config.MaxCodeLength) {
+		return nil, false, errorf(ErrInvalidCodeLength, "code_length must be between %d and %d", us.config.MinCodeLength, us.config.MaxCodeLength)
+	}
+
+	normalizedURL := us.normalizeDestination(originalURL)
+	submittedURL := ""
+	if resolved := us.normalizeDestination(opts.ResolvedURL); opts.ResolvedURL != "" && resolved != normalizedURL {
+		if !validateURL(resolved, us.config.DefaultScheme, us.config.AllowedSchemes) {
+			return nil, false, fmt.Errorf("%w: redirects to %s", ErrInvalidURL, us.logURL(resolved))
+		}
+		submittedURL, normalizedURL = normalizedURL, resolved
+	}
+	if !us.isDestinationAllowed(normalizedURL) {
+		return nil, false, errorf(ErrHostNotAllowed, "destination host is not on the allowlist: %s", us.logURL(normalizedURL))
+	}
+
+	log.Printf("Creating short URL for: %s, with custom name: '%s'", us.logURL(normalizedURL), customName)
+
+	// The dedup scan and the insert share one write lock so concurrent
+	// submissions of the same URL can never both create a mapping.
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	now := time.Now()
+	var previewedKey string
+	if opts.PreviewToken != "" && !opts.Preview {
+		key, err := us.claimPreviewLocked(opts.PreviewToken, namespace, owner, normalizedURL, now)
+		if err != nil {
+			return nil, false, err
+		}
+		previewedKey = key
+	}
 
-	us.mutex.RLock()
 	for _, mapping := range us.storage {
-		if mapping.OriginalURL == normalizedURL {
-			us.mutex.RUnlock()
+		if mapping.OriginalURL == normalizedURL && mapping.Owner == owner && mapping.Namespace == namespace && mapping.status(now) == StatusActive &&
+			maps.Equal(mapping.AppendParams, opts.AppendParams) && mapping.OverwriteParams == opts.OverwriteParams &&
+			maps.Equal(mapping.RedirectHeaders, redirectHeaders) && maps.Equal(mapping.DeviceRules, deviceRules) &&
+			maps.Equal(mapping.GeoRules, geoRules) &&
+			mapping.CampaignID == opts.CampaignID && (opts.CodeLength == 0 || len(mapping.ShortCode) == opts.CodeLength) &&
+			mapping.DelaySeconds == opts.DelaySeconds && mapping.AliasOf == "" && mapping.ConfirmTokenHash == confirmTokenHash &&
+			mapping.CountsOnly == opts.CountsOnly {
 			log.Printf("URL already exists, returning existing mapping: %s", mapping.ShortCode)
-			return mapping, nil
+			us.dropPreviewLocked(previewedKey)
+			return mapping, false, nil
 		}
 	}
-	us.mutex.RUnlock()
+
+	if err := us.checkCampaignLocked(opts.CampaignID, owner); err != nil {
+		return nil, false, err
+	}
 
 	var shortCode string
-	us.mutex.Lock()
-	defer us.mutex.Unlock()
 
-	if customName != "" {
+	if previewedKey != "" {
+		namespace, shortCode = splitStorageKey(previewedKey)
+		log.Printf("Using previewed short code: '%s'", shortCode)
+	} else if customName != "" {
 		log.Printf("Processing custom name: '%s'", customName)
 
 		if !isValidCustomName(customName) {
-			return nil, fmt.Errorf("invalid custom name '%s': must be 3-20 characters, using only letters, numbers, hyphens, and underscores", customName)
+			return nil, false, errorf(ErrInvalidCustomName, "invalid custom name '%s': must be 3-20 characters, using only letters, numbers, hyphens, and underscores", customName)
 		}
 
-		if _, exists := us.storage[customName]; exists {
-			return nil, fmt.Errorf("custom name '%s' is already taken. Please choose a different name", customName)
+		if us.isReservedCode(customName) {
+			return nil, false, errorf(ErrReservedCode, "custom name '%s' is reserved. Please choose a different name", customName)
+		}
+
+		// Canonicalize before the collision check so that, with case-insensitive
+		// codes, "Promo" clashes with an existing "promo" just as lookups would.
+		shortCode = us.canonicalCode(customName)
+		if us.codeTakenLocked(storageKey(namespace, shortCode)) {
+			return nil, false, errorf(ErrCodeTaken, "custom name '%s' is already taken. Please choose a different name", customName)
 		}
 
-		shortCode = customName
 		log.Printf("Using custom name as short code: '%s'", shortCode)
-	} else {
+	} else if opts.TitleSlug != "" {
+		shortCode = us.availableSlugLocked(namespace, opts.TitleSlug)
+		log.Printf("Using title slug as short code: '%s'", shortCode)
+	}
+
+	if deterministic, ok := us.codeGenerator.(DeterministicCodeGenerator); ok && shortCode == "" && opts.CodeLength == 0 {
+		for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+			candidate := us.canonicalCode(deterministic.CodeFor(normalizedURL, attempt))
+			if !us.codeTakenLocked(storageKey(namespace, candidate)) && !us.isReservedCode(candidate) {
+				shortCode = candidate
+				break
+			}
+		}
+		if shortCode == "" {
+			return nil, false, errorf(ErrCodesExhausted, "no free short code found after %d attempts", maxCodeAttempts)
+		}
+		log.Printf("Derived short code from URL: '%s'", shortCode)
+	}
+
+	if shortCode == "" {
 		log.Printf("Generating random short code")
-		for {
-			shortCode = us.generateShortCode()
-			if _, exists := us.storage[shortCode]; !exists {
+		for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+			candidate := us.generateShortCode(opts.CodeLength)
+			if !us.codeTakenLocked(storageKey(namespace, candidate)) && !us.isReservedCode(candidate) {
+				shortCode = candidate
 				break
 			}
 		}
+		if shortCode == "" {
+			return nil, false, errorf(ErrCodesExhausted, "no free short code found after %d attempts", maxCodeAttempts)
+		}
 		log.Printf("Generated random short code: '%s'", shortCode)
 	}
 
+	if !opts.Preview {
+		if err := us.ensureCapacityLocked(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	key := storageKey(namespace, shortCode)
 	mapping := &URLMapping{
-		ID:          shortCode,
+		ID:          key,
 		ShortCode:   shortCode,
+		Namespace:   namespace,
 		OriginalURL: normalizedURL,
-		CreatedAt:   time.Now(),
+		CreatedAt:   now,
 		AccessCount: 0,
+		Owner:       owner,
+		ExpiresAt:   opts.ExpiresAt,
+		Enabled:     true,
+		Description: description,
+
+		AppendParams:    opts.AppendParams,
+		OverwriteParams: opts.OverwriteParams,
+		CampaignID:      opts.CampaignID,
+		DelaySeconds:    opts.DelaySeconds,
+		SubmittedURL:    submittedURL,
+		RedirectHeaders: redirectHeaders,
+		DeviceRules:     deviceRules,
+		GeoRules:        geoRules,
+
+		ConfirmTokenHash: confirmTokenHash,
+		CountsOnly:       opts.CountsOnly,
 	}
 
-	us.storage[shortCode] = mapping
-	return mapping, nil
+	if opts.Preview {
+		us.reservePreviewLocked(key, opts.PreviewToken, owner, normalizedURL, now)
+		return mapping, true, nil
+	}
+
+	us.storage[key] = mapping
+	delete(us.tombstones, key)
+	us.dropPreviewLocked(previewedKey)
+	us.publishCreated(mapping)
+	return mapping, true, nil
 }
 
 func (us *URLShortener) GetOriginalURL(shortCode string) (*URLMapping, error) {
+	mapping, _, err := us.recordAccess(shortCode)
+	return mapping, err
+}
+
+// recordAccess is GetOriginalURL that also returns the link's access count
+// including this visit. The count is read while the visit is recorded, since
+// redirects of the same link keep changing it once the lock is released.
+func (us *URLShortener) recordAccess(shortCode string) (*URLMapping, int64, error) {
+	if us.shardedCounting() {
+		if mapping, accessCount, ok, err := us.recordShardedAccess(shortCode); ok || err != nil {
+			return mapping, accessCount, err
+		}
+	}
+
 	us.mutex.Lock()
 	defer us.mutex.Unlock()
 
+	mapping, err := us.resolveLocked(shortCode)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	accessCount := us.countAccessLocked(mapping, now)
+	mapping.LastAccessedAt = &now
+	if mapping.hits == nil {
+		mapping.hits = &hitRate{}
+	}
+	mapping.hits.record(now)
+	us.recordClickLocked(mapping, now)
+	return mapping, accessCount, nil
+}
+
+// LookupURL resolves a short code exactly like GetOriginalURL but without
+// counting it as an access.
+func (us *URLShortener) LookupURL(shortCode string) (*URLMapping, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	return us.resolveLocked(shortCode)
+}
+
+func (us *URLShortener) resolveLocked(shortCode string) (*URLMapping, error) {
+	shortCode = us.followAliasLocked(us.canonicalCode(shortCode))
 	mapping, exists := us.storage[shortCode]
 	if !exists {
-		return nil, fmt.Errorf("short URL not found")
+		if stone, removed := us.tombstones[shortCode]; removed {
+			return nil, statusError(stone.status)
+		}
+		return nil, ErrNotFound
+	}
+	if status := mapping.status(time.Now()); status != StatusActive {
+		return nil, statusError(status)
+	}
+	if !mapping.Enabled {
+		return nil, ErrDisabled
 	}
-
-	mapping.AccessCount++
 	return mapping, nil
 }
 
+// GetStats returns the mapping for a short code. Links that existed but have
+// since expired or been deleted yield ErrExpired/ErrGone, with the mapping
+// still returned when it has not been purged yet.
 func (us *URLShortener) GetStats(shortCode string) (*URLMapping, error) {
 	us.mutex.RLock()
 	defer us.mutex.RUnlock()
 
+	return us.getStatsLocked(shortCode)
+}
+
+// GetStatsView is GetStats returning a copy made under the lock, for
+// callers that read or encode it after the lock is released.
+func (us *URLShortener) GetStatsView(shortCode string) (*URLMapping, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	mapping, err := us.getStatsLocked(shortCode)
+	if mapping != nil {
+		mapping = us.viewLocked(mapping)
+	}
+	return mapping, err
+}
+
+func (us *URLShortener) getStatsLocked(shortCode string) (*URLMapping, error) {
+	shortCode = us.followAliasLocked(us.canonicalCode(shortCode))
 	mapping, exists := us.storage[shortCode]
 	if !exists {
-		return nil, fmt.Errorf("short URL not found")
+		if stone, removed := us.tombstones[shortCode]; removed {
+			return nil, statusError(stone.status)
+		}
+		return nil, ErrNotFound
+	}
+	if status := mapping.status(time.Now()); status != StatusActive {
+		return mapping, statusError(status)
 	}
 
 	return mapping, nil
 }
 
-func (us *URLShortener) getAllURLs() []*URLMapping {
+func (us *URLShortener) SetEnabled(shortCode string, enabled bool) error {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[us.canonicalCode(shortCode)]
+	if !exists || mapping.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	mapping.Enabled = enabled
+	return nil
+}
+
+// ForEach calls fn with a copy of each live (not soft-deleted) mapping, in no
+// particular order, until fn returns false. The read lock is held throughout,
+// so fn must be quick and must not call methods that take the write lock.
+// The copies share nothing with the store and may be kept.
+func (us *URLShortener) ForEach(fn func(*URLMapping) bool) {
 	us.mutex.RLock()
 	defer us.mutex.RUnlock()
 
-	var urls []*URLMapping
 	for _, mapping := range us.storage {
-		urls = append(urls, mapping)
+		if mapping.DeletedAt != nil {
+			continue
+		}
+		if !fn(us.viewLocked(mapping)) {
+			return
+		}
 	}
-	return urls
 }
 
-func (us *URLShortener) createShortURLHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// viewLocked returns a copy of mapping that can be read once the lock is
+// released: its maps and slices are cloned, since redirects keep writing to
+// them, and visits not yet written back are included. Callers must hold the
+// lock.
+func (us *URLShortener) viewLocked(mapping *URLMapping) *URLMapping {
+	view := *mapping
+	view.AccessCount += us.pendingAccesses(mapping)
+	view.LastAccessedAt = lastAccessed(mapping)
+	view.ClicksByCountry = maps.Clone(mapping.ClicksByCountry)
+	view.AppendParams = maps.Clone(mapping.AppendParams)
+	view.Aliases = slices.Clone(mapping.Aliases)
+	view.RedirectHeaders = maps.Clone(mapping.RedirectHeaders)
+	view.DeviceRules = maps.Clone(mapping.DeviceRules)
+	view.GeoRules = maps.Clone(mapping.GeoRules)
+	view.recentVisitors = nil
+	view.hits = nil
+	view.shards = nil
+	view.clicks = slices.Clone(mapping.clicks)
+	return &view
+}
+
+func (us *URLShortener) DeleteURL(shortCode string, principal Principal) error {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	return us.deleteLocked(shortCode, principal, time.Now())
+}
+
+// deleteLocked soft- or hard-deletes one link. Callers must hold the write lock.
+func (us *URLShortener) deleteLocked(shortCode string, principal Principal, now time.Time) error {
+	shortCode = us.canonicalCode(shortCode)
+	mapping, exists := us.storage[shortCode]
+	if !exists || mapping.DeletedAt != nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		return ErrNotFound
 	}
 
-	var req CreateURLRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding JSON: %v", err)
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-		return
+	if us.config.SoftDelete {
+		mapping.DeletedAt = &now
+		return nil
 	}
 
-	log.Printf("Received request - URL: '%s', CustomName: '%s'", req.URL, req.CustomName)
+	us.removeLocked(shortCode, StatusDeleted, now)
+	return nil
+}
 
+func (us *URLShortener) RestoreURL(shortCode string, principal Principal) (*URLMapping, error) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[us.canonicalCode(shortCode)]
+	if !exists || mapping.DeletedAt == nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		return nil, ErrNotFound
+	}
+	if time.Since(*mapping.DeletedAt) > us.config.SoftDeleteGracePeriod {
+		return nil, ErrNotFound
+	}
+
+	mapping.DeletedAt = nil
+	return us.viewLocked(mapping), nil
+}
+
+func (us *URLShortener) purgeDeleted(now time.Time) int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	purged := 0
+	for code, mapping := range us.storage {
+		if mapping.DeletedAt != nil && now.Sub(*mapping.DeletedAt) > us.config.SoftDeleteGracePeriod {
+			us.removeLocked(code, StatusDeleted, now)
+			purged++
+		}
+	}
+	return purged
+}
+
+// createFromRequest applies the request-level validation shared by the single
+// and batch create endpoints before handing off to CreateShortURL. ctx bounds
+// the title and redirect lookups, so they stop when the client goes away.
+func (us *URLShortener) createFromRequest(ctx context.Context, req api.CreateURLRequest, owner string) (*URLMapping, bool, error) {
+	opts, err := us.createOptions(ctx, req, owner)
+	if err != nil {
+		return nil, false, err
+	}
+	return us.CreateShortURL(req.URL, opts)
+}
+
+// createOptions validates req and turns it into CreateOptions.
+func (us *URLShortener) createOptions(ctx context.Context, req api.CreateURLRequest, owner string) (CreateOptions, error) {
 	if req.URL == "" {
 		log.Printf("Error: Empty URL provided")
-		http.Error(w, "URL is required and cannot be empty", http.StatusBadRequest)
-		return
+		return CreateOptions{}, errorf(ErrEmptyURL, "URL is required and cannot be empty")
 	}
 
 	if req.CustomName != "" && len(req.CustomName) < 3 {
 		log.Printf("Error: Custom name too short: '%s'", req.CustomName)
-		http.Error(w, "Custom name must be at least 3 characters long", http.StatusBadRequest)
-		return
+		return CreateOptions{}, errorf(ErrInvalidCustomName, "Custom name must be at least 3 characters long")
 	}
 
 	if req.CustomName != "" && len(req.CustomName) > 20 {
 		log.Printf("Error: Custom name too long: '%s'", req.CustomName)
-		http.Error(w, "Custom name must be no more than 20 characters long", http.StatusBadRequest)
+		return CreateOptions{}, errorf(ErrInvalidCustomName, "Custom name must be no more than 20 characters long")
+	}
+
+	expiresAt, err := parseExpiry(req, time.Now())
+	if err != nil {
+		log.Printf("Error: Invalid expiry: %v", err)
+		return CreateOptions{}, err
+	}
+
+	opts := CreateOptions{
+		CustomName: req.CustomName,
+		Namespace:  req.Namespace,
+		Owner:      owner,
+		ExpiresAt:  expiresAt,
+
+		AppendParams:    req.AppendParams,
+		OverwriteParams: req.OverwriteParams,
+		RedirectHeaders: req.RedirectHeaders,
+		DeviceRules:     req.DeviceRules,
+		GeoRules:        req.GeoRules,
+		CampaignID:      req.CampaignID,
+		CodeLength:      req.CodeLength,
+		Description:     req.Description,
+		DelaySeconds:    req.DelaySeconds,
+		ConfirmToken:    req.ConfirmToken,
+		PreviewToken:    req.PreviewToken,
+		CountsOnly:      req.CountsOnly,
+	}
+	if req.SlugFromTitle && req.CustomName == "" && us.config.TitleSlugs {
+		opts.TitleSlug = us.titleSlug(ctx, normalizeURL(req.URL, us.config.DefaultScheme, false))
+	}
+	if req.FollowRedirects && us.config.FollowRedirects {
+		opts.ResolvedURL = us.resolvedDestination(ctx, normalizeURL(req.URL, us.config.DefaultScheme, false))
+	}
+
+	return opts, nil
+}
+
+func (us *URLShortener) createShortURLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		us.writeJSONError(w, r, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return
+	}
+
+	var req api.CreateURLRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Printf("Received request - URL: '%s', CustomName: '%s'", us.logURL(req.URL), req.CustomName)
+
+	version, err := negotiateVersion(r)
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusNotAcceptable, err)
+		return
+	}
+
+	if value := r.URL.Query().Get("preview"); value != "" {
+		preview, err := strconv.ParseBool(value)
+		if err != nil {
+			us.writeJSONError(w, r, http.StatusBadRequest, errors.New("preview must be true or false"))
+			return
+		}
+		if preview {
+			us.previewShortURL(w, r, req)
+			return
+		}
+	}
+
+	idemKey, err := idempotencyKey(r)
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
 		return
 	}
+	completed := false
+	if idemKey != "" {
+		cached, err := us.idempotency.begin(idemKey, requestFingerprint(req), time.Now())
+		switch {
+		case errors.Is(err, errIdempotencyKeyReused):
+			us.writeJSONError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, errIdempotencyInProgress):
+			us.writeJSONError(w, r, http.StatusConflict, err)
+			return
+		case cached != nil:
+			if cached.location != "" {
+				w.Header().Set("Location", cached.location)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.Header().Set("Link", statsLink(us.publicBaseURL(r), storageKey(cached.details.Namespace, cached.details.ShortCode)))
+			us.writeCreateResponse(w, r, version, cached.status, cached.details)
+			return
+		}
+		// The key is released unless the response is recorded, so a failed
+		// or panicking request does not lock out retries for the whole TTL.
+		defer func() {
+			if !completed {
+				us.idempotency.abandon(idemKey)
+			}
+		}()
+	}
 
-	mapping, err := us.CreateShortURL(req.URL, req.CustomName)
+	mapping, created, err := us.createFromRequest(r.Context(), req, ownerFromRequest(r))
 	if err != nil {
-		log.Printf("Error creating short URL: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if errors.Is(err, ErrInvalidURL) {
+			log.Printf("Error creating short URL: %v: %s", ErrInvalidURL, us.logURL(req.URL))
+		} else {
+			log.Printf("Error creating short URL: %v", err)
+		}
+		if errors.Is(err, ErrStoreFull) {
+			us.writeJSONError(w, r, http.StatusInsufficientStorage, errorf(ErrStoreFull, "Link storage is full, please try again later"))
+			return
+		}
+		if errors.Is(err, ErrCodeTaken) {
+			us.writeJSONError(w, r, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, ErrCodesExhausted) {
+			us.writeJSONError(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
 	log.Printf("Successfully created mapping - ShortCode: '%s', CustomName was: '%s'", mapping.ShortCode, req.CustomName)
 
-	scheme := "https"
-	if r.Header.Get("X-Forwarded-Proto") != "" {
-		scheme = r.Header.Get("X-Forwarded-Proto")
-	} else if r.TLS == nil {
-		scheme = "http"
+	baseURL := us.publicBaseURL(r)
+
+	details := us.createDetails(mapping, baseURL, created)
+
+	w.Header().Set("Link", statsLink(baseURL, mapping.ID))
+	status, location := http.StatusOK, ""
+	if created {
+		status, location = http.StatusCreated, "/api/urls/"+mapping.ID
+		w.Header().Set("Location", location)
+	} else if us.config.DuplicateResponse == DuplicateResponseConflict {
+		status = http.StatusConflict
+	}
+	if idemKey != "" {
+		us.idempotency.complete(idemKey, status, location, details)
+		completed = true
+	}
+	us.writeCreateResponse(w, r, version, status, details)
+}
+
+// redirectUnavailable sends visitors of an expired or disabled link to the
+// operator's ExpiredRedirectURL, passing the code and reason along.
+func (us *URLShortener) redirectUnavailable(w http.ResponseWriter, r *http.Request, shortCode string, err error) {
+	target, parseErr := url.Parse(us.config.ExpiredRedirectURL)
+	if parseErr != nil {
+		http.Error(w, "Short URL is no longer available", http.StatusGone)
+		return
+	}
+
+	reason := StatusExpired
+	if errors.Is(err, ErrDisabled) {
+		reason = "disabled"
+	}
+	query := target.Query()
+	query.Set("code", shortCode)
+	query.Set("reason", reason)
+	target.RawQuery = query.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// statsLink builds an RFC 8288 Link header value pointing at the stats
+// resource for the mapping stored under key.
+func statsLink(baseURL, key string) string {
+	return fmt.Sprintf(`<%s/api/stats/%s>; rel="stats"`, baseURL, url.PathEscape(key))
+}
+
+func (us *URLShortener) currentBaseURL() string {
+	return us.baseURL.Load().(string)
+}
+
+func (us *URLShortener) publicBaseURL(r *http.Request) string {
+	if !us.config.TrustProxyHeaders {
+		return strings.TrimSuffix(us.currentBaseURL(), "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwardedProto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto")); forwardedProto != "" {
+		if proto := strings.ToLower(forwardedProto); proto == "http" || proto == "https" {
+			scheme = proto
+		}
 	}
 
 	host := r.Host
-	if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+	if forwardedHost := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); forwardedHost != "" {
 		host = forwardedHost
 	}
 
-	baseURL := fmt.Sprintf("%s://%s", scheme, host)
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
+
+func (us *URLShortener) clientIP(r *http.Request) net.IP {
+	if us.config.TrustProxyHeaders {
+		if forwardedFor := firstHeaderValue(r.Header.Get("X-Forwarded-For")); forwardedFor != "" {
+			return net.ParseIP(forwardedFor)
+		}
+	}
 
-	response := CreateURLResponse{
-		ShortCode:   mapping.ShortCode,
-		OriginalURL: mapping.OriginalURL,
-		ShortURL:    fmt.Sprintf("%s/%s", baseURL, mapping.ShortCode),
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
 	}
+	return net.ParseIP(host)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+func firstHeaderValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
 }
 
 func (us *URLShortener) redirectHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	shortCode := vars["shortCode"]
+	if us.serveReservedPath(w, r, vars) {
+		return
+	}
+	shortCode := storageKey(vars["namespace"], vars["shortCode"])
 
-	mapping, err := us.GetOriginalURL(shortCode)
+	// Links with a confirmation token show the token form, uncounted, until
+	// this browser has entered it.
+	if pending, err := us.LookupURL(shortCode); err == nil && us.needsConfirmation(r, pending) {
+		serveConfirm(w, r, http.StatusOK, confirmPage{})
+		return
+	}
+
+	// HEAD is side-effect free: it validates the link and returns the same
+	// redirect headers, but is not counted as a visit. Crawlers still get
+	// redirected but are not counted either.
+	counted := us.countsAsClick(r)
+	var (
+		mapping     *URLMapping
+		accessCount int64
+		err         error
+	)
+	if counted {
+		mapping, accessCount, err = us.recordAccess(shortCode)
+	} else {
+		mapping, err = us.LookupURL(shortCode)
+	}
+	if errors.Is(err, ErrGone) {
+		http.Error(w, "Short URL has been deleted", http.StatusGone)
+		return
+	}
+	if us.config.ExpiredRedirectURL != "" && (errors.Is(err, ErrExpired) || errors.Is(err, ErrDisabled)) {
+		us.redirectUnavailable(w, r, vars["shortCode"], err)
+		return
+	}
+	if errors.Is(err, ErrExpired) {
+		http.Error(w, "Short URL has expired", http.StatusGone)
+		return
+	}
+	if errors.Is(err, ErrDisabled) {
+		http.Error(w, "This link has been disabled", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, ErrNotFound) && us.config.NotFoundRedirectURL != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, us.config.NotFoundRedirectURL, http.StatusFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Short URL not found", http.StatusNotFound)
 		return
 	}
 
-	http.Redirect(w, r, mapping.OriginalURL, http.StatusMovedPermanently)
+	// Geo rules are the one case where the redirect waits on the GeoIP
+	// database. The click is then counted for the country found here rather
+	// than looked up again.
+	ip := us.clientIP(r)
+	country := unknownCountry
+	geoRouted := len(mapping.GeoRules) > 0
+	if geoRouted {
+		country = us.lookupCountry(ip)
+	}
+
+	if counted {
+		us.recordUniqueVisit(mapping.ID, ip)
+		if us.geo != nil || us.analytics != nil {
+			// The country lookup and the analytics record wait on the
+			// GeoIP database, so they are kept off the redirect path.
+			click := newClickRecord(mapping, r)
+			go func() {
+				if us.geo != nil {
+					if geoRouted {
+						us.countClickCountry(mapping.ID, country)
+						click.Country = country
+					} else {
+						click.Country = us.recordClickCountry(mapping.ID, ip)
+					}
+				}
+				if us.analytics != nil {
+					us.analytics.Record(click)
+				}
+			}()
+		}
+		us.publishClick(mapping, accessCount)
+	}
+
+	for name, value := range mapping.RedirectHeaders {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Link", statsLink(us.publicBaseURL(r), mapping.ID))
+	// Browsers keep permanent redirects, so a link whose destination depends
+	// on the visitor gets a temporary one.
+	status := http.StatusMovedPermanently
+	if len(mapping.DeviceRules) > 0 {
+		w.Header().Add("Vary", "User-Agent")
+		status = http.StatusFound
+	}
+	// Shared caches must not hand one country's redirect to another.
+	if geoRouted {
+		w.Header().Set("Cache-Control", "private")
+		status = http.StatusFound
+	}
+	destination := withAppendParams(mapping.destinationFor(country, r.UserAgent()), mapping.AppendParams, mapping.OverwriteParams)
+	if !isWebScheme(destination) && us.config.NonHTTPBehavior == NonHTTPBehaviorLanding {
+		serveLanding(w, r, destination)
+		return
+	}
+	if mapping.DelaySeconds > 0 {
+		serveCountdown(w, r, destination, mapping.DelaySeconds)
+		return
+	}
+	http.Redirect(w, r, destination, status)
 }
 
 func (us *URLShortener) statsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+
+	stats, status, err := us.lookupStats(r, vars["shortCode"])
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	writeJSON(w, status, stats, us.jsonOptions(r))
+}
+
+// lookupStats returns the public stats for shortCode and the status to serve
+// them with: 200 for live links, 410 for deleted or expired ones. It does not
+// count as a visit.
+func (us *URLShortener) lookupStats(r *http.Request, shortCode string) (api.URLStats, int, error) {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	return us.lookupStatsLocked(r, shortCode)
+}
+
+// lookupStatsLocked is lookupStats for callers holding the read lock, which
+// the stats are built under since redirects keep changing them.
+func (us *URLShortener) lookupStatsLocked(r *http.Request, shortCode string) (api.URLStats, int, error) {
+	mapping, err := us.getStatsLocked(shortCode)
+	if errors.Is(err, ErrGone) || errors.Is(err, ErrExpired) {
+		namespace, code := splitStorageKey(us.canonicalCode(shortCode))
+		stats := api.URLStats{ShortCode: code, Namespace: namespace, Status: StatusDeleted}
+		if errors.Is(err, ErrExpired) {
+			stats.Status = StatusExpired
+		}
+		if mapping != nil {
+			stats = us.publicURLStats(r, mapping)
+		}
+		return stats, http.StatusGone, nil
+	}
+	if err != nil {
+		return api.URLStats{}, http.StatusNotFound, err
+	}
+
+	return us.publicURLStats(r, mapping), http.StatusOK, nil
+}
+
+// toURLStats reads mapping's counters, which redirects update; callers must
+// hold the lock.
+func (us *URLShortener) toURLStats(mapping *URLMapping) api.URLStats {
+	return api.URLStats{
+		ShortCode:      mapping.ShortCode,
+		Namespace:      mapping.Namespace,
+		OriginalURL:    mapping.OriginalURL,
+		CreatedAt:      mapping.CreatedAt,
+		AccessCount:    mapping.AccessCount + us.pendingAccesses(mapping),
+		UniqueClicks:   mapping.UniqueClicks,
+		ExpiresAt:      mapping.ExpiresAt,
+		LastAccessedAt: lastAccessed(mapping),
+		Description:    mapping.Description,
+		Status:         mapping.status(time.Now()),
+		RecentPerMin:   mapping.hits.perMinute(time.Now()),
+		RecentClicks:   slices.Clone(mapping.clicks),
+		Retention:      us.historyRetention(mapping),
+	}
+}
+
+func (us *URLShortener) geoStatsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
 	shortCode := vars["shortCode"]
 
-	mapping, err := us.GetStats(shortCode)
+	clicks, err := us.publicClicksByCountry(r, shortCode)
 	if err != nil {
-		http.Error(w, "Short URL not found", http.StatusNotFound)
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	response := map[string]interface{}{
+		"short_code":        shortCode,
+		"clicks_by_country": clicks,
+	}
+
+	writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+}
+
+// GetStatsBatch looks up every code in one pass under the read lock. Each
+// is looked up as GET /api/stats/{code} would, so aliases are followed and
+// expired or deleted links report their status.
+func (us *URLShortener) GetStatsBatch(r *http.Request, shortCodes []string) map[string]api.BatchStatsResult {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	results := make(map[string]api.BatchStatsResult, len(shortCodes))
+	for _, code := range shortCodes {
+		if stats, _, err := us.lookupStatsLocked(r, code); err != nil {
+			results[code] = api.BatchStatsResult{Error: ErrNotFound.Error()}
+		} else {
+			results[code] = api.BatchStatsResult{Stats: &stats}
+		}
+	}
+	return results
+}
+
+func (us *URLShortener) batchStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.BatchStatsRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	stats := URLStats{
-		ShortCode:   mapping.ShortCode,
-		OriginalURL: mapping.OriginalURL,
-		CreatedAt:   mapping.CreatedAt,
-		AccessCount: mapping.AccessCount,
+	if len(req.ShortCodes) == 0 {
+		us.writeJSONError(w, r, http.StatusBadRequest, errors.New("short_codes is required and cannot be empty"))
+		return
+	}
+	if len(req.ShortCodes) > us.config.MaxBatchSize {
+		us.writeJSONError(w, r, http.StatusBadRequest, fmt.Errorf("Batch size %d exceeds the maximum of %d", len(req.ShortCodes), us.config.MaxBatchSize))
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	writeJSON(w, http.StatusOK, us.GetStatsBatch(r, req.ShortCodes), us.jsonOptions(r))
 }
 
 func (us *URLShortener) allURLsHandler(w http.ResponseWriter, r *http.Request) {
-	urls := us.getAllURLs()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(urls)
+	principal, _ := principalFromContext(r.Context())
+
+	all := principal.Admin && (!us.authEnabled() || r.URL.Query().Get("all") == "true")
+
+	// Collect copies first so slow clients are written to without the lock.
+	var urls []*URLMapping
+	us.ForEach(func(mapping *URLMapping) bool {
+		if all || mapping.Owner == principal.Owner {
+			urls = append(urls, mapping)
+		}
+		return true
+	})
+
+	w.Header().Add("Vary", "Accept")
+	if wantsCSV(r) {
+		writeURLsCSV(w, urls)
+		return
+	}
+	writeJSON(w, http.StatusOK, urls, us.jsonOptions(r))
 }
 
-func (us *URLShortener) healthHandler(w http.ResponseWriter, r *http.Request) {
-	response := map[string]string{
-		"status":  "healthy",
-		"service": "URL Shortener",
-		"time":    time.Now().Format(time.RFC3339),
+func (us *URLShortener) getURLHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	principal, _ := principalFromContext(r.Context())
+	mapping, _ := us.GetStatsView(shortCode)
+	if mapping == nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, mapping, us.jsonOptions(r))
+}
+
+func (us *URLShortener) updateURLHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	var req api.UpdateURLRequest
+	if err := us.decodeJSON(r, &req); err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	mapping, err := us.GetStatsView(shortCode)
+	if err != nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	// Validate everything before changing anything, so a bad description
+	// does not leave enabled half-applied.
+	var description string
+	if req.Description != nil {
+		description, err = sanitizeDescription(*req.Description)
+		if err != nil {
+			us.writeJSONError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if req.Enabled != nil {
+		if err := us.SetEnabled(shortCode, *req.Enabled); err != nil {
+			us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+			return
+		}
+		log.Printf("Set enabled=%v for short code: '%s'", *req.Enabled, mapping.ShortCode)
+	}
+
+	if req.Description != nil {
+		if err := us.SetDescription(shortCode, description); err != nil {
+			us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+			return
+		}
+	}
+
+	// A link just disabled comes back with an error alongside its mapping.
+	if updated, _ := us.GetStatsView(shortCode); updated != nil {
+		mapping = updated
+	}
+	writeJSON(w, http.StatusOK, mapping, us.jsonOptions(r))
+}
+
+func (us *URLShortener) deleteURLHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	principal, _ := principalFromContext(r.Context())
+	if err := us.DeleteURL(shortCode, principal); err != nil {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (us *URLShortener) restoreURLHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	principal, _ := principalFromContext(r.Context())
+	mapping, err := us.RestoreURL(shortCode, principal)
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Deleted short URL not found or restore window has passed"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mapping, us.jsonOptions(r))
+}
+
+func (us *URLShortener) rootHandler(w http.ResponseWriter, r *http.Request) {
+	switch us.config.RootBehavior {
+	case RootBehaviorJSON:
+		response := map[string]string{
+			"service": "URL Shortener",
+			"message": "Welcome! POST a URL to /api/shorten to create a short link.",
+			"health":  "/api/health",
+		}
+		writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+	case RootBehaviorRedirect:
+		http.Redirect(w, r, us.config.RootRedirectURL, http.StatusFound)
+	default:
+		w.Header().Set("Content-Type", "text/html")
+		http.ServeFile(w, r, "./static/index.html")
+	}
+}
+
+func (us *URLShortener) versionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get(), us.jsonOptions(r))
+}
+
+const (
+	staticRoot        = "static"
+	staticCacheMaxAge = time.Hour
+)
+
+func resolveStaticPath(requestPath string) (string, bool) {
+	filePath := strings.TrimPrefix(requestPath, "/static/")
+	if filePath == "" || strings.ContainsAny(filePath, "\\\x00") || path.IsAbs(filePath) || filepath.IsAbs(filePath) {
+		return "", false
+	}
+
+	for _, segment := range strings.Split(filePath, "/") {
+		if segment == ".." {
+			return "", false
+		}
+	}
+
+	fullPath := filepath.Join(staticRoot, filepath.FromSlash(filePath))
+	if !strings.HasPrefix(fullPath, staticRoot+string(filepath.Separator)) {
+		return "", false
+	}
+	return fullPath, true
+}
+
+// faviconHandler answers /favicon.ico explicitly so browser requests never
+// fall through to the short code routes.
+func faviconHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", cacheControl(staticCacheMaxAge))
+
+	iconPath := filepath.Join(staticRoot, "favicon.ico")
+	if _, err := os.Stat(iconPath); err == nil {
+		w.Header().Set("Content-Type", "image/x-icon")
+		http.ServeFile(w, r, iconPath)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func staticFileHandler(w http.ResponseWriter, r *http.Request) {
-	filePath := strings.TrimPrefix(r.URL.Path, "/static/")
-	fullPath := filepath.Join("static", filePath)
+	fullPath, ok := resolveStaticPath(r.URL.Path)
+	if !ok {
+		log.Printf("Rejected static file request outside of %s: %q", staticRoot, r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	filePath := filepath.Base(fullPath)
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
 
 	ext := filepath.Ext(filePath)
 	switch ext {
@@ -359,60 +1464,59 @@ func staticFileHandler(w http.ResponseWriter, r *http.Request) {
 	default:
 		if contentType := mime.TypeByExtension(ext); contentType != "" {
 			w.Header().Set("Content-Type", contentType)
+		} else if contentType, err := sniffContentType(file); err == nil {
+			w.Header().Set("Content-Type", contentType)
 		}
 	}
 
-	http.ServeFile(w, r, fullPath)
+	w.Header().Set("Cache-Control", cacheControl(staticCacheMaxAge))
+	w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
 
-func main() {
-	port := "8080"
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		port = envPort
+func sniffContentType(file io.ReadSeeker) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
 	}
 
-	var baseURL string
-	if renderURL := os.Getenv("RENDER_EXTERNAL_URL"); renderURL != "" {
-		baseURL = renderURL
-	} else if appURL := os.Getenv("APP_URL"); appURL != "" {
-		baseURL = appURL
-	} else {
-		baseURL = "http://localhost:" + port
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
 
-	log.Printf("Starting server on port %s with base URL: %s", port, baseURL)
+	return http.DetectContentType(buf[:n]), nil
+}
 
-	urlShortener := NewURLShortener(baseURL)
+func main() {
+	if isCLICommand(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
 
-	r := mux.NewRouter()
+	port := "8080"
+	if envPort := os.Getenv("PORT"); envPort != "" {
+		port = envPort
+	}
 
-	r.PathPrefix("/static/").HandlerFunc(staticFileHandler)
+	config := LoadConfig(port)
+	config.Validate()
+	baseURL := config.BaseURL
 
-	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		http.ServeFile(w, r, "./static/index.html")
-	}).Methods("GET")
-	r.HandleFunc("/api/shorten", urlShortener.createShortURLHandler).Methods("POST")
-	r.HandleFunc("/api/stats/{shortCode}", urlShortener.statsHandler).Methods("GET")
-	r.HandleFunc("/api/urls", urlShortener.allURLsHandler).Methods("GET")
-	r.HandleFunc("/api/health", urlShortener.healthHandler).Methods("GET")
-
-	r.HandleFunc("/{shortCode:[a-zA-Z0-9_-]{3,20}}", urlShortener.redirectHandler).Methods("GET")
-
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+	log.Printf("Starting server on port %s with base URL: %s (version %s, commit %s)", port, baseURL, version.Version, version.GitCommit)
 
-			next.ServeHTTP(w, r)
+	urlShortener := NewURLShortener(config)
+	if config.SnapshotPath != "" {
+		err := withRetry(context.Background(), urlShortener.storageRetryPolicy(), func() error {
+			return urlShortener.LoadSnapshot(config.SnapshotPath)
 		})
-	})
+		if err != nil {
+			log.Fatalf("Failed to load snapshot: %v", err)
+		}
+	}
+	urlShortener.StartSweeper(config.SweepInterval)
+	urlShortener.StartCountFlusher(config.CountFlushInterval)
+
+	handler := urlShortener.routes()
 
 	fmt.Printf("🚀 URL Shortener server starting on port %s\n", port)
 	fmt.Printf("📡 Web Interface: %s\n", baseURL)
@@ -420,10 +1524,43 @@ func main() {
 	fmt.Println("\n📋 Available endpoints:")
 	fmt.Println("   GET  /                    - Web Interface")
 	fmt.Println("   POST /api/shorten        - Create short URL")
+	fmt.Println("   POST /api/shorten/batch  - Create several short URLs")
 	fmt.Println("   GET  /{shortCode}        - Redirect to original URL")
+	fmt.Println("   GET  /{namespace}/{shortCode} - Redirect a namespaced URL")
+	fmt.Println("   GET  /{shortCode}+       - Show a URL's stats instead of redirecting")
+	fmt.Println("   GET  /api/qr/{shortCode}  - QR code for a short URL (PNG, or ?format=svg)")
+	fmt.Println("   GET  /api/available/{code} - Check whether a custom code is free")
+	fmt.Println("   GET  /api/resolve/{shortCode} - Look up a destination (JSONP: ?callback=)")
 	fmt.Println("   GET  /api/stats/{shortCode} - Get URL statistics")
-	fmt.Println("   GET  /api/urls           - Get all URLs (admin)")
-	fmt.Println("   GET  /api/health         - Health check")
+	fmt.Println("   POST /api/stats/batch    - Get statistics for several URLs")
+	fmt.Println("   GET  /api/stats/summary  - Service-wide statistics")
+	fmt.Println("   GET  /api/stats/{shortCode}/geo - Get clicks by country")
+	fmt.Println("   POST /api/campaigns      - Create a campaign")
+	fmt.Println("   GET  /api/campaigns/{id}/stats - Aggregate stats for a campaign (owner or admin)")
+	fmt.Println("   GET  /api/urls           - Get your URLs (admin: ?all=true)")
+	fmt.Println("   GET  /api/urls/search    - Search URLs by destination (admin)")
+	fmt.Println("   GET  /api/urls/{shortCode} - Get a URL record")
+	fmt.Println("   PATCH /api/urls/{shortCode} - Update a URL (e.g. enable/disable)")
+	fmt.Println("   DELETE /api/urls/{shortCode} - Delete a URL")
+	fmt.Println("   POST /api/urls/delete-batch - Delete several URLs")
+	if config.SoftDelete {
+		fmt.Println("   POST /api/urls/{shortCode}/restore - Restore a deleted URL")
+	}
+	fmt.Println("   POST /api/urls/{shortCode}/rotate - Move a URL to a new short code")
+	fmt.Println("   POST /api/urls/{shortCode}/alias - Add another code for the same destination")
+	fmt.Println("   POST /api/admin/purge    - Purge expired (and ?stale_before=) URLs (admin)")
+	fmt.Println("   GET  /api/admin/storage  - In-memory store size and footprint (admin)")
+	fmt.Println("   POST /api/admin/rebase   - Change the base URL used in responses (admin)")
+	fmt.Println("   POST /api/admin/reload   - Replace all URLs with those in RELOAD_FILE (admin)")
+	fmt.Println("   GET  /api/export         - Stream every URL as JSON (?format=ndjson) (admin)")
+	fmt.Println("   GET  /api/events         - Live click events, and creations for admins (SSE)")
+	fmt.Println("   GET  /ws/events          - Live click events, and creations for admins (WebSocket)")
+	fmt.Println("   GET  /api/health         - Health check (alias for readiness)")
+	fmt.Println("   GET  /api/health/live    - Liveness probe")
+	fmt.Println("   GET  /api/health/ready   - Readiness probe")
+	fmt.Println("   GET  /api/version        - Build information")
+	fmt.Println("   GET  /metrics            - Request latency histograms (Prometheus)")
+	fmt.Println("   GET  /robots.txt         - Crawler policy")
 	fmt.Println("\n🌐 Open your browser and go to:")
 	fmt.Printf("   %s\n", baseURL)
 	fmt.Println("\n🔗 Example API usage:")
@@ -433,14 +1570,42 @@ func main() {
 
 	server := &http.Server{
 		Addr:         ":" + port,
-		Handler:      r,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	urlShortener.markReady()
 	log.Printf("Starting HTTP server on :%s", port)
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	// Drain in-flight requests, then write back buffered counts so no visits
+	// are lost, and persist them when a snapshot is configured.
+	log.Printf("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Warning: shutdown did not finish cleanly: %v", err)
+	}
+	urlShortener.FlushCounts()
+	if err := urlShortener.CloseAnalytics(); err != nil {
+		log.Printf("Warning: failed to close analytics sink: %v", err)
+	}
+	if config.SnapshotPath != "" {
+		err := withRetry(ctx, urlShortener.storageRetryPolicy(), func() error {
+			return urlShortener.SaveSnapshot(config.SnapshotPath)
+		})
+		if err != nil {
+			log.Printf("Warning: failed to save snapshot: %v", err)
+		}
 	}
 }
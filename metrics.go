@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// latencyBuckets are the histogram upper bounds in seconds.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type latencyKey struct {
+	route       string
+	statusClass string
+}
+
+type latencyHistogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// latencyMetrics keeps request duration histograms per route template and
+// status class. Labelling by template rather than path keeps cardinality
+// bounded no matter how many short codes exist.
+type latencyMetrics struct {
+	mutex      sync.Mutex
+	histograms map[latencyKey]*latencyHistogram
+}
+
+func newLatencyMetrics() *latencyMetrics {
+	return &latencyMetrics{histograms: make(map[latencyKey]*latencyHistogram)}
+}
+
+func (m *latencyMetrics) observe(route string, status int, duration time.Duration) {
+	key := latencyKey{route: route, statusClass: fmt.Sprintf("%dxx", status/100)}
+	seconds := duration.Seconds()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, exists := m.histograms[key]
+	if !exists {
+		h = &latencyHistogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.histograms[key] = h
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which the
+// event stream relies on for flushing.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if name := route.GetName(); name != "" {
+			return name
+		}
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+func (us *URLShortener) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		us.metrics.observe(routeTemplate(r), status, time.Since(start))
+	})
+}
+
+// metricsHandler exports the latency histograms in the Prometheus text format.
+func (us *URLShortener) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	us.metrics.mutex.Lock()
+	keys := make([]latencyKey, 0, len(us.metrics.histograms))
+	for key := range us.metrics.histograms {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].statusClass < keys[j].statusClass
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP http_request_duration_seconds Request latency by route and status class.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		h := us.metrics.histograms[key]
+		labels := fmt.Sprintf("route=%q,status=%q", key.route, key.statusClass)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, h.buckets[i])
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	us.metrics.mutex.Unlock()
+
+	if us.config.MetricsLinkRates {
+		us.writeLinkRates(&b)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+// writeLinkRates adds a per-link gauge of hits in the last minute. It is
+// opt-in because it has one series per recently active link; idle links are
+// left out to keep the output small.
+func (us *URLShortener) writeLinkRates(b *strings.Builder) {
+	now := time.Now()
+
+	us.mutex.RLock()
+	keys := make([]string, 0, len(us.storage))
+	rates := make(map[string]int64)
+	for key, mapping := range us.storage {
+		if rate := mapping.hits.perMinute(now); rate > 0 {
+			keys = append(keys, key)
+			rates[key] = rate
+		}
+	}
+	us.mutex.RUnlock()
+	sort.Strings(keys)
+
+	b.WriteString("# HELP quicklink_link_hits_per_minute Redirects served per link over the last minute.\n")
+	b.WriteString("# TYPE quicklink_link_hits_per_minute gauge\n")
+	for _, key := range keys {
+		fmt.Fprintf(b, "quicklink_link_hits_per_minute{code=%q} %d\n", key, rates[key])
+	}
+}
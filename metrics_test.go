@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLatencyHistogramCountsPerRouteTemplate(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/m", CreateOptions{CustomName: "metric"})
+
+	for _, code := range []string{"metric", "metric", "missing1"} {
+		serve(t, us, http.MethodGet, "/api/stats/"+code, "")
+	}
+
+	rec := serve(t, us, http.MethodGet, "/metrics", "")
+	expectStatus(t, rec, http.StatusOK)
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_request_duration_seconds_count{route="/api/stats/{shortCode}",status="2xx"} 2`,
+		`http_request_duration_seconds_count{route="/api/stats/{shortCode}",status="4xx"} 1`,
+		`http_request_duration_seconds_bucket{route="/api/stats/{shortCode}",status="2xx",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	if strings.Contains(body, "missing1") || strings.Contains(body, `route="/api/stats/metric"`) {
+		t.Error("metrics are labelled by concrete path instead of route template")
+	}
+
+	serve(t, us, http.MethodGet, "/api/stats/metric", "")
+	if body := serve(t, us, http.MethodGet, "/metrics", "").Body.String(); !strings.Contains(body,
+		`http_request_duration_seconds_count{route="/api/stats/{shortCode}",status="2xx"} 3`) {
+		t.Error("observation count did not increase")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeoutMiddleware bounds each request with http.TimeoutHandler, which also
+// cancels the request context so downstream work can stop early. Paths with
+// one of the exempt prefixes (long-lived streams) are passed through untouched.
+func timeoutMiddleware(timeout time.Duration, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		limited := http.TimeoutHandler(next, timeout, "Request timed out")
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasAnyPrefix(r.URL.Path, exemptPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPSRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return strings.EqualFold(firstHeaderValue(r.Header.Get("X-Forwarded-Proto")), "https")
+}
+
+// requireHTTPSMiddleware redirects plain HTTP page requests to HTTPS with a 308
+// and rejects plain HTTP API calls outright, so credentials are never silently
+// sent in the clear. The health endpoint stays reachable for load balancers.
+func (us *URLShortener) requireHTTPSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !us.config.RequireHTTPS || isHTTPSRequest(r) || strings.HasPrefix(r.URL.Path, "/api/health") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			http.Error(w, "HTTPS is required", http.StatusForbidden)
+			return
+		}
+
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+// concurrencyLimitMiddleware sheds load once maxConcurrent requests are in
+// flight, answering 503 immediately instead of queueing. Exempt prefixes
+// (health probes, long-lived streams) never take a slot.
+func concurrencyLimitMiddleware(maxConcurrent int, exemptPrefixes ...string) func(http.Handler) http.Handler {
+	// mux wraps a route's handler in its middleware on every request, so the
+	// semaphore must be shared from out here.
+	semaphore := make(chan struct{}, max(maxConcurrent, 0))
+	return func(next http.Handler) http.Handler {
+		if maxConcurrent <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasAnyPrefix(r.URL.Path, exemptPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Server is busy, please retry shortly", http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+func isWriteMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
+
+// globalRateLimitMiddleware caps the whole server at GlobalRateLimit requests
+// per second, bursting to GlobalRateBurst, whoever sends them; with
+// GlobalRateWritesOnly, reads are not counted. Excess requests get a 429 with
+// Retry-After. Health checks are exempt.
+func (us *URLShortener) globalRateLimitMiddleware(next http.Handler) http.Handler {
+	if us.rateLimit == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/health") || (us.config.GlobalRateWritesOnly && !isWriteMethod(r.Method)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, wait := us.rateLimit.take(time.Now())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+			us.writeJSONError(w, r, http.StatusTooManyRequests, errorf(ErrRateLimited, "Too many requests, please retry shortly"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
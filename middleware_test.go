@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTimeoutMiddlewareAnswers503(t *testing.T) {
+	canceled := make(chan bool, 1)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			canceled <- true
+		case <-time.After(time.Second):
+			canceled <- false
+		}
+	})
+	handler := timeoutMiddleware(20*time.Millisecond, "/api/events")(slow)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	if !<-canceled {
+		t.Fatal("slow handler's context was not canceled")
+	}
+}
+
+func TestTimeoutMiddlewareSkipsExemptPaths(t *testing.T) {
+	handler := timeoutMiddleware(10*time.Millisecond, "/api/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestRequireHTTPS(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.RequireHTTPS = true })
+	mustCreate(t, us, "https://example.com/secure", CreateOptions{CustomName: "secure"})
+
+	rec := serve(t, us, http.MethodGet, "/secure?x=1", "")
+	expectStatus(t, rec, http.StatusPermanentRedirect)
+	if got := rec.Header().Get("Location"); got != "https://example.com/secure?x=1" {
+		t.Fatalf("Location = %q", got)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/stats/secure", ""), http.StatusForbidden)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/health/live", ""), http.StatusOK)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/secure", "", "X-Forwarded-Proto", "https"), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/stats/secure", "", "X-Forwarded-Proto", "https"), http.StatusOK)
+}
+
+func TestHTTPAllowedByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/plain", CreateOptions{CustomName: "plain"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/plain", ""), http.StatusMovedPermanently)
+}
+
+func TestConcurrencyLimitSheds503(t *testing.T) {
+	const limit = 2
+	release := make(chan struct{})
+	started := make(chan struct{}, limit+1)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+	r.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {})
+	r.Use(concurrencyLimitMiddleware(limit, "/api/health"))
+
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		}()
+		return rec
+	}
+	for i := 0; i < limit; i++ {
+		get("/slow")
+		<-started
+	}
+
+	over := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		r.ServeHTTP(over, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("request beyond the limit was let through")
+	}
+	expectStatus(t, over, http.StatusServiceUnavailable)
+	if over.Header().Get("Retry-After") == "" {
+		t.Fatal("503 without Retry-After")
+	}
+
+	health := httptest.NewRecorder()
+	r.ServeHTTP(health, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	expectStatus(t, health, http.StatusOK)
+
+	close(release)
+	inFlight.Wait()
+	after := httptest.NewRecorder()
+	r.ServeHTTP(after, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	expectStatus(t, after, http.StatusOK)
+}
+
+func TestGlobalRateLimitAppliesAcrossClients(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.GlobalRateLimit = 0.001
+		c.GlobalRateBurst = 3
+	})
+	mustCreate(t, us, "https://example.com/limited", CreateOptions{CustomName: "limited"})
+	handler := us.routes()
+
+	statuses := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:4000", i+1)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		statuses = append(statuses, rec.Code)
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Fatal("429 without Retry-After")
+		}
+	}
+	want := []int{http.StatusMovedPermanently, http.StatusMovedPermanently, http.StatusMovedPermanently, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	if !slices.Equal(statuses, want) {
+		t.Fatalf("statuses from distinct IPs = %v, want %v", statuses, want)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/health/live", ""), http.StatusOK)
+}
+
+func TestGlobalRateLimitWritesOnly(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.GlobalRateLimit = 0.001
+		c.GlobalRateBurst = 1
+		c.GlobalRateWritesOnly = true
+	})
+	mustCreate(t, us, "https://example.com/read", CreateOptions{CustomName: "readme"})
+
+	for i := 0; i < 3; i++ {
+		expectStatus(t, serve(t, us, http.MethodGet, "/readme", ""), http.StatusMovedPermanently)
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/one"}`), http.StatusCreated)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/two"}`), http.StatusTooManyRequests)
+}
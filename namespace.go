@@ -0,0 +1,49 @@
+package main
+
+import "strings"
+
+// Namespaced links are stored under "namespace:code". The separator cannot
+// appear in a short code, so keys never collide with the default namespace,
+// and the key doubles as the ID used by the /api/... endpoints.
+const namespaceSeparator = ":"
+
+var reservedNamespaces = map[string]bool{
+	"api":    true,
+	"static": true,
+}
+
+func storageKey(namespace, shortCode string) string {
+	if namespace == "" {
+		return shortCode
+	}
+	return namespace + namespaceSeparator + shortCode
+}
+
+func isValidNamespace(namespace string) bool {
+	if len(namespace) < 1 || len(namespace) > 20 || reservedNamespaces[namespace] {
+		return false
+	}
+
+	for _, char := range namespace {
+		if !((char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns the public redirect path for the mapping, e.g. "abc123" or "p/abc123".
+func (m *URLMapping) path() string {
+	if m.Namespace == "" {
+		return m.ShortCode
+	}
+	return m.Namespace + "/" + m.ShortCode
+}
+
+func splitStorageKey(key string) (namespace, shortCode string) {
+	namespace, shortCode, found := strings.Cut(key, namespaceSeparator)
+	if !found {
+		return "", key
+	}
+	return namespace, shortCode
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNamespacesIsolateIdenticalCodes(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/default", CreateOptions{CustomName: "sale"})
+	mustCreate(t, us, "https://example.com/promo", CreateOptions{CustomName: "sale", Namespace: "p"})
+	mustCreate(t, us, "https://example.com/docs", CreateOptions{CustomName: "sale", Namespace: "d"})
+
+	for path, want := range map[string]string{
+		"/sale":   "https://example.com/default",
+		"/p/sale": "https://example.com/promo",
+		"/d/sale": "https://example.com/docs",
+	} {
+		rec := serve(t, us, http.MethodGet, path, "")
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != want {
+			t.Errorf("%s redirected to %q, want %q", path, got, want)
+		}
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/x/sale", ""), http.StatusNotFound)
+
+	if _, _, err := us.CreateShortURL("https://example.com/again", CreateOptions{CustomName: "sale", Namespace: "p"}); err == nil {
+		t.Fatal("reused a custom name within its namespace")
+	}
+}
+
+func TestNamespaceValidation(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for _, namespace := range []string{"api", "Promo", "has space", "waytoolongnamespace-abc"} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/ns","namespace":"`+namespace+`"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("namespace %q: status = %d, want 400", namespace, rec.Code)
+		}
+	}
+}
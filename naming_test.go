@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSnakeToCamel(t *testing.T) {
+	for in, want := range map[string]string{
+		"short_code":          "shortCode",
+		"original_url":        "originalUrl",
+		"recent_rate_per_min": "recentRatePerMin",
+		"enabled":             "enabled",
+		"x__y":                "xY",
+	} {
+		if got := snakeToCamel(in); got != want {
+			t.Errorf("snakeToCamel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func decodeFields(t *testing.T, body []byte) map[string]json.RawMessage {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return fields
+}
+
+func expectFields(t *testing.T, fields map[string]json.RawMessage, present, absent []string) {
+	t.Helper()
+	for _, key := range present {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("missing field %q in %v", key, fields)
+		}
+	}
+	for _, key := range absent {
+		if _, ok := fields[key]; ok {
+			t.Errorf("unexpected field %q", key)
+		}
+	}
+}
+
+func TestSnakeCaseIsDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/snake","custom_name":"snake"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	expectFields(t, decodeFields(t, rec.Body.Bytes()), []string{"short_code", "original_url", "short_url"}, []string{"shortCode"})
+
+	rec = serve(t, us, http.MethodGet, "/api/stats/snake", "")
+	expectFields(t, decodeFields(t, rec.Body.Bytes()), []string{"short_code", "access_count", "recent_rate_per_min"}, []string{"accessCount"})
+}
+
+func TestCamelCaseNaming(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config func(*Config)
+		query  string
+	}{
+		"query":  {nil, "?naming=camel"},
+		"config": {func(c *Config) { c.JSONNaming = JSONNamingCamel }, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			us := newTestShortener(t, tt.config)
+			rec := serve(t, us, http.MethodPost, "/api/shorten"+tt.query,
+				`{"url":"https://example.com/camel","custom_name":"camel"}`)
+			expectStatus(t, rec, http.StatusCreated)
+			expectFields(t, decodeFields(t, rec.Body.Bytes()), []string{"shortCode", "originalUrl", "shortUrl"}, []string{"short_code"})
+
+			rec = serve(t, us, http.MethodGet, "/api/stats/camel"+tt.query, "")
+			expectStatus(t, rec, http.StatusOK)
+			fields := decodeFields(t, rec.Body.Bytes())
+			expectFields(t, fields, []string{"shortCode", "originalUrl", "accessCount", "recentRatePerMin"}, []string{"short_code", "access_count"})
+		})
+	}
+}
+
+func TestNamingQueryOverridesConfig(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.JSONNaming = JSONNamingCamel })
+	mustCreate(t, us, "https://example.com/snake", CreateOptions{CustomName: "snake"})
+
+	rec := serve(t, us, http.MethodGet, "/api/stats/snake?naming=snake", "")
+	expectFields(t, decodeFields(t, rec.Body.Bytes()), []string{"short_code"}, []string{"shortCode"})
+}
+
+func TestCamelCaseMatchesSnakeCaseValues(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/same", CreateOptions{CustomName: "same", Description: "note"})
+
+	snake := decodeFields(t, serve(t, us, http.MethodGet, "/api/stats/same", "").Body.Bytes())
+	camel := decodeFields(t, serve(t, us, http.MethodGet, "/api/stats/same?naming=camel", "").Body.Bytes())
+	if len(snake) != len(camel) {
+		t.Fatalf("snake has %d fields, camel has %d", len(snake), len(camel))
+	}
+	for key, value := range snake {
+		if value[0] == '{' {
+			continue // nested objects are renamed too
+		}
+		if string(camel[snakeToCamel(key)]) != string(value) {
+			t.Errorf("%s = %s, camel %s = %s", key, value, snakeToCamel(key), camel[snakeToCamel(key)])
+		}
+	}
+}
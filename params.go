@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/url"
+	"unicode"
+)
+
+const (
+	maxAppendParams     = 20
+	maxAppendParamKey   = 64
+	maxAppendParamValue = 512
+)
+
+func isValidParamKey(key string) bool {
+	if key == "" || len(key) > maxAppendParamKey {
+		return false
+	}
+	for _, char := range key {
+		if !((char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') ||
+			char == '-' || char == '_' || char == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func validateAppendParams(params map[string]string) error {
+	if len(params) > maxAppendParams {
+		return errorf(ErrInvalidParams, "at most %d append params are allowed", maxAppendParams)
+	}
+	for key, value := range params {
+		if !isValidParamKey(key) {
+			return errorf(ErrInvalidParams, "invalid param key '%s': must be 1-%d letters, numbers, '.', '-' or '_'", key, maxAppendParamKey)
+		}
+		if len(value) > maxAppendParamValue {
+			return errorf(ErrInvalidParams, "value for param '%s' is longer than %d characters", key, maxAppendParamValue)
+		}
+		for _, char := range value {
+			if unicode.IsControl(char) {
+				return errorf(ErrInvalidParams, "value for param '%s' contains control characters", key)
+			}
+		}
+	}
+	return nil
+}
+
+// withAppendParams merges params into the destination's query string. Params
+// the destination already carries are kept unless overwrite is set.
+func withAppendParams(destination string, params map[string]string, overwrite bool) string {
+	if len(params) == 0 {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		if query.Has(key) && !overwrite {
+			continue
+		}
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func redirectQuery(t *testing.T, us *URLShortener, path string) url.Values {
+	t.Helper()
+	rec := serve(t, us, http.MethodGet, path, "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
+
+func TestAppendParamsOnCleanDestination(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/landing", CreateOptions{
+		CustomName:   "utm",
+		AppendParams: map[string]string{"utm_source": "newsletter", "utm_medium": "email"},
+	})
+
+	query := redirectQuery(t, us, "/utm")
+	if query.Get("utm_source") != "newsletter" || query.Get("utm_medium") != "email" {
+		t.Fatalf("query = %v", query)
+	}
+}
+
+func TestAppendParamsWithExistingParams(t *testing.T) {
+	us := newTestShortener(t, nil)
+	params := map[string]string{"utm_source": "newsletter", "ref": "campaign"}
+	mustCreate(t, us, "https://example.com/p?utm_source=partner&id=7", CreateOptions{CustomName: "keep", AppendParams: params})
+	mustCreate(t, us, "https://example.com/p?utm_source=partner&id=7", CreateOptions{CustomName: "overwrite", AppendParams: params, OverwriteParams: true})
+
+	kept := redirectQuery(t, us, "/keep")
+	if kept.Get("utm_source") != "partner" || kept.Get("ref") != "campaign" || kept.Get("id") != "7" {
+		t.Fatalf("keep: query = %v", kept)
+	}
+	overwritten := redirectQuery(t, us, "/overwrite")
+	if overwritten.Get("utm_source") != "newsletter" || overwritten.Get("ref") != "campaign" || overwritten.Get("id") != "7" {
+		t.Fatalf("overwrite: query = %v", overwritten)
+	}
+}
+
+func TestAppendParamsValidation(t *testing.T) {
+	us := newTestShortener(t, nil)
+	for _, params := range []string{`{"bad key":"x"}`, `{"":"x"}`, `{"k":"line\nbreak"}`} {
+		rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v","append_params":`+params+`}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("append_params %s: status = %d, want 400", params, rec.Code)
+		}
+	}
+}
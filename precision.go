@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"url-shortener/api"
+)
+
+// roundCount rounds n down to precision significant digits and returns it
+// with a display label such as "100+" or "1.2k+". Counts that are already
+// exact at that precision are labelled plainly, and a precision of zero or
+// less leaves n untouched.
+func roundCount(n int64, precision int) (int64, string) {
+	if precision <= 0 || n < 0 {
+		return n, strconv.FormatInt(n, 10)
+	}
+
+	scale := int64(1)
+	for digits := len(strconv.FormatInt(n, 10)); digits > precision; digits-- {
+		scale *= 10
+	}
+	rounded := n / scale * scale
+
+	label := compactCount(rounded)
+	if rounded != n {
+		label += "+"
+	}
+	return rounded, label
+}
+
+// compactCount formats n with a k or M suffix, e.g. 1200 as "1.2k".
+func compactCount(n int64) string {
+	for _, unit := range []struct {
+		size   int64
+		suffix string
+	}{{1_000_000, "M"}, {1_000, "k"}} {
+		if n >= unit.size {
+			whole := strconv.FormatInt(n/unit.size, 10)
+			fraction := strings.TrimRight(strconv.FormatInt(n%unit.size*10/unit.size, 10), "0")
+			if fraction != "" && len(whole) == 1 {
+				return whole + "." + fraction + unit.suffix
+			}
+			return whole + unit.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// seesExactCounts reports whether the caller of r may see a link's exact
+// counts: admins and the link's owner always do.
+func (us *URLShortener) seesExactCounts(r *http.Request, mapping *URLMapping) bool {
+	return us.seesExactCountsOf(r, mapping.Owner)
+}
+
+// seesExactCountsOf is seesExactCounts for a link known only by its owner.
+func (us *URLShortener) seesExactCountsOf(r *http.Request, owner string) bool {
+	return us.config.StatsPrecision <= 0 || callerOwns(r, owner)
+}
+
+// publicURLStats is toURLStats with counts rounded to StatsPrecision for
+// callers who may not see exact values, and without the destination of a
+// guarded link the caller has not unlocked.
+func (us *URLShortener) publicURLStats(r *http.Request, mapping *URLMapping) api.URLStats {
+	stats := us.toURLStats(mapping)
+	if !us.revealsDestination(r, mapping) {
+		stats.OriginalURL = ""
+	}
+	if us.seesExactCounts(r, mapping) {
+		return stats
+	}
+
+	stats.AccessCount, stats.AccessCountLabel = roundCount(stats.AccessCount, us.config.StatsPrecision)
+	stats.UniqueClicks, _ = roundCount(stats.UniqueClicks, us.config.StatsPrecision)
+	stats.RecentPerMin, _ = roundCount(stats.RecentPerMin, us.config.StatsPrecision)
+	// Click times would give the exact count away.
+	stats.RecentClicks = nil
+	return stats
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestRoundCount(t *testing.T) {
+	for _, tt := range []struct {
+		n         int64
+		precision int
+		want      int64
+		label     string
+	}{
+		{1234, 0, 1234, "1234"},
+		{7, 1, 7, "7"},
+		{42, 1, 40, "40+"},
+		{40, 1, 40, "40"},
+		{1234, 1, 1000, "1k+"},
+		{1234, 2, 1200, "1.2k+"},
+		{1200, 2, 1200, "1.2k"},
+		{98765, 2, 98000, "98k+"},
+		{98765, 3, 98700, "98k+"},
+		{1_500_000, 2, 1_500_000, "1.5M"},
+		{12_345_678, 3, 12_300_000, "12M+"},
+		{99, 5, 99, "99"},
+	} {
+		got, label := roundCount(tt.n, tt.precision)
+		if got != tt.want || label != tt.label {
+			t.Errorf("roundCount(%d, %d) = %d, %q; want %d, %q", tt.n, tt.precision, got, label, tt.want, tt.label)
+		}
+	}
+}
+
+func TestPublicStatsAreRounded(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.StatsPrecision = 2
+		c.APIKeys = map[string]string{"alice-key": "alice", "bob-key": "bob"}
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+	})
+	mapping := mustCreate(t, us, "https://example.com/popular", CreateOptions{CustomName: "popular", Owner: "alice"})
+	mapping.AccessCount = 1234
+
+	for _, tt := range []struct {
+		name    string
+		headers []string
+		count   int64
+		label   string
+	}{
+		{"anonymous", nil, 1200, "1.2k+"},
+		{"other user", []string{"X-API-Key", "bob-key"}, 1200, "1.2k+"},
+		{"owner", []string{"X-API-Key", "alice-key"}, 1234, ""},
+		{"admin", []string{"X-API-Key", "admin-key"}, 1234, ""},
+	} {
+		rec := serve(t, us, http.MethodGet, "/api/stats/popular", "", tt.headers...)
+		expectStatus(t, rec, http.StatusOK)
+		var stats api.URLStats
+		decodeBody(t, rec, &stats)
+		if stats.AccessCount != tt.count || stats.AccessCountLabel != tt.label {
+			t.Errorf("%s sees %d %q, want %d %q", tt.name, stats.AccessCount, stats.AccessCountLabel, tt.count, tt.label)
+		}
+	}
+}
+
+func TestStatsExactWithoutPrecision(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mapping := mustCreate(t, us, "https://example.com/popular", CreateOptions{CustomName: "popular"})
+	mapping.AccessCount = 1234
+
+	var stats api.URLStats
+	decodeBody(t, serve(t, us, http.MethodGet, "/api/stats/popular", ""), &stats)
+	if stats.AccessCount != 1234 || stats.AccessCountLabel != "" {
+		t.Fatalf("stats = %d %q, want exact", stats.AccessCount, stats.AccessCountLabel)
+	}
+}
+
+func TestGeoStatsAreRounded(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.StatsPrecision = 1
+		c.APIKeys = map[string]string{"alice-key": "alice"}
+	})
+	mustCreate(t, us, "https://example.com/popular", CreateOptions{CustomName: "popular", Owner: "alice"})
+	for i := 0; i < 42; i++ {
+		us.countClickCountry("popular", "GB")
+	}
+
+	for _, tt := range []struct {
+		name    string
+		headers []string
+		count   int64
+	}{
+		{"anonymous", nil, 40},
+		{"owner", []string{"X-API-Key", "alice-key"}, 42},
+	} {
+		rec := serve(t, us, http.MethodGet, "/api/stats/popular/geo", "", tt.headers...)
+		expectStatus(t, rec, http.StatusOK)
+		var body struct {
+			Clicks map[string]int64 `json:"clicks_by_country"`
+		}
+		decodeBody(t, rec, &body)
+		if body.Clicks["GB"] != tt.count {
+			t.Errorf("%s sees GB = %d, want %d", tt.name, body.Clicks["GB"], tt.count)
+		}
+	}
+}
+
+func TestClickEventsAreRounded(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.StatsPrecision = 1
+		c.APIKeys = map[string]string{"alice-key": "alice"}
+	})
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+
+	mapping := mustCreate(t, us, "https://example.com/popular", CreateOptions{CustomName: "popular", Owner: "alice"})
+	mapping.AccessCount = 41
+	anonymous, _ := dialWS(t, server)
+	owner, _ := dialWS(t, server, "X-API-Key", "alice-key")
+	waitForSubscribers(us, 2)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/popular", ""), http.StatusMovedPermanently)
+
+	if event := readWSEvent(t, anonymous); event.AccessCount != 40 {
+		t.Errorf("anonymous subscriber sees %d clicks, want 40", event.AccessCount)
+	}
+	if event := readWSEvent(t, owner); event.AccessCount != 42 {
+		t.Errorf("owner sees %d clicks, want 42", event.AccessCount)
+	}
+}
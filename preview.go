@@ -0,0 +1,99 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"url-shortener/api"
+)
+
+// previewTemplate is the HTML form of a link's stats, served for /code+.
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.ShortURL}}</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<main class="container">
+<h1>{{.ShortURL}}</h1>
+{{if .Stats.Description}}<p>{{.Stats.Description}}</p>{{end}}
+<dl>
+<dt>Destination</dt><dd>{{if .Stats.OriginalURL}}<a href="{{.Stats.OriginalURL}}" rel="noopener noreferrer nofollow">{{.Stats.OriginalURL}}</a>{{else}}Unavailable{{end}}</dd>
+<dt>Status</dt><dd>{{.Stats.Status}}</dd>
+{{if not .Stats.CreatedAt.IsZero}}<dt>Created</dt><dd>{{.Stats.CreatedAt.Format "2 Jan 2006"}}</dd>{{end}}
+<dt>Clicks</dt><dd>{{.Clicks}}</dd>
+</dl>
+</main>
+</body>
+</html>
+`))
+
+type previewPage struct {
+	ShortURL string
+	Stats    api.URLStats
+	Clicks   string
+}
+
+// wantsHTML reports whether the Accept header lists text/html ahead of JSON.
+func wantsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+// previewHandler serves /code+ and /namespace/code+, bit.ly style: the
+// link's public stats instead of a redirect, as an HTML page for browsers
+// and JSON otherwise. Like /api/stats, it is not counted as a visit.
+func (us *URLShortener) previewHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if us.serveReservedPath(w, r, vars) {
+		return
+	}
+	shortCode := storageKey(vars["namespace"], vars["shortCode"])
+
+	w.Header().Add("Vary", "Accept")
+	html := wantsHTML(r)
+	stats, status, err := us.lookupStats(r, shortCode)
+	if err != nil {
+		if html {
+			http.Error(w, "Short URL not found", http.StatusNotFound)
+		} else {
+			us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		}
+		return
+	}
+
+	if !html {
+		writeJSON(w, status, stats, us.jsonOptions(r))
+		return
+	}
+
+	clicks := stats.AccessCountLabel
+	if clicks == "" {
+		clicks = strconv.FormatInt(stats.AccessCount, 10)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := previewTemplate.Execute(w, previewPage{
+		ShortURL: us.publicBaseURL(r) + "/" + shortCode,
+		Stats:    stats,
+		Clicks:   clicks,
+	}); err != nil {
+		log.Printf("Error rendering preview for '%s': %v", shortCode, err)
+	}
+}
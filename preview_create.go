@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"url-shortener/api"
+)
+
+// codePreview holds a code shown by POST /api/shorten?preview=1 until the
+// link is created with its token or the reservation expires.
+type codePreview struct {
+	token       string
+	owner       string
+	originalURL string
+	expiresAt   time.Time
+}
+
+func newPreviewToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// previewHeldLocked reports whether key is reserved by an unexpired preview.
+func (us *URLShortener) previewHeldLocked(key string) bool {
+	preview, held := us.previews[key]
+	return held && time.Now().Before(preview.expiresAt)
+}
+
+// reservePreviewLocked holds key for PreviewTTL under token. Only an expired
+// reservation can be in its place, and that one is dropped.
+func (us *URLShortener) reservePreviewLocked(key, token, owner, originalURL string, now time.Time) {
+	us.dropPreviewLocked(key)
+	us.previews[key] = codePreview{token: token, owner: owner, originalURL: originalURL, expiresAt: now.Add(us.config.PreviewTTL)}
+	us.previewTokens[token] = key
+}
+
+// dropPreviewLocked releases the reservation of key, if any.
+func (us *URLShortener) dropPreviewLocked(key string) {
+	if preview, held := us.previews[key]; held {
+		delete(us.previewTokens, preview.token)
+		delete(us.previews, key)
+	}
+}
+
+// purgePreviews drops expired reservations, so abandoned previews do not
+// pile up.
+func (us *URLShortener) purgePreviews(now time.Time) int {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	purged := 0
+	for key, preview := range us.previews {
+		if !now.Before(preview.expiresAt) {
+			us.dropPreviewLocked(key)
+			purged++
+		}
+	}
+	return purged
+}
+
+// previewExpiry returns when the reservation of key lapses.
+func (us *URLShortener) previewExpiry(key string) time.Time {
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+
+	return us.previews[key].expiresAt
+}
+
+// claimPreviewLocked checks the reservation made under token and returns its
+// key. The link being created must be the one that was previewed. The
+// reservation stays in place until the link is stored, so a request that
+// fails here or later leaves the code held for a retry.
+func (us *URLShortener) claimPreviewLocked(token, namespace, owner, originalURL string, now time.Time) (string, error) {
+	key, known := us.previewTokens[token]
+	if !known {
+		return "", errorf(ErrInvalidPreview, "preview token is unknown or has expired")
+	}
+	preview := us.previews[key]
+	if !now.Before(preview.expiresAt) {
+		us.dropPreviewLocked(key)
+		return "", errorf(ErrInvalidPreview, "preview token is unknown or has expired")
+	}
+	if heldNamespace, _ := splitStorageKey(key); heldNamespace != namespace || preview.owner != owner || preview.originalURL != originalURL {
+		return "", errorf(ErrInvalidPreview, "preview token was issued for a different link")
+	}
+	_, isLink := us.storage[key]
+	_, isAlias := us.aliases[key]
+	if isLink || isAlias {
+		return "", errorf(ErrCodeTaken, "previewed short code is no longer available")
+	}
+	return key, nil
+}
+
+// previewShortURL answers POST /api/shorten?preview=1. The request goes
+// through the same validation and code choice as a create, but the link is
+// not stored: its code is held for PreviewTTL, and a create request carrying
+// the returned preview_token stores the link under that code. If the URL is
+// already shortened, the existing code is returned and nothing is held.
+func (us *URLShortener) previewShortURL(w http.ResponseWriter, r *http.Request, req api.CreateURLRequest) {
+	opts, err := us.createOptions(r.Context(), req, ownerFromRequest(r))
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	opts.Preview = true
+	opts.PreviewToken, err = newPreviewToken()
+	if err != nil {
+		log.Printf("Error issuing preview token: %v", err)
+		us.writeJSONError(w, r, http.StatusInternalServerError, errors.New("Could not issue a preview token"))
+		return
+	}
+
+	mapping, reserved, err := us.CreateShortURL(req.URL, opts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidURL) {
+			log.Printf("Error previewing short URL: %v: %s", ErrInvalidURL, us.logURL(req.URL))
+		} else {
+			log.Printf("Error previewing short URL: %v", err)
+		}
+		if errors.Is(err, ErrCodeTaken) {
+			us.writeJSONError(w, r, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, ErrCodesExhausted) {
+			us.writeJSONError(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		us.writeJSONError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := api.PreviewURLResponse{
+		ShortCode:   mapping.ShortCode,
+		Namespace:   mapping.Namespace,
+		OriginalURL: mapping.OriginalURL,
+		ShortURL:    fmt.Sprintf("%s/%s", us.publicBaseURL(r), mapping.path()),
+		Exists:      !reserved,
+	}
+	if reserved {
+		expiresAt := us.previewExpiry(mapping.ID)
+		resp.PreviewToken = opts.PreviewToken
+		resp.ExpiresAt = &expiresAt
+	}
+	writeJSON(w, http.StatusOK, resp, us.jsonOptions(r))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func previewLink(t *testing.T, us *URLShortener, body string) api.PreviewURLResponse {
+	t.Helper()
+	rec := serve(t, us, http.MethodPost, "/api/shorten?preview=1", body)
+	expectStatus(t, rec, http.StatusOK)
+	var preview api.PreviewURLResponse
+	decodeBody(t, rec, &preview)
+	return preview
+}
+
+func TestPreviewThenConfirm(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	preview := previewLink(t, us, `{"url":"https://example.com/launch"}`)
+	if preview.Exists || preview.ShortCode == "" || preview.PreviewToken == "" || preview.ExpiresAt == nil {
+		t.Fatalf("preview = %+v, want a held code with a token and expiry", preview)
+	}
+	if _, err := us.GetOriginalURL(preview.ShortCode); err == nil {
+		t.Fatal("previewed code resolves before it was confirmed")
+	}
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/launch","preview_token":"`+preview.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	var created api.CreateURLDetails
+	decodeBody(t, rec, &created)
+	if created.ShortCode != preview.ShortCode || created.ShortURL != preview.ShortURL {
+		t.Errorf("created %s (%s), previewed %s (%s)", created.ShortCode, created.ShortURL, preview.ShortCode, preview.ShortURL)
+	}
+
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/other","preview_token":"`+preview.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeInvalidPreview {
+		t.Errorf("reused token: error code = %s, want %s", body.ErrorCode, CodeInvalidPreview)
+	}
+
+	again := previewLink(t, us, `{"url":"https://example.com/launch"}`)
+	if !again.Exists || again.ShortCode != preview.ShortCode || again.PreviewToken != "" {
+		t.Errorf("preview of an existing link = %+v, want the existing code and no token", again)
+	}
+}
+
+func TestPreviewHoldsCode(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	preview := previewLink(t, us, `{"url":"https://example.com/a","custom_name":"launch"}`)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","custom_name":"launch"}`)
+	expectStatus(t, rec, http.StatusConflict)
+	rec = serve(t, us, http.MethodPost, "/api/shorten?preview=1", `{"url":"https://example.com/b","custom_name":"launch"}`)
+	expectStatus(t, rec, http.StatusConflict)
+
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","preview_token":"`+preview.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestExpiredPreviewFreesCode(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.PreviewTTL = time.Millisecond })
+
+	preview := previewLink(t, us, `{"url":"https://example.com/a","custom_name":"launch"}`)
+	time.Sleep(5 * time.Millisecond)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/a","preview_token":"`+preview.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeInvalidPreview {
+		t.Errorf("expired token: error code = %s, want %s", body.ErrorCode, CodeInvalidPreview)
+	}
+
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","custom_name":"launch"}`)
+	expectStatus(t, rec, http.StatusCreated)
+}
+
+func TestFailedClaimKeepsPreview(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MaxLinks = 1 })
+	preview := previewLink(t, us, `{"url":"https://example.com/a","custom_name":"launch"}`)
+	confirm := `{"url":"https://example.com/a","preview_token":"` + preview.PreviewToken + `"}`
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","preview_token":"`+preview.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	filler := mustCreate(t, us, "https://example.com/filler", CreateOptions{})
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", confirm), http.StatusInsufficientStorage)
+
+	// Neither failure released the code, so the claim still works once there
+	// is room.
+	us.mutex.Lock()
+	delete(us.storage, filler.ID)
+	us.mutex.Unlock()
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", confirm), http.StatusCreated)
+}
+
+func TestSweepDropsExpiredPreviews(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.PreviewTTL = time.Minute })
+	previewLink(t, us, `{"url":"https://example.com/a","custom_name":"stale"}`)
+	fresh := previewLink(t, us, `{"url":"https://example.com/b","custom_name":"fresh"}`)
+	us.mutex.Lock()
+	stale := us.previews["stale"]
+	stale.expiresAt = time.Now().Add(-time.Second)
+	us.previews["stale"] = stale
+	us.mutex.Unlock()
+
+	us.sweep(time.Now())
+	us.mutex.RLock()
+	held, tokens := len(us.previews), len(us.previewTokens)
+	us.mutex.RUnlock()
+	if held != 1 || tokens != 1 {
+		t.Fatalf("%d previews and %d tokens left, want only the fresh one", held, tokens)
+	}
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/b","preview_token":"`+fresh.PreviewToken+`"}`)
+	expectStatus(t, rec, http.StatusCreated)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestPlusSuffixShowsStatsInsteadOfRedirecting(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/article", CreateOptions{CustomName: "abc123"})
+
+	rec := serve(t, us, http.MethodGet, "/abc123", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("Location"); got != "https://example.com/article" {
+		t.Fatalf("/abc123 redirected to %q", got)
+	}
+
+	rec = serve(t, us, http.MethodGet, "/abc123+", "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.URLStats
+	decodeBody(t, rec, &stats)
+	if stats.ShortCode != "abc123" || stats.OriginalURL != "https://example.com/article" || stats.AccessCount != 1 {
+		t.Fatalf("/abc123+ stats = %+v", stats)
+	}
+	if n := accessCount(t, us, "abc123"); n != 1 {
+		t.Fatalf("access count after a preview = %d, want 1", n)
+	}
+
+	rec = serve(t, us, http.MethodGet, "/abc123+", "", "Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("browser preview Content-Type = %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "https://example.com/article") || !strings.Contains(body, testBaseURL+"/abc123") {
+		t.Fatalf("preview page lacks the link details:\n%s", body)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/missing+", ""), http.StatusNotFound)
+}
+
+func TestPlusSuffixWorksForNamespacedLinks(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/team", CreateOptions{CustomName: "wiki", Namespace: "team"})
+
+	rec := serve(t, us, http.MethodGet, "/team/wiki+", "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.URLStats
+	decodeBody(t, rec, &stats)
+	if stats.Namespace != "team" || stats.ShortCode != "wiki" || stats.AccessCount != 0 {
+		t.Fatalf("/team/wiki+ stats = %+v", stats)
+	}
+}
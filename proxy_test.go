@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func shortURLFor(t *testing.T, us *URLShortener, headers ...string) string {
+	t.Helper()
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/p","custom_name":"proxied"}`, headers...)
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		ShortURL string `json:"short_url"`
+	}
+	decodeBody(t, rec, &body)
+	return body.ShortURL
+}
+
+func TestShortURLUsesForwardedSchemeAndHost(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.TrustProxyHeaders = true })
+	got := shortURLFor(t, us, "X-Forwarded-Proto", "https", "X-Forwarded-Host", "sho.rt, internal:8080")
+	if got != "https://sho.rt/proxied" {
+		t.Fatalf("short_url = %q, want https://sho.rt/proxied", got)
+	}
+}
+
+func TestShortURLIgnoresForwardedHeadersUnlessTrusted(t *testing.T) {
+	us := newTestShortener(t, nil)
+	got := shortURLFor(t, us, "X-Forwarded-Proto", "https", "X-Forwarded-Host", "evil.example")
+	if got != testBaseURL+"/proxied" {
+		t.Fatalf("short_url = %q, want %s/proxied", got, testBaseURL)
+	}
+}
+
+func TestShortURLIgnoresUnknownForwardedProto(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.TrustProxyHeaders = true })
+	got := shortURLFor(t, us, "X-Forwarded-Proto", "javascript")
+	if got != "http://example.com/proxied" {
+		t.Fatalf("short_url = %q, want http://example.com/proxied", got)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+const (
+	qrFormatPNG = "png"
+	qrFormatSVG = "svg"
+)
+
+// qrOptions are the rendering choices that change a QR code's bytes.
+type qrOptions struct {
+	size       int
+	format     string
+	foreground string // six-digit lowercase hex, without '#'
+	background string
+}
+
+func qrETag(shortURL string, opts qrOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%s|%s", shortURL, opts.size, opts.format, opts.foreground, opts.background)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// parseHexColor accepts "rrggbb" or "rgb", with or without a leading '#',
+// and returns it as six lowercase hex digits.
+func parseHexColor(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimPrefix(value, "#"))
+	if len(value) == 3 {
+		value = string([]byte{value[0], value[0], value[1], value[1], value[2], value[2]})
+	}
+	if len(value) != 6 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+func hexToRGBA(value string) color.RGBA {
+	b, _ := hex.DecodeString(value)
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+}
+
+// qrSVG renders q as a size-pixel square SVG. Each row's runs of dark
+// modules become one path segment, so the output stays small and scales
+// without blurring.
+func qrSVG(q *qrcode.QRCode, opts qrOptions) []byte {
+	bitmap := q.Bitmap()
+	n := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < n; {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < n && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, opts.size, opts.size, n, n)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="#%s"/>`, n, n, opts.background)
+	fmt.Fprintf(&svg, `<path d="%s" fill="#%s"/>`, path.String(), opts.foreground)
+	svg.WriteString("</svg>\n")
+	return []byte(svg.String())
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheControl(maxAge time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+func (us *URLShortener) qrHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	query := r.URL.Query()
+	opts := qrOptions{size: defaultQRSize, format: qrFormatPNG, foreground: "000000", background: "ffffff"}
+	if sizeParam := query.Get("size"); sizeParam != "" {
+		parsed, err := strconv.Atoi(sizeParam)
+		if err != nil || parsed < minQRSize || parsed > maxQRSize {
+			http.Error(w, fmt.Sprintf("size must be an integer between %d and %d", minQRSize, maxQRSize), http.StatusBadRequest)
+			return
+		}
+		opts.size = parsed
+	}
+	if format := strings.ToLower(query.Get("format")); format != "" {
+		if format != qrFormatPNG && format != qrFormatSVG {
+			http.Error(w, "format must be png or svg", http.StatusBadRequest)
+			return
+		}
+		opts.format = format
+	}
+	for param, target := range map[string]*string{"fg": &opts.foreground, "bg": &opts.background} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, ok := parseHexColor(value)
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s must be a hex color such as #1a2b3c", param), http.StatusBadRequest)
+			return
+		}
+		*target = parsed
+	}
+	if opts.foreground == opts.background {
+		http.Error(w, "fg and bg must be different colors", http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := us.GetStats(shortCode)
+	if err != nil {
+		http.Error(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+
+	shortURL := fmt.Sprintf("%s/%s", us.publicBaseURL(r), mapping.path())
+	etag := qrETag(shortURL, opts)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl(us.config.QRCacheMaxAge))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	code, err := qrcode.New(shortURL, qrcode.Medium)
+	if err != nil {
+		http.Error(w, "Failed to generate QR code", http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	if opts.format == qrFormatSVG {
+		body = qrSVG(code, opts)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		code.ForegroundColor = hexToRGBA(opts.foreground)
+		code.BackgroundColor = hexToRGBA(opts.background)
+		if body, err = code.PNG(opts.size); err != nil {
+			http.Error(w, "Failed to generate QR code", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQRETagStableAndNotModified(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/qr", CreateOptions{CustomName: "qrcode"})
+
+	first := serve(t, us, http.MethodGet, "/api/qr/qrcode?size=128", "")
+	expectStatus(t, first, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on QR response")
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=86400" {
+		t.Fatalf("Cache-Control = %q", got)
+	}
+
+	second := serve(t, us, http.MethodGet, "/api/qr/qrcode?size=128", "")
+	if got := second.Header().Get("ETag"); got != etag {
+		t.Fatalf("ETag changed between requests: %q then %q", etag, got)
+	}
+	if other := serve(t, us, http.MethodGet, "/api/qr/qrcode?size=256", ""); other.Header().Get("ETag") == etag {
+		t.Fatal("different sizes share an ETag")
+	}
+
+	rec := serve(t, us, http.MethodGet, "/api/qr/qrcode?size=128", "", "If-None-Match", etag)
+	expectStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 {
+		t.Fatalf("304 carried a %d byte body", rec.Body.Len())
+	}
+}
+
+func TestQRSVGFormat(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/print", CreateOptions{CustomName: "print"})
+
+	rec := serve(t, us, http.MethodGet, "/api/qr/print?format=svg&size=300&fg=%23112233&bg=fafafa", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "image/svg+xml" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300"`) || !strings.HasSuffix(body, "</svg>\n") {
+		t.Fatalf("body is not an SVG document: %.120s", body)
+	}
+	if !strings.Contains(body, `fill="#112233"`) || !strings.Contains(body, `fill="#fafafa"`) {
+		t.Fatalf("SVG does not use the requested colors")
+	}
+
+	png := serve(t, us, http.MethodGet, "/api/qr/print?size=300&fg=123", "")
+	expectStatus(t, png, http.StatusOK)
+	if got := png.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("default Content-Type = %q", got)
+	}
+	if png.Header().Get("ETag") == rec.Header().Get("ETag") {
+		t.Fatal("PNG and SVG share an ETag")
+	}
+}
+
+func TestQRRejectsInvalidOptions(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/print", CreateOptions{CustomName: "print"})
+
+	for _, query := range []string{"fg=red", "bg=%2312345", "fg=ggg", "fg=000&bg=000000", "format=gif"} {
+		expectStatus(t, serve(t, us, http.MethodGet, "/api/qr/print?"+query, ""), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const hitRateWindow = 60
+
+// hitRate counts hits over the last minute in a ring of per-second buckets,
+// so memory per link stays fixed however busy the link is.
+type hitRate struct {
+	mutex   sync.Mutex
+	counts  [hitRateWindow]int64
+	seconds [hitRateWindow]int64
+}
+
+func (h *hitRate) record(now time.Time) {
+	second := now.Unix()
+	slot := second % hitRateWindow
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.seconds[slot] != second {
+		h.seconds[slot], h.counts[slot] = second, 0
+	}
+	h.counts[slot]++
+}
+
+// perMinute returns the number of hits in the 60 seconds up to now.
+func (h *hitRate) perMinute(now time.Time) int64 {
+	if h == nil {
+		return 0
+	}
+	oldest := now.Unix() - hitRateWindow
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var total int64
+	for i, second := range h.seconds {
+		if second > oldest {
+			total += h.counts[i]
+		}
+	}
+	return total
+}
+
+// tokenBucket allows rate events per second on average and up to burst at
+// once. It starts full.
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if burst <= 0 {
+		burst = max(int(math.Ceil(rate)), 1)
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// take spends a token if one is available. Otherwise it reports how long
+// until the next one is.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
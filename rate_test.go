@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHitRateWindow(t *testing.T) {
+	var h hitRate
+	start := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 5; i++ {
+		h.record(start)
+	}
+	if got := h.perMinute(start); got != 5 {
+		t.Fatalf("rate = %d, want 5", got)
+	}
+	h.record(start.Add(30 * time.Second))
+
+	for _, tt := range []struct {
+		after time.Duration
+		want  int64
+	}{
+		{30 * time.Second, 6},
+		{59 * time.Second, 6},
+		{60 * time.Second, 1},
+		{90 * time.Second, 0},
+	} {
+		if got := h.perMinute(start.Add(tt.after)); got != tt.want {
+			t.Errorf("rate %s after start = %d, want %d", tt.after, got, tt.want)
+		}
+	}
+
+	// A bucket reused a minute later starts from zero.
+	h.record(start.Add(hitRateWindow * time.Second))
+	if got := h.perMinute(start.Add(hitRateWindow * time.Second)); got != 2 {
+		t.Fatalf("rate after bucket reuse = %d, want 2", got)
+	}
+
+	var idle *hitRate
+	if got := idle.perMinute(start); got != 0 {
+		t.Fatalf("nil rate = %d", got)
+	}
+}
+
+func TestStatsReportRecentRate(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.MetricsLinkRates = true })
+	mustCreate(t, us, "https://example.com/hot", CreateOptions{CustomName: "hotlink"})
+	mustCreate(t, us, "https://example.com/cold", CreateOptions{CustomName: "coldlink"})
+
+	const hits = 7
+	for i := 0; i < hits; i++ {
+		expectStatus(t, serve(t, us, http.MethodGet, "/hotlink", ""), http.StatusMovedPermanently)
+	}
+
+	rec := serve(t, us, http.MethodGet, "/api/stats/hotlink", "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats struct {
+		RecentPerMin int64 `json:"recent_rate_per_min"`
+	}
+	decodeBody(t, rec, &stats)
+	if stats.RecentPerMin != hits {
+		t.Fatalf("recent_rate_per_min = %d, want %d", stats.RecentPerMin, hits)
+	}
+
+	body := serve(t, us, http.MethodGet, "/metrics", "").Body.String()
+	if !strings.Contains(body, `quicklink_link_hits_per_minute{code="hotlink"} 7`+"\n") {
+		t.Fatalf("metrics lack the hotlink gauge:\n%s", body)
+	}
+	if strings.Contains(body, `code="coldlink"`) {
+		t.Fatal("metrics include an idle link")
+	}
+}
+
+// TestStatsWhileRedirecting is meant for go test -race: stats are read
+// while redirects update the same link.
+func TestStatsWhileRedirecting(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/busy", CreateOptions{CustomName: "busy"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			us.GetOriginalURL("busy")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			serve(t, us, http.MethodGet, "/api/stats/busy", "")
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLinkRateMetricsOptIn(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/hot", CreateOptions{CustomName: "hotlink"})
+	serve(t, us, http.MethodGet, "/hotlink", "")
+
+	if body := serve(t, us, http.MethodGet, "/metrics", "").Body.String(); strings.Contains(body, "quicklink_link_hits_per_minute") {
+		t.Fatal("per-link gauge exported without MetricsLinkRates")
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	bucket := newTokenBucket(2, 2, start)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.take(start); !ok {
+			t.Fatalf("take %d within the burst was refused", i+1)
+		}
+	}
+	ok, wait := bucket.take(start)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("take beyond the burst = %v, %s, want refused with 500ms wait", ok, wait)
+	}
+	if ok, _ := bucket.take(start.Add(500 * time.Millisecond)); !ok {
+		t.Fatal("no token after half a second at 2/s")
+	}
+	if ok, _ := bucket.take(start.Add(time.Hour)); !ok {
+		t.Fatal("bucket did not refill")
+	}
+	if bucket.tokens > bucket.burst {
+		t.Fatalf("bucket holds %g tokens, more than its burst of %g", bucket.tokens, bucket.burst)
+	}
+}
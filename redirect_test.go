@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+// accessCount reads code's count through the stats endpoint, so buffered
+// increments are included.
+func accessCount(t *testing.T, us *URLShortener, code string) int64 {
+	t.Helper()
+	rec := serve(t, us, http.MethodGet, "/api/stats/"+code, "")
+	expectStatus(t, rec, http.StatusOK)
+	var stats api.URLStats
+	decodeBody(t, rec, &stats)
+	return stats.AccessCount
+}
+
+func TestHeadRedirectsWithoutCounting(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/head", CreateOptions{CustomName: "probe"})
+
+	for i := 0; i < 3; i++ {
+		rec := serve(t, us, http.MethodHead, "/probe", "")
+		expectStatus(t, rec, http.StatusMovedPermanently)
+		if got := rec.Header().Get("Location"); got != "https://example.com/head" {
+			t.Fatalf("HEAD Location = %q", got)
+		}
+	}
+	if n := accessCount(t, us, "probe"); n != 0 {
+		t.Fatalf("count after HEAD = %d, want 0", n)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/probe", ""), http.StatusMovedPermanently)
+	if n := accessCount(t, us, "probe"); n != 1 {
+		t.Fatalf("count after GET = %d, want 1", n)
+	}
+	expectStatus(t, serve(t, us, http.MethodHead, "/nope404", ""), http.StatusNotFound)
+}
+
+func TestBlankShortCodeIsJSONNotFound(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/team", CreateOptions{CustomName: "home", Namespace: "team"})
+
+	for _, target := range []string{"/%20/", "/%20%20", "/team/", "/team/%20%20"} {
+		rec := serve(t, us, http.MethodGet, target, "")
+		expectStatus(t, rec, http.StatusNotFound)
+		var body api.ErrorResponse
+		decodeBody(t, rec, &body)
+		if body.ErrorCode != CodeEmptyCode {
+			t.Fatalf("GET %s error_code = %q, want %q", target, body.ErrorCode, CodeEmptyCode)
+		}
+	}
+}
+
+func TestBlankShortCodeLeavesOtherRoutesAlone(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/", ""), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodHead, "/%20", ""), http.StatusNotFound)
+
+	rec := serve(t, us, http.MethodGet, "/api/", "")
+	expectStatus(t, rec, http.StatusNotFound)
+	if got := rec.Header().Get("Content-Type"); got == "application/json" {
+		t.Fatalf("GET /api/ Content-Type = %q, want the router's plain 404", got)
+	}
+	expectStatus(t, serve(t, us, http.MethodPost, "/team/", ""), http.StatusNotFound)
+}
+
+func TestUnknownCodeRedirectsToNotFoundURL(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.NotFoundRedirectURL = "https://example.com/home" })
+	mustCreate(t, us, "https://example.com/gone", CreateOptions{CustomName: "gone"})
+	if err := us.DeleteURL("gone", Principal{Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, us, http.MethodGet, "/mistyped", "")
+	expectStatus(t, rec, http.StatusFound)
+	if got := rec.Header().Get("Location"); got != "https://example.com/home" {
+		t.Fatalf("Location = %q, want the fallback URL", got)
+	}
+
+	// Deleted links still report that they are gone rather than falling back.
+	expectStatus(t, serve(t, us, http.MethodGet, "/gone", ""), http.StatusGone)
+}
+
+func TestUnknownCodeIsNotFoundByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodGet, "/mistyped", "")
+	expectStatus(t, rec, http.StatusNotFound)
+	if rec.Header().Get("Location") != "" {
+		t.Fatal("unknown code sent a Location header")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// reloadFile is the format read by POST /api/admin/reload: a JSON object
+// whose "links" list the permanent redirects. A snapshot file has the same
+// shape, so one can be used as a reload file.
+type reloadFile struct {
+	Links []reloadLink `json:"links"`
+}
+
+type reloadLink struct {
+	ShortCode   string `json:"short_code"`
+	Namespace   string `json:"namespace,omitempty"`
+	OriginalURL string `json:"original_url"`
+	Description string `json:"description,omitempty"`
+}
+
+// indexMappings builds the link map and the shared-alias index for mappings.
+func indexMappings(mappings []*URLMapping) (map[string]*URLMapping, map[string]string) {
+	storage := make(map[string]*URLMapping, len(mappings))
+	aliases := make(map[string]string)
+	for _, mapping := range mappings {
+		if mapping.ID == "" {
+			mapping.ID = storageKey(mapping.Namespace, mapping.ShortCode)
+		}
+		storage[mapping.ID] = mapping
+		for _, alias := range mapping.Aliases {
+			aliases[storageKey(mapping.Namespace, alias)] = mapping.ID
+		}
+	}
+	return storage, aliases
+}
+
+// ReplaceAll swaps the whole set of links for mappings in one step, so
+// redirects never see a half-loaded store. The new map and alias index are
+// built before the lock is taken. Links whose key is still present keep their
+// creation time and the visit stats gathered while running; links that are
+// gone are dropped without tombstones.
+func (us *URLShortener) ReplaceAll(mappings []*URLMapping) {
+	storage, aliases := indexMappings(mappings)
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	us.flushCountsLocked()
+	for key, mapping := range storage {
+		if old, exists := us.storage[key]; exists {
+			mapping.CreatedAt = old.CreatedAt
+			mapping.AccessCount = old.AccessCount
+			mapping.UniqueClicks = old.UniqueClicks
+			mapping.ClicksByCountry = old.ClicksByCountry
+			mapping.LastAccessedAt = old.LastAccessedAt
+			mapping.recentVisitors = old.recentVisitors
+			mapping.hits = old.hits
+			mapping.clicks = old.clicks
+		}
+	}
+	us.storage = storage
+	us.aliases = aliases
+}
+
+// readReloadFile parses and validates the links in path.
+func (us *URLShortener) readReloadFile(path string) ([]*URLMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file reloadFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode reload file %s: %w", path, err)
+	}
+
+	now := time.Now()
+	mappings := make([]*URLMapping, 0, len(file.Links))
+	seen := make(map[string]bool, len(file.Links))
+	for i, link := range file.Links {
+		if !isValidCustomName(link.ShortCode) {
+			return nil, errorf(ErrInvalidCustomName, "link %d: invalid short code '%s'", i, link.ShortCode)
+		}
+		if us.isReservedCode(link.ShortCode) {
+			return nil, errorf(ErrReservedCode, "link %d: short code '%s' is reserved", i, link.ShortCode)
+		}
+		if link.Namespace != "" && (!isValidNamespace(link.Namespace) || us.reservedPaths[link.Namespace]) {
+			return nil, errorf(ErrInvalidNamespace, "link %d: invalid namespace '%s'", i, link.Namespace)
+		}
+		if hasUnsafeScheme(link.OriginalURL) || !validateURL(link.OriginalURL, us.config.DefaultScheme, us.config.AllowedSchemes) {
+			return nil, fmt.Errorf("%w: link %d: %s", ErrInvalidURL, i, us.logURL(link.OriginalURL))
+		}
+		description, err := sanitizeDescription(link.Description)
+		if err != nil {
+			return nil, fmt.Errorf("link %d: %w", i, err)
+		}
+
+		shortCode := us.canonicalCode(link.ShortCode)
+		key := storageKey(link.Namespace, shortCode)
+		if seen[key] {
+			return nil, errorf(ErrCodeTaken, "link %d: short code '%s' is listed twice", i, key)
+		}
+		seen[key] = true
+
+		mappings = append(mappings, &URLMapping{
+			ID:          key,
+			ShortCode:   shortCode,
+			Namespace:   link.Namespace,
+			OriginalURL: us.normalizeDestination(link.OriginalURL),
+			CreatedAt:   now,
+			Enabled:     true,
+			Description: description,
+		})
+	}
+	return mappings, nil
+}
+
+func (us *URLShortener) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if us.config.ReloadFile == "" {
+		http.Error(w, "RELOAD_FILE is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	var mappings []*URLMapping
+	err := withRetry(r.Context(), us.storageRetryPolicy(), func() error {
+		var err error
+		mappings, err = us.readReloadFile(us.config.ReloadFile)
+		return err
+	})
+	if err != nil {
+		log.Printf("Admin reload from %s failed: %v", us.config.ReloadFile, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	us.ReplaceAll(mappings)
+	log.Printf("Admin reload replaced the store with %d link(s) from %s", len(mappings), us.config.ReloadFile)
+
+	writeJSON(w, http.StatusOK, map[string]int{"links": len(mappings)}, us.jsonOptions(r))
+}
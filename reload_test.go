@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeReloadFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplaceAllKeepsCountsAndDropsStale(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/docs", CreateOptions{CustomName: "docs"})
+	mustCreate(t, us, "https://example.com/old", CreateOptions{CustomName: "old"})
+	for i := 0; i < 3; i++ {
+		expectStatus(t, serve(t, us, http.MethodGet, "/docs", ""), http.StatusMovedPermanently)
+	}
+
+	us.ReplaceAll([]*URLMapping{
+		{ShortCode: "docs", OriginalURL: "https://example.com/docs/v2", Enabled: true},
+		{ShortCode: "new", OriginalURL: "https://example.com/new", Enabled: true},
+	})
+
+	rec := serve(t, us, http.MethodGet, "/docs", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := rec.Header().Get("Location"); got != "https://example.com/docs/v2" {
+		t.Fatalf("docs redirected to %q, want the reloaded destination", got)
+	}
+	if n := accessCount(t, us, "docs"); n != 4 {
+		t.Fatalf("docs count = %d, want 4", n)
+	}
+	if n := accessCount(t, us, "new"); n != 0 {
+		t.Fatalf("new count = %d, want 0", n)
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/old", ""), http.StatusNotFound)
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	us := newTestShortener(t, func(c *Config) {
+		c.AdminAPIKeys = map[string]string{"admin-key": "root"}
+		c.ReloadFile = path
+	})
+	mustCreate(t, us, "https://example.com/stale", CreateOptions{CustomName: "stale"})
+
+	writeReloadFile(t, path, `{"links":[
+		{"short_code":"handbook","original_url":"https://example.com/handbook"},
+		{"short_code":"wiki","namespace":"team","original_url":"https://wiki.example.com"}
+	]}`)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/admin/reload", ""), http.StatusUnauthorized)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/admin/reload", "", "X-API-Key", "admin-key"), http.StatusOK)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/handbook", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/team/wiki", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/stale", ""), http.StatusNotFound)
+
+	// A bad file is rejected as a whole and leaves the store as it was.
+	writeReloadFile(t, path, `{"links":[
+		{"short_code":"fine","original_url":"https://example.com/fine"},
+		{"short_code":"evil","original_url":"javascript:alert(1)"}
+	]}`)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/admin/reload", "", "X-API-Key", "admin-key"), http.StatusUnprocessableEntity)
+	expectStatus(t, serve(t, us, http.MethodGet, "/handbook", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/fine", ""), http.StatusNotFound)
+}
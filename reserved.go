@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// builtinReservedCodes are never handed out because they collide with routes
+// or are easily confused for them.
+var builtinReservedCodes = []string{
+	"admin", "api", "static", "assets", "health", "login", "logout",
+	"favicon", "metrics", "robots", "sitemap", "www", "help", "about",
+}
+
+// defaultReservedPaths are path segments kept free for routes and static
+// files so the catch-all redirect routes never shadow them.
+var defaultReservedPaths = []string{"static", "assets", "robots", "sitemap", "favicon", "ws"}
+
+// builtinBlockedWords are rejected anywhere inside a code.
+var builtinBlockedWords = []string{
+	"fuck", "shit", "cunt", "bitch", "slut", "whore", "nazi", "porn",
+}
+
+func loadReservedCodesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var codes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		codes = append(codes, line)
+	}
+	return codes, scanner.Err()
+}
+
+func buildReservedSet(codes []string) map[string]bool {
+	reserved := make(map[string]bool, len(builtinReservedCodes)+len(codes))
+	for _, code := range builtinReservedCodes {
+		reserved[code] = true
+	}
+	for _, code := range codes {
+		reserved[strings.ToLower(code)] = true
+	}
+	return reserved
+}
+
+func (us *URLShortener) isReservedCode(code string) bool {
+	lower := strings.ToLower(code)
+	if us.reserved[lower] {
+		return true
+	}
+	for _, word := range builtinBlockedWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildReservedPathSet(paths []string) map[string]bool {
+	reserved := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if path = strings.ToLower(strings.Trim(strings.TrimSpace(path), "/")); path != "" {
+			reserved[path] = true
+		}
+	}
+	return reserved
+}
+
+// serveReservedPath handles requests whose first path segment is reserved
+// before they reach the redirect logic. A file of that name in the static
+// root is served, behind UIBasicAuth like the rest of the static files;
+// anything else is a 404. It reports whether it handled r.
+func (us *URLShortener) serveReservedPath(w http.ResponseWriter, r *http.Request, vars map[string]string) bool {
+	segment := vars["namespace"]
+	if segment == "" {
+		segment = vars["shortCode"]
+	}
+	if !us.reservedPaths[strings.ToLower(segment)] {
+		return false
+	}
+
+	if vars["namespace"] == "" {
+		filePath := filepath.Join(staticRoot, filepath.Base(vars["shortCode"]))
+		if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
+			us.requireUIAuth(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeFile(w, r, filePath)
+			})(w, r)
+			return true
+		}
+	}
+	http.NotFound(w, r)
+	return true
+}
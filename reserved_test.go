@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReservedCustomCodesRejected(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(file, []byte("# marketing\nSummer\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	us := newTestShortener(t, func(c *Config) {
+		c.ReservedCodes = []string{"promo"}
+		c.ReservedCodesFile = file
+	})
+
+	for _, name := range []string{"admin", "promo", "PROMO", "summer", "myshitlink"} {
+		_, _, err := us.CreateShortURL("https://example.com/r", CreateOptions{CustomName: name})
+		if !errors.Is(err, ErrReservedCode) {
+			t.Errorf("custom name %q: err = %v, want ErrReservedCode", name, err)
+		}
+	}
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/r","custom_name":"promo"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestReservedPathsSkipRedirectLookup(t *testing.T) {
+	us := newTestShortener(t, nil)
+	// Plant links under reserved names directly, as an old data file might.
+	for _, code := range []string{"assets", "robots", storageKey("static", "promo1")} {
+		us.storage[code] = &URLMapping{ShortCode: code, OriginalURL: "https://example.com/" + code, Enabled: true, CreatedAt: time.Now()}
+	}
+
+	for _, path := range []string{"/assets", "/ROBOTS", "/robots/stats", "/static/promo1"} {
+		rec := serve(t, us, http.MethodGet, path, "")
+		expectStatus(t, rec, http.StatusNotFound)
+		if rec.Header().Get("Location") != "" {
+			t.Fatalf("%s redirected to %q", path, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestReservedPathServesStaticFile(t *testing.T) {
+	name := filepath.Base(writeStaticFile(t, "rsv*", []byte("User-agent: *\n")))
+	us := newTestShortener(t, func(c *Config) { c.ReservedPaths = []string{name} })
+
+	rec := serve(t, us, http.MethodGet, "/"+name, "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "User-agent: *\n" {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+
+	_, _, err := us.CreateShortURL("https://example.com/r", CreateOptions{CustomName: name})
+	if !errors.Is(err, ErrReservedCode) {
+		t.Fatalf("custom name %q: err = %v, want ErrReservedCode", name, err)
+	}
+}
+
+func TestReservedPathsAreConfigurable(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.ReservedPaths = []string{"/promos/"} })
+	us.storage["assets"] = &URLMapping{ShortCode: "assets", OriginalURL: "https://example.com/assets", Enabled: true, CreatedAt: time.Now()}
+	us.storage["promos"] = &URLMapping{ShortCode: "promos", OriginalURL: "https://example.com/promos", Enabled: true, CreatedAt: time.Now()}
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/promos", ""), http.StatusNotFound)
+	expectStatus(t, serve(t, us, http.MethodGet, "/assets", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/n","namespace":"promos"}`), http.StatusBadRequest)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var jsonpCallbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]{0,63}(\.[a-zA-Z_$][a-zA-Z0-9_$]{0,63}){0,3}$`)
+
+type resolveResponse struct {
+	ShortCode   string `json:"short_code"`
+	OriginalURL string `json:"original_url"`
+}
+
+// resolveHandler reports where a short code points without redirecting or
+// counting an access. A ?callback= parameter wraps the body as JSONP.
+func (us *URLShortener) resolveHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	callback := r.URL.Query().Get("callback")
+	if callback != "" && !jsonpCallbackPattern.MatchString(callback) {
+		http.Error(w, "Invalid callback name", http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := us.GetStats(shortCode)
+	if errors.Is(err, ErrGone) || errors.Is(err, ErrExpired) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil || !mapping.Enabled || mapping.status(time.Now()) != StatusActive {
+		http.Error(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+	if !us.revealsDestination(r, mapping) {
+		http.Error(w, "This link needs a confirmation token", http.StatusForbidden)
+		return
+	}
+
+	response := resolveResponse{
+		ShortCode:   mapping.ShortCode,
+		OriginalURL: mapping.OriginalURL,
+	}
+	opts := us.jsonOptions(r)
+	if callback == "" {
+		writeJSON(w, http.StatusOK, response, opts)
+		return
+	}
+
+	body, err := encodeJSON(response, jsonOptions{camel: opts.camel})
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write([]byte("/**/" + callback + "("))
+	w.Write(body)
+	w.Write([]byte(");"))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResolveJSONPCallback(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/jsonp", CreateOptions{CustomName: "jsonp"})
+
+	rec := serve(t, us, http.MethodGet, "/api/resolve/jsonp?callback=app.onResolve", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "application/javascript" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "/**/app.onResolve({") || !strings.HasSuffix(body, "});") ||
+		!strings.Contains(body, `"original_url":"https://example.com/jsonp"`) {
+		t.Fatalf("body = %s", body)
+	}
+}
+
+func TestResolveRejectsUnsafeCallback(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/jsonp", CreateOptions{CustomName: "jsonp"})
+
+	for _, callback := range []string{"alert(1);x", "<script>", "a b", "fn//", "1abc"} {
+		rec := serve(t, us, http.MethodGet, "/api/resolve/jsonp?callback="+url.QueryEscape(callback), "")
+		expectStatus(t, rec, http.StatusBadRequest)
+		if strings.Contains(rec.Body.String(), callback+"(") {
+			t.Fatalf("callback %q was echoed: %s", callback, rec.Body.String())
+		}
+	}
+}
+
+func TestResolveWithoutCallbackIsJSON(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/jsonp", CreateOptions{CustomName: "jsonp"})
+
+	rec := serve(t, us, http.MethodGet, "/api/resolve/jsonp", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	var body resolveResponse
+	decodeBody(t, rec, &body)
+	if body.OriginalURL != "https://example.com/jsonp" {
+		t.Fatalf("original_url = %q", body.OriginalURL)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"url-shortener/api"
+)
+
+// Field naming styles for JSON responses.
+const (
+	JSONNamingSnake = "snake"
+	JSONNamingCamel = "camel"
+)
+
+// jsonOptions controls how a response body is rendered.
+type jsonOptions struct {
+	pretty bool
+	camel  bool
+}
+
+func encodeJSON(v interface{}, opts jsonOptions) ([]byte, error) {
+	if opts.camel {
+		v = camelCaseValue(v)
+	}
+	if opts.pretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return json.Marshal(v)
+}
+
+// decodeJSON decodes the request body into v. With StrictJSON on, fields
+// that v does not declare are rejected, so a typo such as "ur" for "url" is
+// reported instead of being silently dropped.
+func (us *URLShortener) decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	if us.config.StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return errorf(ErrInvalidJSON, "Unknown field %s in request body", field)
+		}
+		return errorf(ErrInvalidJSON, "Invalid JSON format")
+	}
+	return nil
+}
+
+// writeJSON writes v as the JSON response body with the given status. Pretty
+// output is indented with two spaces; the default stays compact. A
+// Content-Type already set by the caller is kept.
+func writeJSON(w http.ResponseWriter, status int, v interface{}, opts jsonOptions) {
+	body, err := encodeJSON(v, opts)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// writeJSONError writes err as an api.ErrorResponse with the given status.
+// error_code comes from the sentinel err wraps; client errors without one
+// are labelled by their status instead, e.g. BAD_REQUEST.
+func (us *URLShortener) writeJSONError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	code := errorCode(err)
+	if code == CodeInternal && status < http.StatusInternalServerError {
+		code = strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	}
+	writeJSON(w, status, api.ErrorResponse{ErrorCode: code, Error: err.Error()}, us.jsonOptions(r))
+}
+
+// jsonOptions reports how the response to r should be rendered. The ?pretty=
+// and ?naming= query parameters override the PrettyJSON and JSONNaming
+// config defaults.
+func (us *URLShortener) jsonOptions(r *http.Request) jsonOptions {
+	opts := jsonOptions{
+		pretty: us.config.PrettyJSON,
+		camel:  us.config.JSONNaming == JSONNamingCamel,
+	}
+	query := r.URL.Query()
+	if value := query.Get("pretty"); value != "" {
+		if pretty, err := strconv.ParseBool(value); err == nil {
+			opts.pretty = pretty
+		}
+	}
+	switch strings.ToLower(query.Get("naming")) {
+	case JSONNamingCamel:
+		opts.camel = true
+	case JSONNamingSnake:
+		opts.camel = false
+	}
+	return opts
+}
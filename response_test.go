@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrettyStatsMatchCompact(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/pretty", CreateOptions{CustomName: "pretty"})
+
+	compact := serve(t, us, http.MethodGet, "/api/stats/pretty", "")
+	expectStatus(t, compact, http.StatusOK)
+	if strings.Contains(compact.Body.String(), "\n  ") {
+		t.Fatalf("default output is indented: %s", compact.Body.String())
+	}
+
+	pretty := serve(t, us, http.MethodGet, "/api/stats/pretty?pretty=1", "")
+	expectStatus(t, pretty, http.StatusOK)
+	if !strings.HasPrefix(pretty.Body.String(), "{\n  \"") {
+		t.Fatalf("?pretty=1 output is not indented by two spaces: %s", pretty.Body.String())
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(compact.Body.Bytes()), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(pretty.Body.String()); got != indented.String() {
+		t.Fatalf("pretty output differs from indented compact output:\n%s\nvs\n%s", got, indented.String())
+	}
+}
+
+func TestPrettyJSONConfigDefault(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.PrettyJSON = true })
+	mustCreate(t, us, "https://example.com/pretty", CreateOptions{CustomName: "pretty"})
+
+	if body := serve(t, us, http.MethodGet, "/api/stats/pretty", "").Body.String(); !strings.Contains(body, "\n  ") {
+		t.Fatalf("PrettyJSON output is compact: %s", body)
+	}
+	if body := serve(t, us, http.MethodGet, "/api/stats/pretty?pretty=0", "").Body.String(); strings.Contains(body, "\n  ") {
+		t.Fatalf("?pretty=0 did not override PrettyJSON: %s", body)
+	}
+}
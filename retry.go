@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures exponential backoff for storage operations.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// isRetryable reports whether err looks transient: timeouts, refused or reset
+// connections and busy resources. Missing records and bad input are not.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.EPIPE, syscall.EAGAIN, syscall.EBUSY, syscall.ETIMEDOUT} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// withRetry runs op until it succeeds, fails with a non-retryable error, runs
+// out of attempts, or ctx is done. Delays double from BaseDelay up to MaxDelay
+// and never sleep past the context deadline.
+func withRetry(ctx context.Context, policy RetryPolicy, op func() error) error {
+	attempts := max(policy.MaxAttempts, 1)
+	delay := policy.BaseDelay
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = op(); err == nil || !isRetryable(err) || attempt == attempts {
+			return err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, policy.MaxDelay)
+	}
+	return err
+}
+
+func (us *URLShortener) storageRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: us.config.StorageRetryAttempts,
+		BaseDelay:   us.config.StorageRetryBaseDelay,
+		MaxDelay:    us.config.StorageRetryMaxDelay,
+	}
+}
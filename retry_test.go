@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyStore fails its first failures calls with err, then succeeds.
+type flakyStore struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyStore) save() error {
+	s.calls++
+	if s.calls <= s.failures {
+		return s.err
+	}
+	return nil
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+
+func TestRetryRecoversFromTransientErrors(t *testing.T) {
+	store := &flakyStore{failures: 2, err: fmt.Errorf("write: %w", syscall.ECONNRESET)}
+	if err := withRetry(context.Background(), testRetryPolicy, store.save); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 3 {
+		t.Fatalf("%d calls, want 3", store.calls)
+	}
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	store := &flakyStore{failures: 10, err: ErrNotFound}
+	if err := withRetry(context.Background(), testRetryPolicy, store.save); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if store.calls != 1 {
+		t.Fatalf("%d calls for a permanent error, want 1", store.calls)
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	store := &flakyStore{failures: 10, err: syscall.ECONNREFUSED}
+	if err := withRetry(context.Background(), testRetryPolicy, store.save); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("err = %v", err)
+	}
+	if store.calls != testRetryPolicy.MaxAttempts {
+		t.Fatalf("%d calls, want %d", store.calls, testRetryPolicy.MaxAttempts)
+	}
+}
+
+func TestRetryRespectsContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	store := &flakyStore{failures: 10, err: syscall.ETIMEDOUT}
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}
+
+	start := time.Now()
+	withRetry(ctx, policy, store.save)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("retry slept %s past a 20ms deadline", elapsed)
+	}
+	if store.calls != 1 {
+		t.Fatalf("%d calls, want 1 when the backoff would outlast the deadline", store.calls)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+)
+
+// defaultRobotsPolicy keeps crawlers off short links and the API, which
+// would otherwise inflate click counts, while leaving the home page indexable.
+const defaultRobotsPolicy = `User-agent: *
+Allow: /$
+Disallow: /api/
+Disallow: /
+`
+
+// robotsHandler serves /robots.txt from RobotsFile, or the built-in policy
+// when no file is configured or it cannot be read.
+func (us *URLShortener) robotsHandler(w http.ResponseWriter, r *http.Request) {
+	policy := []byte(defaultRobotsPolicy)
+	if us.config.RobotsFile != "" {
+		content, err := os.ReadFile(us.config.RobotsFile)
+		if err != nil {
+			log.Printf("Warning: could not read robots file %s, serving default policy: %v", us.config.RobotsFile, err)
+		} else {
+			policy = content
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl(staticCacheMaxAge))
+	w.Write(policy)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRobotsDefaultPolicy(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodGet, "/robots.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Fatal("robots.txt was handled as a short link")
+	}
+	if rec.Body.String() != defaultRobotsPolicy {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+	for _, line := range []string{"Allow: /$", "Disallow: /api/", "Disallow: /"} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("default policy lacks %q", line)
+		}
+	}
+}
+
+func TestRobotsFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "robots.txt")
+	if err := os.WriteFile(file, []byte("User-agent: *\nDisallow:\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	us := newTestShortener(t, func(c *Config) { c.RobotsFile = file })
+
+	rec := serve(t, us, http.MethodGet, "/robots.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "User-agent: *\nDisallow:\n" {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+}
+
+func TestRobotsMissingFileFallsBack(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.RobotsFile = filepath.Join(t.TempDir(), "absent.txt") })
+	rec := serve(t, us, http.MethodGet, "/robots.txt", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != defaultRobotsPolicy {
+		t.Fatalf("body = %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRootServesUIByDefault(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodGet, "/", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("Content-Type = %q, want the UI page", got)
+	}
+}
+
+func TestRootJSONWelcome(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.RootBehavior = RootBehaviorJSON })
+	rec := serve(t, us, http.MethodGet, "/", "")
+	expectStatus(t, rec, http.StatusOK)
+	var body map[string]string
+	decodeBody(t, rec, &body)
+	if body["health"] != "/api/health" || body["message"] == "" {
+		t.Fatalf("welcome = %v", body)
+	}
+}
+
+func TestRootRedirect(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.RootBehavior = RootBehaviorRedirect
+		c.RootRedirectURL = "https://example.com/landing"
+	})
+	rec := serve(t, us, http.MethodGet, "/", "")
+	expectStatus(t, rec, http.StatusFound)
+	if got := rec.Header().Get("Location"); got != "https://example.com/landing" {
+		t.Fatalf("Location = %q", got)
+	}
+}
+
+func TestRootRedirectWithoutURLFallsBackToUI(t *testing.T) {
+	config := DefaultConfig()
+	config.RootBehavior = RootBehaviorRedirect
+	config.Validate()
+	if config.RootBehavior != RootBehaviorUI {
+		t.Fatalf("RootBehavior = %q, want %q", config.RootBehavior, RootBehaviorUI)
+	}
+}
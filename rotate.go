@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"url-shortener/api"
+)
+
+// RotateShortCode moves a live mapping to a freshly generated code, keeping
+// its destination and stats. With leaveTombstone the old code answers 410
+// afterwards; otherwise it simply becomes free again. A shared alias rotates
+// the link it points at.
+func (us *URLShortener) RotateShortCode(shortCode string, principal Principal, leaveTombstone bool) (*URLMapping, error) {
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	oldKey := us.followAliasLocked(us.canonicalCode(shortCode))
+	mapping, exists := us.storage[oldKey]
+	if !exists || mapping.DeletedAt != nil || (!principal.Admin && mapping.Owner != principal.Owner) {
+		return nil, ErrNotFound
+	}
+
+	// With the default generator, keep at least the old code's length so
+	// rotating a long, high-entropy code never weakens it.
+	length := 0
+	if _, random := us.codeGenerator.(RandomCodeGenerator); random && len(mapping.ShortCode) > us.config.CodeLength {
+		length = min(len(mapping.ShortCode), us.config.MaxCodeLength)
+	}
+
+	var newCode string
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		candidate := us.generateShortCode(length)
+		if !us.codeTakenLocked(storageKey(mapping.Namespace, candidate)) && !us.isReservedCode(candidate) {
+			newCode = candidate
+			break
+		}
+	}
+	if newCode == "" {
+		return nil, errorf(ErrCodesExhausted, "no free short code found after %d attempts", maxCodeAttempts)
+	}
+
+	// Only the old code is retired; shared aliases move with the mapping.
+	delete(us.storage, oldKey)
+	if leaveTombstone {
+		us.tombstones[oldKey] = tombstone{status: StatusDeleted, removedAt: time.Now()}
+	}
+
+	newKey := storageKey(mapping.Namespace, newCode)
+	mapping.ShortCode = newCode
+	mapping.ID = newKey
+	us.storage[newKey] = mapping
+	delete(us.tombstones, newKey)
+	us.repointAliasesLocked(mapping, oldKey)
+
+	log.Printf("Rotated short code '%s' to '%s'", oldKey, newKey)
+	return mapping, nil
+}
+
+func (us *URLShortener) rotateURLHandler(w http.ResponseWriter, r *http.Request) {
+	leaveTombstone := true
+	if value := r.URL.Query().Get("tombstone"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			us.writeJSONError(w, r, http.StatusBadRequest, errors.New("tombstone must be true or false"))
+			return
+		}
+		leaveTombstone = parsed
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	mapping, err := us.RotateShortCode(mux.Vars(r)["shortCode"], principal, leaveTombstone)
+	if errors.Is(err, ErrCodesExhausted) {
+		us.writeJSONError(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		us.writeJSONError(w, r, http.StatusNotFound, errorf(ErrNotFound, "Short URL not found"))
+		return
+	}
+
+	response := api.CreateURLResponse{
+		ShortCode:   mapping.ShortCode,
+		Namespace:   mapping.Namespace,
+		OriginalURL: mapping.OriginalURL,
+		ShortURL:    fmt.Sprintf("%s/%s", us.publicBaseURL(r), mapping.path()),
+	}
+	w.Header().Set("Location", "/api/urls/"+mapping.ID)
+	writeJSON(w, http.StatusOK, response, us.jsonOptions(r))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func rotate(t *testing.T, us *URLShortener, code string) api.CreateURLResponse {
+	t.Helper()
+	rec := serve(t, us, http.MethodPost, "/api/urls/"+code+"/rotate", "")
+	expectStatus(t, rec, http.StatusOK)
+	var rotated api.CreateURLResponse
+	decodeBody(t, rec, &rotated)
+	return rotated
+}
+
+func TestRotateThroughSharedAlias(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/shared", CreateOptions{CustomName: "primary"})
+	if err := us.AddAlias("primary", "nickname"); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := rotate(t, us, "nickname")
+	if rotated.ShortCode == "primary" || rotated.ShortCode == "nickname" {
+		t.Fatalf("rotated to %s, want a fresh code", rotated.ShortCode)
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/primary", ""), http.StatusGone)
+	expectStatus(t, serve(t, us, http.MethodGet, "/nickname", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/"+rotated.ShortCode, ""), http.StatusMovedPermanently)
+}
+
+func TestRotateUpdatesSeparateAliases(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.AliasStats = AliasStatsSeparate })
+	mustCreate(t, us, "https://example.com/separate", CreateOptions{CustomName: "original"})
+	if err := us.AddAlias("original", "copycat"); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := rotate(t, us, "original")
+
+	us.mutex.RLock()
+	aliasOf := us.storage["copycat"].AliasOf
+	us.mutex.RUnlock()
+	if aliasOf != rotated.ShortCode {
+		t.Fatalf("copycat AliasOf = %q, want %q", aliasOf, rotated.ShortCode)
+	}
+}
+func TestRotateKeepsDestinationAndStats(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/leaked", CreateOptions{CustomName: "leaked"})
+	for i := 0; i < 3; i++ {
+		expectStatus(t, serve(t, us, http.MethodGet, "/leaked", ""), http.StatusMovedPermanently)
+	}
+
+	rotated := rotate(t, us, "leaked")
+	if rotated.ShortURL != testBaseURL+"/"+rotated.ShortCode || rotated.OriginalURL != "https://example.com/leaked" {
+		t.Fatalf("rotate returned %+v", rotated)
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/leaked", ""), http.StatusGone)
+	if n := accessCount(t, us, rotated.ShortCode); n != 3 {
+		t.Fatalf("access count after rotate = %d, want 3", n)
+	}
+
+	expectStatus(t, serve(t, us, http.MethodPost, "/api/urls/nope12/rotate", ""), http.StatusNotFound)
+}
+
+func TestRotateGivesUpWhenCodesRunOut(t *testing.T) {
+	gen := &sequenceGenerator{codes: []string{"taken"}}
+	us := newTestShortener(t, func(c *Config) { c.CodeGenerator = gen })
+	mustCreate(t, us, "https://example.com/first", CreateOptions{CustomName: "taken"})
+	mustCreate(t, us, "https://example.com/second", CreateOptions{CustomName: "second"})
+
+	rec := serve(t, us, http.MethodPost, "/api/urls/second/rotate", "")
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	var body api.ErrorResponse
+	decodeBody(t, rec, &body)
+	if body.ErrorCode != CodeCodesExhausted || gen.calls != maxCodeAttempts {
+		t.Fatalf("error code = %s after %d calls, want %s after %d", body.ErrorCode, gen.calls, CodeCodesExhausted, maxCodeAttempts)
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, "/second", ""), http.StatusMovedPermanently)
+}
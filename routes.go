@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"url-shortener/api"
+
+	"github.com/gorilla/mux"
+)
+
+// routes builds the server's full handler: every route plus the middleware
+// stack and CORS.
+func (us *URLShortener) routes() http.Handler {
+	r := mux.NewRouter()
+
+	r.PathPrefix("/static/").HandlerFunc(us.requireUIAuth(staticFileHandler))
+
+	r.HandleFunc("/", us.requireUIAuth(us.rootHandler)).Methods("GET")
+	r.HandleFunc("/favicon.ico", faviconHandler).Methods("GET")
+	r.HandleFunc("/robots.txt", us.robotsHandler).Methods("GET")
+	r.HandleFunc("/api/shorten", us.createShortURLHandler).Methods("POST")
+	r.HandleFunc("/api/shorten/batch", us.batchCreateHandler).Methods("POST")
+	r.HandleFunc("/api/qr/{shortCode}", us.qrHandler).Methods("GET")
+	r.HandleFunc("/api/available/{code}", us.availabilityHandler).Methods("GET")
+	r.HandleFunc("/api/resolve/{shortCode}", us.resolveHandler).Methods("GET")
+	r.HandleFunc("/api/stats/batch", us.batchStatsHandler).Methods("POST")
+	r.HandleFunc("/api/stats/summary", us.summaryHandler).Methods("GET")
+	r.HandleFunc("/api/stats/{shortCode}", us.statsHandler).Methods("GET")
+	r.HandleFunc("/api/stats/{shortCode}/geo", us.geoStatsHandler).Methods("GET")
+	r.HandleFunc("/api/campaigns", us.createCampaignHandler).Methods("POST")
+	r.HandleFunc("/api/campaigns/{id}/stats", us.requireAuth(us.campaignStatsHandler)).Methods("GET")
+	r.HandleFunc("/api/urls", us.requireAuth(us.allURLsHandler)).Methods("GET")
+	r.HandleFunc("/api/urls/delete-batch", us.requireAuth(us.batchDeleteHandler)).Methods("POST")
+	r.HandleFunc("/api/urls/search", us.requireAdmin(us.searchHandler)).Methods("GET")
+	r.HandleFunc("/api/urls/{shortCode}", us.requireAuth(us.getURLHandler)).Methods("GET")
+	r.HandleFunc("/api/urls/{shortCode}", us.requireAuth(us.updateURLHandler)).Methods("PATCH")
+	r.HandleFunc("/api/urls/{shortCode}", us.requireAuth(us.deleteURLHandler)).Methods("DELETE")
+	r.HandleFunc("/api/urls/{shortCode}/restore", us.requireAuth(us.restoreURLHandler)).Methods("POST")
+	r.HandleFunc("/api/urls/{shortCode}/rotate", us.requireAuth(us.rotateURLHandler)).Methods("POST")
+	r.HandleFunc("/api/urls/{shortCode}/alias", us.requireAuth(us.addAliasHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/purge", us.requireAdmin(us.purgeHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/storage", us.requireAdmin(us.storageStatsHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/rebase", us.requireAdmin(us.rebaseHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/reload", us.requireAdmin(us.reloadHandler)).Methods("POST")
+	r.HandleFunc("/api/export", us.requireAdmin(us.exportHandler)).Methods("GET")
+	r.HandleFunc("/api/events", us.eventsHandler).Methods("GET")
+	r.HandleFunc("/ws/events", us.wsEventsHandler).Methods("GET")
+	r.HandleFunc("/api/health", us.readinessHandler).Methods("GET")
+	r.HandleFunc("/api/health/live", us.livenessHandler).Methods("GET")
+	r.HandleFunc("/api/health/ready", us.readinessHandler).Methods("GET")
+	r.HandleFunc("/api/version", us.versionHandler).Methods("GET")
+	r.HandleFunc("/metrics", us.metricsHandler).Methods("GET")
+
+	r.HandleFunc("/"+shortCodeRoute+"+", us.previewHandler).Methods("GET", "HEAD")
+	r.HandleFunc("/{namespace:[a-z0-9-]{1,20}}/"+shortCodeRoute+"+", us.previewHandler).Methods("GET", "HEAD").MatcherFunc(notReservedNamespace)
+	r.HandleFunc("/"+shortCodeRoute, us.redirectHandler).Methods("GET", "HEAD").Name(redirectRouteName)
+	r.HandleFunc("/{namespace:[a-z0-9-]{1,20}}/"+shortCodeRoute, us.redirectHandler).Methods("GET", "HEAD").MatcherFunc(notReservedNamespace).Name(redirectRouteName)
+	r.HandleFunc("/"+shortCodeRoute, us.confirmVisitHandler).Methods("POST")
+	r.HandleFunc("/{namespace:[a-z0-9-]{1,20}}/"+shortCodeRoute, us.confirmVisitHandler).Methods("POST").MatcherFunc(notReservedNamespace)
+
+	r.NotFoundHandler = http.HandlerFunc(us.notFoundHandler)
+
+	r.Use(us.metricsMiddleware)
+	r.Use(us.accessLogMiddleware)
+	r.Use(gzipMiddleware(us.config.GzipMinSize, "/api/events", "/api/qr/", "/ws/"))
+	r.Use(us.globalRateLimitMiddleware)
+	r.Use(concurrencyLimitMiddleware(us.config.MaxConcurrent, "/api/health", "/api/events", "/ws/"))
+	r.Use(us.requireHTTPSMiddleware)
+	r.Use(us.authMiddleware)
+	r.Use(timeoutMiddleware(us.config.RequestTimeout, "/api/events", "/api/export", "/ws/"))
+
+	return corsHandler(r, us.config.CORSMaxAge)
+}
+
+// notReservedNamespace keeps the namespaced short code routes off paths such
+// as /api/shorten, so those get the router's own 404 or 405 and preflights
+// only advertise the methods the real route accepts.
+func notReservedNamespace(r *http.Request, _ *mux.RouteMatch) bool {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	return !reservedNamespaces[segment]
+}
+
+// notFoundHandler answers requests that no route matched. A GET or HEAD whose
+// short code is empty or only whitespace, such as /team/ or /%20%20, gets a
+// JSON 404 naming the problem instead of the router's bare one.
+func (us *URLShortener) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && isBlankCodePath(r.URL.Path) {
+		writeJSON(w, http.StatusNotFound, api.ErrorResponse{
+			ErrorCode: CodeEmptyCode,
+			Error:     ErrEmptyCode.Error(),
+		}, us.jsonOptions(r))
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// isBlankCodePath reports whether path has the shape of a redirect, /code or
+// /namespace/code, with nothing but whitespace where the code should be.
+func isBlankCodePath(path string) bool {
+	segment, code, namespaced := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !namespaced {
+		code = segment
+	} else if reservedNamespaces[strings.ToLower(segment)] {
+		return false
+	}
+	return strings.TrimSpace(code) == ""
+}
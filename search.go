@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SearchModePrefix   = "prefix"
+	SearchModeContains = "contains"
+	SearchModeExact    = "exact"
+)
+
+const (
+	maxSearchQueryLength = 2048
+	defaultSearchLimit   = 50
+	maxSearchLimit       = 500
+)
+
+type searchResponse struct {
+	Total   int           `json:"total"`
+	Offset  int           `json:"offset"`
+	Limit   int           `json:"limit"`
+	Results []*URLMapping `json:"results"`
+}
+
+func isValidSearchMode(mode string) bool {
+	return mode == SearchModePrefix || mode == SearchModeContains || mode == SearchModeExact
+}
+
+// SearchByURL returns copies of the live links whose destination matches q
+// under mode, compared case-insensitively, oldest first. An unknown mode
+// matches nothing. It scans every link, so it is O(n) in the store size:
+// fine for an admin console, not for anything on the redirect path.
+func (us *URLShortener) SearchByURL(q, mode string) []*URLMapping {
+	if !isValidSearchMode(mode) {
+		return nil
+	}
+	q = strings.ToLower(q)
+
+	var matches []*URLMapping
+	us.ForEach(func(mapping *URLMapping) bool {
+		destination := strings.ToLower(mapping.OriginalURL)
+		var match bool
+		switch mode {
+		case SearchModePrefix:
+			match = strings.HasPrefix(destination, q)
+		case SearchModeContains:
+			match = strings.Contains(destination, q)
+		case SearchModeExact:
+			match = destination == q
+		}
+		if match {
+			matches = append(matches, mapping)
+		}
+		return true
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+// searchHandler serves GET /api/urls/search?q=&mode=&limit=&offset=. mode
+// defaults to contains.
+func (us *URLShortener) searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" || len(q) > maxSearchQueryLength {
+		http.Error(w, fmt.Sprintf("q is required and must be at most %d characters", maxSearchQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	mode := strings.ToLower(query.Get("mode"))
+	if mode == "" {
+		mode = SearchModeContains
+	}
+	if !isValidSearchMode(mode) {
+		http.Error(w, fmt.Sprintf("mode must be one of %s, %s, %s", SearchModePrefix, SearchModeContains, SearchModeExact), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitParam := query.Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if offsetParam := query.Get("offset"); offsetParam != "" {
+		parsed, err := strconv.Atoi(offsetParam)
+		if err != nil || parsed < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	matches := us.SearchByURL(q, mode)
+	start := min(offset, len(matches))
+	end := min(start+limit, len(matches))
+
+	writeJSON(w, http.StatusOK, searchResponse{
+		Total:   len(matches),
+		Offset:  offset,
+		Limit:   limit,
+		Results: append([]*URLMapping{}, matches[start:end]...),
+	}, us.jsonOptions(r))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func seedSearchLinks(t *testing.T, us *URLShortener) {
+	t.Helper()
+	for _, dest := range []string{
+		"https://Example.com/docs",
+		"https://example.com/docs/intro",
+		"https://blog.example.com/docs",
+		"https://other.org/page",
+	} {
+		mustCreate(t, us, dest, CreateOptions{})
+	}
+}
+
+func searchURLs(results []*URLMapping) []string {
+	var urls []string
+	for _, mapping := range results {
+		urls = append(urls, mapping.OriginalURL)
+	}
+	return urls
+}
+
+func TestSearchByURLModes(t *testing.T) {
+	us := newTestShortener(t, nil)
+	seedSearchLinks(t, us)
+
+	tests := []struct {
+		q, mode string
+		want    int
+	}{
+		{"HTTPS://EXAMPLE.COM/docs", SearchModePrefix, 2},
+		{"example.com/docs", SearchModeContains, 3},
+		{"https://example.com/DOCS", SearchModeExact, 1},
+		{"other.org", SearchModePrefix, 0},
+		{"docs", "fuzzy", 0},
+	}
+	for _, tt := range tests {
+		if got := us.SearchByURL(tt.q, tt.mode); len(got) != tt.want {
+			t.Errorf("SearchByURL(%q, %q) = %v, want %d matches", tt.q, tt.mode, searchURLs(got), tt.want)
+		}
+	}
+}
+
+func TestSearchHandlerPaginates(t *testing.T) {
+	us := newAdminTestShortener(t)
+	seedSearchLinks(t, us)
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/urls/search?q=docs", ""), http.StatusUnauthorized)
+
+	rec := serve(t, us, http.MethodGet, "/api/urls/search?q=example.com&limit=2&offset=1", "", "X-API-Key", "admin-key")
+	expectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Total   int           `json:"total"`
+		Results []*URLMapping `json:"results"`
+	}
+	decodeBody(t, rec, &page)
+	if page.Total != 3 || len(page.Results) != 2 {
+		t.Fatalf("total %d with %d results, want 3 with 2", page.Total, len(page.Results))
+	}
+
+	for _, target := range []string{
+		"/api/urls/search",
+		"/api/urls/search?q=docs&mode=fuzzy",
+		"/api/urls/search?q=docs&limit=0",
+		"/api/urls/search?q=docs&offset=-1",
+	} {
+		expectStatus(t, serve(t, us, http.MethodGet, target, "", "X-API-Key", "admin-key"), http.StatusBadRequest)
+	}
+}
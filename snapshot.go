@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 1
+
+// snapshot is the on-disk format shared by the server and the CLI.
+type snapshot struct {
+	Version int            `json:"version"`
+	SavedAt time.Time      `json:"saved_at"`
+	Links   []snapshotLink `json:"links"`
+
+	Campaigns []*Campaign `json:"campaigns,omitempty"`
+}
+
+// snapshotLink is a mapping as saved, with the fields that URLMapping keeps
+// out of API responses.
+type snapshotLink struct {
+	*URLMapping
+	ConfirmTokenHash string `json:"confirm_token_hash,omitempty"`
+}
+
+// SaveSnapshot writes all stored mappings to path, flushing buffered access
+// counts first. The file is replaced atomically so a crash never leaves a
+// half-written snapshot behind.
+func (us *URLShortener) SaveSnapshot(path string) error {
+	us.FlushCounts()
+
+	us.mutex.RLock()
+	snap := snapshot{
+		Version: snapshotVersion,
+		SavedAt: time.Now(),
+		Links:   make([]snapshotLink, 0, len(us.storage)),
+	}
+	for _, mapping := range us.storage {
+		snap.Links = append(snap.Links, snapshotLink{URLMapping: mapping, ConfirmTokenHash: mapping.ConfirmTokenHash})
+	}
+	for _, campaign := range us.campaigns {
+		snap.Campaigns = append(snap.Campaigns, campaign)
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	us.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot replaces the store's contents with the mappings saved at path.
+// A missing file is not an error and leaves the store empty.
+func (us *URLShortener) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decode snapshot %s: %w", path, err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("snapshot %s has unsupported version %d", path, snap.Version)
+	}
+
+	mappings := make([]*URLMapping, 0, len(snap.Links))
+	for _, link := range snap.Links {
+		if link.URLMapping == nil {
+			continue
+		}
+		link.URLMapping.ConfirmTokenHash = link.ConfirmTokenHash
+		mappings = append(mappings, link.URLMapping)
+	}
+	storage, aliases := indexMappings(mappings)
+
+	campaigns := make(map[string]*Campaign, len(snap.Campaigns))
+	for _, campaign := range snap.Campaigns {
+		campaigns[campaign.ID] = campaign
+	}
+
+	us.mutex.Lock()
+	us.storage = storage
+	us.aliases = aliases
+	us.campaigns = campaigns
+	us.mutex.Unlock()
+	return nil
+}
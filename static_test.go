@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeStaticFile puts a file with contents under staticRoot for the length
+// of the test and returns its /static/ path.
+func writeStaticFile(t *testing.T, pattern string, contents []byte) string {
+	t.Helper()
+	file, err := os.CreateTemp(staticRoot, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(file.Name()) })
+	if _, err := file.Write(contents); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	return "/static/" + filepath.Base(file.Name())
+}
+
+func TestStaticSniffsUnknownExtensions(t *testing.T) {
+	us := newTestShortener(t, nil)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	path := writeStaticFile(t, "sniff-*.qlasset", png)
+
+	rec := serve(t, us, http.MethodGet, path, "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("Content-Type = %q, want image/png", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), png) {
+		t.Fatal("body was not served from the start of the file after sniffing")
+	}
+}
+
+func TestSniffContentTypeRewinds(t *testing.T) {
+	reader := strings.NewReader("<!DOCTYPE html><html></html>")
+	contentType, err := sniffContentType(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(contentType, "text/html") {
+		t.Fatalf("content type = %q, want text/html", contentType)
+	}
+	if rest, _ := io.ReadAll(reader); !strings.HasPrefix(string(rest), "<!DOCTYPE") {
+		t.Fatalf("reader not rewound, remaining %q", rest)
+	}
+}
+
+func TestResolveStaticPathRejectsTraversal(t *testing.T) {
+	for _, requestPath := range []string{
+		"/static/../main.go",
+		"/static/css/../../main.go",
+		"/static/..",
+		"/static//etc/passwd",
+		"/static/..\\main.go",
+		"/static/",
+		"/static/a\x00b",
+	} {
+		if fullPath, ok := resolveStaticPath(requestPath); ok {
+			t.Errorf("%q resolved to %q", requestPath, fullPath)
+		}
+	}
+
+	fullPath, ok := resolveStaticPath("/static/img/logo.png")
+	if !ok || fullPath != filepath.Join(staticRoot, "img", "logo.png") {
+		t.Fatalf("nested path resolved to %q, %v", fullPath, ok)
+	}
+}
+
+func TestStaticHandlerServesFilesButNotTraversal(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodGet, "/static/style.css", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "text/css" {
+		t.Fatalf("Content-Type = %q, want text/css", got)
+	}
+
+	for _, target := range []string{"/static/%2e%2e/main.go", "/static/..%5cmain.go"} {
+		rec := serve(t, us, http.MethodGet, target, "")
+		if rec.Code == http.StatusOK || strings.Contains(rec.Body.String(), "package main") {
+			t.Fatalf("%s served main.go: %d", target, rec.Code)
+		}
+	}
+}
+
+func TestStaticNotModified(t *testing.T) {
+	us := newTestShortener(t, nil)
+	path := writeStaticFile(t, "cache-*.css", []byte("body{}"))
+
+	rec := serve(t, us, http.MethodGet, path, "")
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" || !strings.HasPrefix(rec.Header().Get("Cache-Control"), "public, max-age=") {
+		t.Fatalf("ETag = %q, Cache-Control = %q", etag, rec.Header().Get("Cache-Control"))
+	}
+	expectStatus(t, serve(t, us, http.MethodGet, path, "", "If-None-Match", etag), http.StatusNotModified)
+}
+
+func TestFaviconDoesNotReachRedirects(t *testing.T) {
+	us := newTestShortener(t, nil)
+	if _, _, err := us.CreateShortURL("https://example.com/icon", CreateOptions{CustomName: "favicon"}); err == nil {
+		t.Fatal("favicon was accepted as a custom name")
+	}
+
+	rec := serve(t, us, http.MethodGet, "/favicon.ico", "")
+	expectStatus(t, rec, http.StatusNoContent)
+	if rec.Header().Get("Location") != "" || rec.Body.Len() != 0 {
+		t.Fatalf("favicon response looks like a redirect lookup: %v %q", rec.Header(), rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Cache-Control"), "public, max-age=") {
+		t.Fatalf("Cache-Control = %q", rec.Header().Get("Cache-Control"))
+	}
+}
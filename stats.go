@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"url-shortener/api"
+)
+
+// Summary aggregates service-wide stats in a single pass over the store.
+// Soft-deleted links are not counted. CharsSaved compares each destination
+// with its short URL under the configured base URL, so links whose short URL
+// is the longer of the two count as negative savings.
+func (us *URLShortener) Summary() api.SummaryStats {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var (
+		summary        api.SummaryStats
+		originalChars  int64
+		shortenedChars int64
+	)
+	baseURL := us.currentBaseURL()
+	us.ForEach(func(mapping *URLMapping) bool {
+		summary.TotalLinks++
+		summary.TotalClicks += mapping.AccessCount
+		originalChars += int64(utf8.RuneCountInString(mapping.OriginalURL))
+		shortenedChars += int64(utf8.RuneCountInString(baseURL + "/" + mapping.path()))
+		if !mapping.CreatedAt.Before(startOfDay) {
+			summary.CreatedToday++
+		}
+		if summary.LastCreatedAt == nil || mapping.CreatedAt.After(*summary.LastCreatedAt) {
+			createdAt := mapping.CreatedAt
+			summary.LastCreatedAt = &createdAt
+		}
+		return true
+	})
+
+	if summary.TotalLinks > 0 {
+		summary.AverageClicks = float64(summary.TotalClicks) / float64(summary.TotalLinks)
+	}
+	summary.CharsSaved = originalChars - shortenedChars
+	if originalChars > 0 {
+		summary.CompressionRatio = float64(shortenedChars) / float64(originalChars)
+	}
+	return summary
+}
+
+func (us *URLShortener) summaryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, us.Summary(), us.jsonOptions(r))
+}
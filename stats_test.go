@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func TestSummaryAggregates(t *testing.T) {
+	us := newTestShortener(t, nil)
+	old := mustCreate(t, us, "https://example.com/a", CreateOptions{})
+	mid := mustCreate(t, us, "https://example.com/b", CreateOptions{})
+	latest := mustCreate(t, us, "https://example.com/c", CreateOptions{})
+
+	us.mutex.Lock()
+	old.AccessCount, mid.AccessCount, latest.AccessCount = 10, 5, 0
+	old.CreatedAt = time.Now().Add(-72 * time.Hour)
+	latestCreated := latest.CreatedAt
+	us.mutex.Unlock()
+
+	rec := serve(t, us, http.MethodGet, "/api/stats/summary", "")
+	expectStatus(t, rec, http.StatusOK)
+	var summary api.SummaryStats
+	decodeBody(t, rec, &summary)
+
+	if summary.TotalLinks != 3 || summary.TotalClicks != 15 || summary.AverageClicks != 5 {
+		t.Fatalf("links = %d, clicks = %d, average = %v, want 3, 15, 5",
+			summary.TotalLinks, summary.TotalClicks, summary.AverageClicks)
+	}
+	if summary.CreatedToday != 2 {
+		t.Fatalf("created today = %d, want 2", summary.CreatedToday)
+	}
+	if summary.LastCreatedAt == nil || !summary.LastCreatedAt.Equal(latestCreated) {
+		t.Fatalf("last created = %v, want %v", summary.LastCreatedAt, latestCreated)
+	}
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	us := newTestShortener(t, nil)
+	summary := us.Summary()
+	if summary.TotalLinks != 0 || summary.AverageClicks != 0 || summary.LastCreatedAt != nil {
+		t.Fatalf("empty summary = %+v", summary)
+	}
+}
+
+func TestLastAccessedAtAdvancesOnAccess(t *testing.T) {
+	us := newTestShortener(t, nil)
+	mustCreate(t, us, "https://example.com/used", CreateOptions{CustomName: "used"})
+
+	lastAccessed := func() *time.Time {
+		t.Helper()
+		rec := serve(t, us, http.MethodGet, "/api/stats/used", "")
+		expectStatus(t, rec, http.StatusOK)
+		var stats api.URLStats
+		decodeBody(t, rec, &stats)
+		return stats.LastAccessedAt
+	}
+	if got := lastAccessed(); got != nil {
+		t.Fatalf("unvisited link has last_accessed_at %v", got)
+	}
+
+	before := time.Now()
+	expectStatus(t, serve(t, us, http.MethodGet, "/used", ""), http.StatusMovedPermanently)
+	first := lastAccessed()
+	if first == nil || first.Before(before.Truncate(time.Second)) {
+		t.Fatalf("last_accessed_at = %v after a visit at %v", first, before)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	expectStatus(t, serve(t, us, http.MethodGet, "/used", ""), http.StatusMovedPermanently)
+	if second := lastAccessed(); second == nil || !second.After(*first) {
+		t.Fatalf("last_accessed_at did not advance: %v then %v", first, second)
+	}
+}
+
+// parseStatsLink extracts the target of a Link header with rel="stats".
+func parseStatsLink(t *testing.T, header string) string {
+	t.Helper()
+	target, params, found := strings.Cut(header, ";")
+	if !found || strings.TrimSpace(params) != `rel="stats"` || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+		t.Fatalf("Link = %q, want <target>; rel=\"stats\"", header)
+	}
+	return strings.Trim(target, "<>")
+}
+
+func TestStatsLinkHeader(t *testing.T) {
+	us := newTestShortener(t, nil)
+
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/linked","custom_name":"linked"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := parseStatsLink(t, rec.Header().Get("Link")); got != testBaseURL+"/api/stats/linked" {
+		t.Fatalf("shorten Link target = %q", got)
+	}
+
+	rec = serve(t, us, http.MethodGet, "/linked", "")
+	expectStatus(t, rec, http.StatusMovedPermanently)
+	if got := parseStatsLink(t, rec.Header().Get("Link")); got != testBaseURL+"/api/stats/linked" {
+		t.Fatalf("redirect Link target = %q", got)
+	}
+
+	mustCreate(t, us, "https://example.com/ns", CreateOptions{CustomName: "linked", Namespace: "d"})
+	rec = serve(t, us, http.MethodGet, "/d/linked", "")
+	target := parseStatsLink(t, rec.Header().Get("Link"))
+	stats := serve(t, us, http.MethodGet, strings.TrimPrefix(target, testBaseURL), "")
+	expectStatus(t, stats, http.StatusOK)
+	var body api.URLStats
+	decodeBody(t, stats, &body)
+	if body.Namespace != "d" || body.ShortCode != "linked" {
+		t.Fatalf("namespaced Link %s points at %+v", target, body)
+	}
+}
+
+func TestSummaryCharsSaved(t *testing.T) {
+	us := newTestShortener(t, nil)
+	// 51 characters shortened to http://short.test/longone (25): saves 26.
+	mustCreate(t, us, "https://example.com/a-rather-long-path/to/somewhere", CreateOptions{CustomName: "longone"})
+	// 12 characters "shortened" to http://short.test/negative (26): costs 14.
+	mustCreate(t, us, "https://x.io", CreateOptions{CustomName: "negative"})
+
+	summary := us.Summary()
+	if summary.CharsSaved != 12 {
+		t.Fatalf("chars_saved = %d, want 12", summary.CharsSaved)
+	}
+	if want := 51.0 / 63.0; math.Abs(summary.CompressionRatio-want) > 1e-9 {
+		t.Fatalf("compression ratio = %v, want %v", summary.CompressionRatio, want)
+	}
+}
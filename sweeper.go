@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+func (us *URLShortener) StartSweeper(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			us.sweep(now)
+		}
+	}()
+}
+
+func (us *URLShortener) sweep(now time.Time) {
+	if purged := us.purgeDeleted(now); purged > 0 {
+		log.Printf("Sweeper purged %d soft-deleted URL(s)", purged)
+	}
+	us.purgeTombstones(now)
+	us.purgePreviews(now)
+	us.trimClickHistory(now)
+	if us.config.SnapshotPath != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), us.config.SweepInterval)
+		defer cancel()
+		err := withRetry(ctx, us.storageRetryPolicy(), func() error {
+			return us.SaveSnapshot(us.config.SnapshotPath)
+		})
+		if err != nil {
+			log.Printf("Warning: failed to save snapshot: %v", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	maxTitleBodyBytes = 64 << 10
+	maxSlugAttempts   = 50
+	maxSlugLength     = maxShortCodeLength
+	minSlugLength     = minShortCodeLength
+
+	// fetchIdleConnTimeout closes kept-alive connections to destinations
+	// that are not asked for again.
+	fetchIdleConnTimeout = 90 * time.Second
+)
+
+var (
+	titlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+	errPrivateAddress = errors.New("refusing to fetch a private address")
+)
+
+// titleFetchClient fetches destination pages for title slugs. It refuses to
+// connect to loopback, private or link-local addresses so the feature cannot
+// be used to probe the internal network.
+func titleFetchClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, IdleConnTimeout: fetchIdleConnTimeout},
+	}
+}
+
+// fetchTitle returns the text of the destination page's <title> element.
+func fetchTitle(ctx context.Context, client *http.Client, destination string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, destination, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("destination returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTitleBodyBytes))
+	if err != nil {
+		return "", err
+	}
+	match := titlePattern.FindSubmatch(body)
+	if match == nil {
+		return "", errors.New("destination has no <title>")
+	}
+	return strings.TrimSpace(html.UnescapeString(string(match[1]))), nil
+}
+
+// slugify lowercases title and joins its alphanumeric runs with hyphens,
+// trimmed to fit a short code. It returns "" if too little is left.
+func slugify(title string) string {
+	slug := strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if len(slug) < minSlugLength {
+		return ""
+	}
+	return slug
+}
+
+// titleSlug derives a slug from the destination's title, returning "" when
+// the page cannot be fetched so the caller falls back to a random code.
+func (us *URLShortener) titleSlug(ctx context.Context, destination string) string {
+	if !isWebScheme(destination) {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, us.config.TitleFetchTimeout)
+	defer cancel()
+
+	title, err := fetchTitle(ctx, us.titleClient, destination)
+	if err != nil {
+		log.Printf("Could not derive a slug from %s, using a random code: %v", us.logURL(destination), err)
+		return ""
+	}
+	return slugify(title)
+}
+
+// availableSlugLocked returns base, or base with a numeric suffix, that is not
+// taken or reserved in namespace. Callers must hold the lock.
+func (us *URLShortener) availableSlugLocked(namespace, base string) string {
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+		candidate := base
+		if attempt > 1 {
+			suffix := fmt.Sprintf("-%d", attempt)
+			candidate = strings.TrimRight(base[:min(len(base), maxSlugLength-len(suffix))], "-") + suffix
+		}
+		candidate = us.canonicalCode(candidate)
+		if !us.codeTakenLocked(storageKey(namespace, candidate)) && !us.isReservedCode(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
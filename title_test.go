@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/api"
+)
+
+func newTitleServer(t *testing.T, page string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchTitleFromDestination(t *testing.T) {
+	srv := newTitleServer(t, "<html><head><TITLE class=x>\n  My Blog &amp; Post!  </TITLE></head></html>")
+	title, err := fetchTitle(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "My Blog & Post!" {
+		t.Fatalf("title = %q", title)
+	}
+	if slug := slugify(title); slug != "my-blog-post" {
+		t.Fatalf("slug = %q, want my-blog-post", slug)
+	}
+
+	untitled := newTitleServer(t, "<html><body>no title</body></html>")
+	if _, err := fetchTitle(context.Background(), untitled.Client(), untitled.URL); err == nil {
+		t.Fatal("page without a title produced one")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for title, want := range map[string]string{
+		"Hello, World":                          "hello-world",
+		"  --Déjà Vu--  ":                       "d-j-vu",
+		"!!":                                    "",
+		"A very long title that goes on and on": "a-very-long-title-th",
+	} {
+		if got := slugify(title); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestTitleSlugsGetSuffixOnCollision(t *testing.T) {
+	us := newTestShortener(t, nil)
+	first := mustCreate(t, us, "https://example.com/1", CreateOptions{TitleSlug: "my-post"})
+	second := mustCreate(t, us, "https://example.com/2", CreateOptions{TitleSlug: "my-post"})
+	if first.ShortCode != "my-post" || second.ShortCode != "my-post-2" {
+		t.Fatalf("codes = %q, %q, want my-post and my-post-2", first.ShortCode, second.ShortCode)
+	}
+}
+
+func TestTitleSlugFallsBackToRandomCode(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.TitleSlugs = true
+		c.TitleFetchTimeout = time.Second
+	})
+	// The fetch client refuses loopback addresses, so the slug lookup fails.
+	srv := newTitleServer(t, "<title>Internal Page</title>")
+	mapping, _, err := us.createFromRequest(context.Background(), api.CreateURLRequest{URL: srv.URL, SlugFromTitle: true}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.ShortCode == "internal-page" || len(mapping.ShortCode) != us.config.CodeLength {
+		t.Fatalf("code = %q, want a random fallback", mapping.ShortCode)
+	}
+}
+
+func TestTitleSlugFromDestination(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.TitleSlugs = true
+		c.TitleFetchTimeout = time.Second
+	})
+	srv := newTitleServer(t, "<title>Launch Notes</title>")
+	// The test server is on loopback, which the default client refuses.
+	us.titleClient = srv.Client()
+
+	mapping, _, err := us.createFromRequest(context.Background(), api.CreateURLRequest{URL: srv.URL, SlugFromTitle: true}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.ShortCode != "launch-notes" {
+		t.Fatalf("code = %q, want launch-notes", mapping.ShortCode)
+	}
+}
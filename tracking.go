@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// defaultTrackingParams are the query keys StripTrackingParams removes unless
+// TrackingParams says otherwise. A trailing "*" matches any key with that
+// prefix.
+var defaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+func isTrackingParam(key string, params []string) bool {
+	key = strings.ToLower(key)
+	for _, param := range params {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTrackingParams removes the query parameters whose key, once decoded,
+// matches params. The remaining parameters are kept byte for byte and in
+// order, since some destinations care about either; so is the fragment.
+func stripTrackingParams(rawURL string, params []string) string {
+	rest, fragment, hasFragment := strings.Cut(rawURL, "#")
+	base, query, hasQuery := strings.Cut(rest, "?")
+	if !hasQuery || query == "" {
+		return rawURL
+	}
+
+	pairs := strings.Split(query, "&")
+	kept := pairs[:0:0]
+	for _, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+		if !isTrackingParam(key, params) {
+			kept = append(kept, pair)
+		}
+	}
+	if len(kept) == len(pairs) {
+		return rawURL
+	}
+
+	stripped := base
+	if len(kept) > 0 {
+		stripped += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		stripped += "#" + fragment
+	}
+	return stripped
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestStripTrackingParams(t *testing.T) {
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{"https://example.com/p?utm_source=news&utm_medium=email", "https://example.com/p"},
+		{"https://example.com/p?id=7&utm_campaign=x&q=a%26b", "https://example.com/p?id=7&q=a%26b"},
+		{"https://example.com/p?fbclid=abc&gclid=def#top", "https://example.com/p#top"},
+		{"https://example.com/p?UTM_Source=x&page=2", "https://example.com/p?page=2"},
+		{"https://example.com/p?utm%5Fsource=x&page=2", "https://example.com/p?page=2"},
+		{"https://example.com/p?source=utm_source&utm=1", "https://example.com/p?source=utm_source&utm=1"},
+		{"https://example.com/p?b=2&a=1", "https://example.com/p?b=2&a=1"},
+		{"https://example.com/p", "https://example.com/p"},
+	} {
+		if got := stripTrackingParams(tt.input, defaultTrackingParams); got != tt.want {
+			t.Errorf("stripTrackingParams(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if got := stripTrackingParams("https://example.com/p?ref=x&utm_source=y", []string{"ref"}); got != "https://example.com/p?utm_source=y" {
+		t.Errorf("custom param list: got %q", got)
+	}
+}
+
+func TestStripTrackingParamsDedupsLinks(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.StripTrackingParams = true })
+	first := mustCreate(t, us, "https://example.com/post?id=1&utm_source=twitter", CreateOptions{})
+	second := mustCreate(t, us, "https://example.com/post?id=1&utm_source=newsletter&fbclid=xyz", CreateOptions{})
+	if first.ShortCode != second.ShortCode || first.OriginalURL != "https://example.com/post?id=1" {
+		t.Fatalf("got %s -> %s and %s -> %s, want one link without tracking params",
+			first.ShortCode, first.OriginalURL, second.ShortCode, second.OriginalURL)
+	}
+
+	us = newTestShortener(t, nil)
+	first = mustCreate(t, us, "https://example.com/post?id=1&utm_source=twitter", CreateOptions{})
+	second = mustCreate(t, us, "https://example.com/post?id=1&utm_source=newsletter", CreateOptions{})
+	if first.ShortCode == second.ShortCode {
+		t.Fatal("tracking params were stripped with StripTrackingParams off")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const uiAuthRealm = "QuickLink"
+
+// BasicAuth is a single username and bcrypt password hash for HTTP Basic
+// Auth, e.g. from htpasswd -nbB "" "$PASSWORD" | cut -d: -f2.
+type BasicAuth struct {
+	Username     string
+	PasswordHash string
+}
+
+func (a BasicAuth) enabled() bool {
+	return a.Username != "" || a.PasswordHash != ""
+}
+
+func isValidUIPasswordHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// check reports whether username and password match. The password is always
+// compared, so a wrong username takes as long as a wrong password. A
+// malformed hash matches nothing.
+func (a BasicAuth) check(username, password string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(a.Username)) == 1
+	passOK := bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) == nil
+	return userOK && passOK
+}
+
+// requireUIAuth puts the web UI behind UIBasicAuth when it is configured.
+// Only the UI routes and static files are wrapped; redirects and the API stay
+// as they are.
+func (us *URLShortener) requireUIAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := us.config.UIBasicAuth
+		if !auth.enabled() {
+			next(w, r)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || !auth.check(username, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+uiAuthRealm+`", charset="UTF-8"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
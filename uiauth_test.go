@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func basicAuthHeader(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// testUIBasicAuth is ops:hunter2, hashed at the lowest cost to keep tests fast.
+func testUIBasicAuth(t *testing.T) BasicAuth {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return BasicAuth{Username: "ops", PasswordHash: string(hash)}
+}
+
+func newUIAuthTestShortener(t *testing.T) *URLShortener {
+	auth := testUIBasicAuth(t)
+	return newTestShortener(t, func(c *Config) { c.UIBasicAuth = auth })
+}
+
+func TestUIBasicAuthChallenges(t *testing.T) {
+	us := newUIAuthTestShortener(t)
+
+	for name, headers := range map[string][]string{
+		"missing":        nil,
+		"wrong password": {"Authorization", basicAuthHeader("ops", "hunter3")},
+		"wrong user":     {"Authorization", basicAuthHeader("root", "hunter2")},
+		"bearer":         {"Authorization", "Bearer hunter2"},
+	} {
+		for _, target := range []string{"/", "/static/style.css"} {
+			rec := serve(t, us, http.MethodGet, target, "", headers...)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("%s credentials on %s: status %d, want 401", name, target, rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != `Basic realm="QuickLink", charset="UTF-8"` {
+				t.Fatalf("%s credentials on %s: WWW-Authenticate = %q", name, target, got)
+			}
+		}
+	}
+}
+
+func TestUIBasicAuthAcceptsCorrectCredentials(t *testing.T) {
+	us := newUIAuthTestShortener(t)
+	auth := basicAuthHeader("ops", "hunter2")
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/", "", "Authorization", auth), http.StatusOK)
+	expectStatus(t, serve(t, us, http.MethodGet, "/static/style.css", "", "Authorization", auth), http.StatusOK)
+}
+
+func TestUIBasicAuthCoversReservedStaticFiles(t *testing.T) {
+	name := filepath.Base(writeStaticFile(t, "rsv*", []byte("User-agent: *\n")))
+	auth := testUIBasicAuth(t)
+	us := newTestShortener(t, func(c *Config) {
+		c.UIBasicAuth = auth
+		c.ReservedPaths = []string{name}
+	})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/"+name, ""), http.StatusUnauthorized)
+	expectStatus(t, serve(t, us, http.MethodGet, "/"+name, "", "Authorization", basicAuthHeader("ops", "hunter2")), http.StatusOK)
+}
+
+func TestUIBasicAuthLeavesRedirectsAndAPIOpen(t *testing.T) {
+	us := newUIAuthTestShortener(t)
+	mustCreate(t, us, "https://example.com/open", CreateOptions{CustomName: "open"})
+
+	expectStatus(t, serve(t, us, http.MethodGet, "/open", ""), http.StatusMovedPermanently)
+	expectStatus(t, serve(t, us, http.MethodGet, "/api/stats/open", ""), http.StatusOK)
+}
+
+func TestUIBasicAuthMalformedHashRejectsAll(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.UIBasicAuth = BasicAuth{Username: "ops", PasswordHash: "$2y$10$notsupported"}
+	})
+	expectStatus(t, serve(t, us, http.MethodGet, "/", "", "Authorization", basicAuthHeader("ops", "$2y$10$notsupported")), http.StatusUnauthorized)
+}
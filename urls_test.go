@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultSchemeAppliedToBareHosts(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.DefaultScheme = "https" })
+	mapping := mustCreate(t, us, "example.com/path", CreateOptions{})
+	if mapping.OriginalURL != "https://example.com/path" {
+		t.Fatalf("stored %q, want https://example.com/path", mapping.OriginalURL)
+	}
+
+	us = newTestShortener(t, nil)
+	mapping = mustCreate(t, us, "example.com/path", CreateOptions{})
+	if mapping.OriginalURL != "http://example.com/path" {
+		t.Fatalf("stored %q with the default config, want http://example.com/path", mapping.OriginalURL)
+	}
+}
+
+func TestNormalizeAndValidateAgree(t *testing.T) {
+	allowed := []string{"http", "https"}
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{"example.com", "https://example.com"},
+		{"example.com:8080/x", "https://example.com:8080/x"},
+		{"http://example.com", "http://example.com"},
+	} {
+		if got := normalizeURL(tt.input, "https", false); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if !validateURL(tt.input, "https", allowed) {
+			t.Errorf("validateURL(%q) = false", tt.input)
+		}
+	}
+
+	// Only https is allowed, so a bare host is valid exactly when it would
+	// be normalized to https.
+	if validateURL("example.com", "http", []string{"https"}) {
+		t.Error("bare host accepted although it normalizes to a disallowed scheme")
+	}
+}
+
+func TestStripFragment(t *testing.T) {
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{"https://example.com/page#section", "https://example.com/page"},
+		{"https://example.com/page?q=a%23b#top", "https://example.com/page?q=a%23b"},
+		{"https://example.com/page?q=a%23b", "https://example.com/page?q=a%23b"},
+		{"https://example.com/page", "https://example.com/page"},
+	} {
+		if got := normalizeURL(tt.input, "https", true); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if got := normalizeURL("https://example.com/page#section", "https", false); got != "https://example.com/page#section" {
+		t.Errorf("fragment stripped with the option off: %q", got)
+	}
+}
+
+func TestStripFragmentDedupsLinks(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.StripFragment = true })
+	first := mustCreate(t, us, "https://example.com/doc#intro", CreateOptions{})
+	second := mustCreate(t, us, "https://example.com/doc#usage", CreateOptions{})
+	if first.ShortCode != second.ShortCode || first.OriginalURL != "https://example.com/doc" {
+		t.Fatalf("got %s -> %s and %s -> %s, want one link without the fragment",
+			first.ShortCode, first.OriginalURL, second.ShortCode, second.OriginalURL)
+	}
+
+	us = newTestShortener(t, nil)
+	first = mustCreate(t, us, "https://example.com/doc#intro", CreateOptions{})
+	second = mustCreate(t, us, "https://example.com/doc#usage", CreateOptions{})
+	if first.ShortCode == second.ShortCode {
+		t.Fatal("fragments were merged with StripFragment off")
+	}
+}
+
+func TestAllowedSchemes(t *testing.T) {
+	defaults := DefaultConfig().AllowedSchemes
+	for input, want := range map[string]bool{
+		"https://example.com":        true,
+		"http://example.com":         true,
+		"ftp://files.example.com":    false,
+		"mailto:someone@example.com": false,
+	} {
+		if got := validateURL(input, "https", defaults); got != want {
+			t.Errorf("default schemes: validateURL(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	custom := []string{"https", "mailto", "tel", "myapp"}
+	for input, want := range map[string]bool{
+		"mailto:someone@example.com": true,
+		"tel:+15551234567":           true,
+		"myapp://open/settings":      true,
+		"mailto:":                    false,
+		"http://example.com":         false,
+		"ftp://files.example.com":    false,
+	} {
+		if got := validateURL(input, "https", custom); got != want {
+			t.Errorf("custom schemes: validateURL(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestAllowedSchemesOnCreate(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.AllowedSchemes = []string{"https", "mailto"} })
+	mapping := mustCreate(t, us, "mailto:team@example.com", CreateOptions{})
+	if mapping.OriginalURL != "mailto:team@example.com" {
+		t.Fatalf("stored %q", mapping.OriginalURL)
+	}
+	if _, _, err := us.CreateShortURL("ftp://files.example.com/a", CreateOptions{}); err == nil {
+		t.Fatal("ftp accepted although it is not allowed")
+	}
+}
+
+func TestUnsafeSchemesRejected(t *testing.T) {
+	// Even an allowlist naming them does not let these through.
+	allowed := []string{"https", "javascript", "data", "vbscript"}
+	for input, want := range map[string]bool{
+		"javascript:alert(1)":                      false,
+		"  JavaScript:alert(1)":                    false,
+		"java\tscript:alert(1)":                    false,
+		"data:text/html,<script>alert(1)</script>": false,
+		"vbscript:msgbox(1)":                       false,
+		"https://example.com/javascript:":          true,
+	} {
+		if got := validateURL(input, "https", allowed); got != want {
+			t.Errorf("validateURL(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	us := newTestShortener(t, nil)
+	for _, input := range []string{"javascript:alert(1)", "data:text/html,<h1>hi</h1>"} {
+		if _, _, err := us.CreateShortURL(input, CreateOptions{}); !errors.Is(err, ErrUnsafeScheme) {
+			t.Errorf("CreateShortURL(%q) error = %v, want ErrUnsafeScheme", input, err)
+		}
+	}
+	mustCreate(t, us, "https://example.com/safe", CreateOptions{})
+}
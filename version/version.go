@@ -0,0 +1,28 @@
+package version
+
+import "runtime"
+
+// Populated at build time via:
+//
+//	go build -ldflags "-X url-shortener/version.Version=... -X url-shortener/version.GitCommit=... -X url-shortener/version.BuildTime=..."
+var (
+	Version   = "dev"
+	GitCommit = "dev"
+	BuildTime = "dev"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
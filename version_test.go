@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+)
+
+func TestVersionEndpointDefaultsToDev(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) {
+		c.APIKeys = map[string]string{"key": "alice"}
+	})
+
+	rec := serve(t, us, http.MethodGet, "/api/version", "")
+	expectStatus(t, rec, http.StatusOK)
+	var info map[string]string
+	decodeBody(t, rec, &info)
+	for _, field := range []string{"version", "git_commit", "build_time"} {
+		if info[field] != "dev" {
+			t.Errorf("%s = %q, want dev", field, info[field])
+		}
+	}
+	if info["go_version"] != runtime.Version() {
+		t.Errorf("go_version = %q, want %q", info["go_version"], runtime.Version())
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"url-shortener/api"
+)
+
+const (
+	versionUnspecified = 0
+	latestAPIVersion   = 2
+
+	vendorMediaPrefix = "application/vnd.quicklink.v"
+	vendorMediaSuffix = "+json"
+)
+
+var errUnsupportedVersion = errors.New("requested API version is not supported")
+
+func vendorMediaType(version int) string {
+	return vendorMediaPrefix + strconv.Itoa(version) + vendorMediaSuffix
+}
+
+// negotiateVersion picks the create response version from the Accept header.
+// Requests without a vendor media type get versionUnspecified and the original
+// response shape. Asking only for unknown versions is an error.
+func negotiateVersion(r *http.Request) (int, error) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return versionUnspecified, nil
+	}
+
+	chosen, generic, unsupported := versionUnspecified, false, false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if !strings.HasPrefix(mediaType, vendorMediaPrefix) || !strings.HasSuffix(mediaType, vendorMediaSuffix) {
+			generic = true
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaPrefix), vendorMediaSuffix))
+		if err != nil || version < 1 || version > latestAPIVersion {
+			unsupported = true
+			continue
+		}
+		if version > chosen {
+			chosen = version
+		}
+	}
+
+	if chosen == versionUnspecified && unsupported && !generic {
+		return 0, errUnsupportedVersion
+	}
+	return chosen, nil
+}
+
+func (us *URLShortener) createDetails(mapping *URLMapping, baseURL string, created bool) api.CreateURLDetails {
+	return api.CreateURLDetails{
+		ShortCode:    mapping.ShortCode,
+		Namespace:    mapping.Namespace,
+		OriginalURL:  mapping.OriginalURL,
+		SubmittedURL: mapping.SubmittedURL,
+		ShortURL:     fmt.Sprintf("%s/%s", baseURL, mapping.path()),
+		Created:      created,
+		CreatedAt:    mapping.CreatedAt,
+		ExpiresAt:    mapping.ExpiresAt,
+		Enabled:      mapping.Enabled,
+		Description:  mapping.Description,
+		CampaignID:   mapping.CampaignID,
+		DelaySeconds: mapping.DelaySeconds,
+		AppendParams: mapping.AppendParams,
+
+		RedirectHeaders: mapping.RedirectHeaders,
+		DeviceRules:     mapping.DeviceRules,
+		GeoRules:        mapping.GeoRules,
+		ConfirmRequired: mapping.ConfirmTokenHash != "",
+	}
+}
+
+// writeCreateResponse renders details in the negotiated response version.
+func (us *URLShortener) writeCreateResponse(w http.ResponseWriter, r *http.Request, version, status int, details api.CreateURLDetails) {
+	w.Header().Add("Vary", "Accept")
+
+	var body interface{}
+	switch version {
+	case 2:
+		body = api.CreateURLResponseV2{APIVersion: "2", Data: details}
+	default:
+		response := api.CreateURLResponse{
+			ShortCode:   details.ShortCode,
+			Namespace:   details.Namespace,
+			OriginalURL: details.OriginalURL,
+			ShortURL:    details.ShortURL,
+		}
+		if version == 1 {
+			response.APIVersion = "1"
+		}
+		body = response
+	}
+
+	if version != versionUnspecified {
+		w.Header().Set("Content-Type", vendorMediaType(version))
+	}
+	writeJSON(w, status, body, us.jsonOptions(r))
+}
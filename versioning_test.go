@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"url-shortener/api"
+)
+
+func TestCreateResponseDefaultShape(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v0","custom_name":"plain"}`)
+	expectStatus(t, rec, http.StatusCreated)
+
+	var fields map[string]json.RawMessage
+	decodeBody(t, rec, &fields)
+	for _, key := range []string{"api_version", "data", "created_at"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("unversioned response has %q", key)
+		}
+	}
+	if string(fields["short_code"]) != `"plain"` {
+		t.Fatalf("short_code = %s", fields["short_code"])
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+}
+
+func TestCreateResponseV1(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v1","custom_name":"vone"}`,
+		"Accept", "application/vnd.quicklink.v1+json")
+	expectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Content-Type"); got != "application/vnd.quicklink.v1+json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+
+	var resp api.CreateURLResponse
+	decodeBody(t, rec, &resp)
+	if resp.APIVersion != "1" || resp.ShortCode != "vone" || resp.ShortURL != testBaseURL+"/vone" {
+		t.Fatalf("v1 response = %+v", resp)
+	}
+}
+
+func TestCreateResponseV2(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten",
+		`{"url":"https://example.com/v2","custom_name":"vtwo","description":"launch","expires_in_seconds":3600}`,
+		"Accept", "application/json, application/vnd.quicklink.v2+json")
+	expectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Content-Type"); got != "application/vnd.quicklink.v2+json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+
+	var resp api.CreateURLResponseV2
+	decodeBody(t, rec, &resp)
+	data := resp.Data
+	if resp.APIVersion != "2" || data.ShortCode != "vtwo" || !data.Created || !data.Enabled || data.Description != "launch" || data.ExpiresAt == nil || data.CreatedAt.IsZero() {
+		t.Fatalf("v2 response = %+v", resp)
+	}
+
+	// A dedup hit reports that nothing new was created.
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v2","custom_name":"vtwo","description":"launch","expires_in_seconds":3600}`,
+		"Accept", "application/vnd.quicklink.v2+json")
+	decodeBody(t, rec, &resp)
+	if resp.Data.Created {
+		t.Fatalf("repeat request reported created: status %d", rec.Code)
+	}
+}
+
+func TestCreateResponseUnsupportedVersion(t *testing.T) {
+	us := newTestShortener(t, nil)
+	rec := serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v9"}`,
+		"Accept", "application/vnd.quicklink.v9+json")
+	expectStatus(t, rec, http.StatusNotAcceptable)
+	if countLinksTo(us, "https://example.com/v9") != 0 {
+		t.Fatal("link created despite the unsupported version")
+	}
+
+	rec = serve(t, us, http.MethodPost, "/api/shorten", `{"url":"https://example.com/v9"}`,
+		"Accept", "application/vnd.quicklink.v9+json, */*")
+	expectStatus(t, rec, http.StatusCreated)
+}
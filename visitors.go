@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"time"
+)
+
+func randomSalt() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().String()
+	}
+	return hex.EncodeToString(buf)
+}
+
+// visitorHash never stores the raw IP; only a salted digest is kept.
+func (us *URLShortener) visitorHash(ip net.IP) string {
+	sum := sha256.Sum256([]byte(us.config.VisitorSalt + "|" + ip.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// recordUniqueVisit bumps UniqueClicks when the visitor has not been seen for
+// this link within UniqueVisitorWindow. At most MaxTrackedVisitors hashes are
+// kept per link; the oldest is dropped when the set is full.
+func (us *URLShortener) recordUniqueVisit(key string, ip net.IP) {
+	if ip == nil {
+		return
+	}
+	hash := us.visitorHash(ip)
+	now := time.Now()
+
+	us.mutex.Lock()
+	defer us.mutex.Unlock()
+
+	mapping, exists := us.storage[key]
+	if !exists {
+		return
+	}
+	if mapping.recentVisitors == nil {
+		mapping.recentVisitors = make(map[string]time.Time)
+	}
+
+	if lastSeen, seen := mapping.recentVisitors[hash]; seen && now.Sub(lastSeen) < us.config.UniqueVisitorWindow {
+		mapping.recentVisitors[hash] = now
+		return
+	}
+
+	if len(mapping.recentVisitors) >= us.config.MaxTrackedVisitors {
+		us.pruneVisitorsLocked(mapping, now)
+	}
+
+	mapping.recentVisitors[hash] = now
+	mapping.UniqueClicks++
+}
+
+func (us *URLShortener) pruneVisitorsLocked(mapping *URLMapping, now time.Time) {
+	var (
+		oldestHash string
+		oldestTime time.Time
+	)
+	for hash, seen := range mapping.recentVisitors {
+		if now.Sub(seen) >= us.config.UniqueVisitorWindow {
+			delete(mapping.recentVisitors, hash)
+			continue
+		}
+		if oldestHash == "" || seen.Before(oldestTime) {
+			oldestHash, oldestTime = hash, seen
+		}
+	}
+
+	if len(mapping.recentVisitors) >= us.config.MaxTrackedVisitors && oldestHash != "" {
+		delete(mapping.recentVisitors, oldestHash)
+	}
+}
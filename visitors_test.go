@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUniqueClicksCountDistinctVisitors(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.TrustProxyHeaders = true })
+	mapping := mustCreate(t, us, "https://example.com/unique", CreateOptions{CustomName: "unique"})
+
+	for _, ip := range []string{"203.0.113.1", "203.0.113.1", "203.0.113.1", "203.0.113.2"} {
+		expectStatus(t, serve(t, us, http.MethodGet, "/unique", "", "X-Forwarded-For", ip), http.StatusMovedPermanently)
+	}
+
+	us.mutex.RLock()
+	defer us.mutex.RUnlock()
+	if mapping.AccessCount != 4 || mapping.UniqueClicks != 2 {
+		t.Fatalf("total = %d, unique = %d, want 4 and 2", mapping.AccessCount, mapping.UniqueClicks)
+	}
+	for hash := range mapping.recentVisitors {
+		if strings.Contains(hash, "203.0.113") {
+			t.Fatalf("raw IP stored as visitor key %q", hash)
+		}
+	}
+}
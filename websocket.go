@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout    = 10 * time.Second
+	wsPingInterval    = 30 * time.Second
+	wsPongTimeout     = 2 * wsPingInterval
+	wsMaxFramePayload = 4096
+)
+
+// wsOriginAllowed reports whether a browser on r's Origin may open the event
+// socket: the page must come from this server, as addressed by the request or
+// by its public base URL. Clients that send no Origin are not browsers and
+// are let through.
+func (us *URLShortener) wsOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return strings.EqualFold(u.Scheme+"://"+u.Host, us.publicBaseURL(r))
+}
+
+// hijacker unwraps the middleware's response writers down to one that can
+// hand over the connection, which the upgrader needs.
+func hijacker(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		if _, ok := w.(http.Hijacker); ok {
+			return w
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// wsEventsHandler serves GET /ws/events: the same events as /api/events, one
+// JSON text message per event. Events are dropped for a client that falls
+// behind, as they are for SSE, so producers never wait.
+func (us *URLShortener) wsEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if limit := us.config.WSMaxSubscribers; limit > 0 {
+		if us.wsSubscribers.Add(1) > int64(limit) {
+			us.wsSubscribers.Add(-1)
+			http.Error(w, "Too many WebSocket subscribers", http.StatusServiceUnavailable)
+			return
+		}
+		defer us.wsSubscribers.Add(-1)
+	}
+
+	upgrader := websocket.Upgrader{
+		HandshakeTimeout: wsWriteTimeout,
+		CheckOrigin:      us.wsOriginAllowed,
+	}
+	conn, err := upgrader.Upgrade(hijacker(w), r, nil)
+	if err != nil {
+		// Upgrade has already written the error response.
+		return
+	}
+	defer conn.Close()
+	// The server's read and write timeouts were set for ordinary requests.
+	conn.NetConn().SetDeadline(time.Time{})
+
+	events := us.Subscribe()
+	defer us.Unsubscribe(events)
+
+	// The reader notices the client going away; gorilla answers its pings
+	// and the handler below keeps the read deadline moving on pongs.
+	conn.SetReadLimit(wsMaxFramePayload)
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(wsPingInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-keepAlive.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		case event, ok := <-events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if !us.eventVisibleTo(r, event) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = conn.WriteJSON(us.eventFor(r, event))
+		}
+		if err != nil {
+			log.Printf("WebSocket subscriber %s dropped: %v", r.RemoteAddr, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS opens /ws/events on server with the given extra headers. The
+// response is returned whether or not the handshake succeeded.
+func dialWS(t *testing.T, server *httptest.Server, headers ...string) (*websocket.Conn, *http.Response) {
+	t.Helper()
+	header := http.Header{}
+	for i := 0; i+1 < len(headers); i += 2 {
+		header.Set(headers[i], headers[i+1])
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/events", header)
+	if resp == nil {
+		t.Fatal(err)
+	}
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp
+}
+
+// waitForSubscribers waits for n event subscriptions to be registered.
+func waitForSubscribers(us *URLShortener, n int) {
+	deadline := time.Now().Add(time.Second)
+	for us.events.count() != n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readWSEvent(t *testing.T, conn *websocket.Conn) ClickEvent {
+	t.Helper()
+	var event ClickEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestWebSocketReceivesEvents(t *testing.T) {
+	us := newTestShortener(t, nil)
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+
+	conn, resp := dialWS(t, server)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	waitForSubscribers(us, 1)
+
+	mustCreate(t, us, "https://example.com/live", CreateOptions{CustomName: "live"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/live", ""), http.StatusMovedPermanently)
+
+	for _, want := range []string{EventTypeCreated, EventTypeClick} {
+		if event := readWSEvent(t, conn); event.Type != want || event.ShortCode != "live" {
+			t.Fatalf("event = %+v, want a %s event for live", event, want)
+		}
+	}
+}
+
+func TestWebSocketCreatedEventsNeedAdmin(t *testing.T) {
+	us := newAdminTestShortener(t)
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+
+	anonymous, resp := dialWS(t, server)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("anonymous handshake status = %d, want 101", resp.StatusCode)
+	}
+	admin, resp := dialWS(t, server, "X-API-Key", "admin-key")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("admin handshake status = %d, want 101", resp.StatusCode)
+	}
+	waitForSubscribers(us, 2)
+
+	mustCreate(t, us, "https://example.com/live", CreateOptions{CustomName: "live"})
+	expectStatus(t, serve(t, us, http.MethodGet, "/live", ""), http.StatusMovedPermanently)
+
+	if event := readWSEvent(t, anonymous); event.Type != EventTypeClick {
+		t.Fatalf("anonymous subscriber got %+v, want only the click", event)
+	}
+	if event := readWSEvent(t, admin); event.Type != EventTypeCreated {
+		t.Fatalf("admin subscriber got %+v, want the creation first", event)
+	}
+}
+
+func TestWebSocketRejectsForeignOrigin(t *testing.T) {
+	us := newTestShortener(t, nil)
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+
+	if _, resp := dialWS(t, server, "Origin", "https://evil.example"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("foreign origin got %d, want 403", resp.StatusCode)
+	}
+	if _, resp := dialWS(t, server, "Origin", server.URL); resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("same origin got %d, want 101", resp.StatusCode)
+	}
+}
+
+func TestWebSocketSubscriberCap(t *testing.T) {
+	us := newTestShortener(t, func(c *Config) { c.WSMaxSubscribers = 1 })
+	server := httptest.NewServer(us.routes())
+	defer server.Close()
+
+	first, resp := dialWS(t, server)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("first subscriber got %d, want 101", resp.StatusCode)
+	}
+	if _, resp := dialWS(t, server); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("second subscriber got %d, want 503", resp.StatusCode)
+	}
+
+	// Disconnecting frees the slot and the subscription.
+	first.Close()
+	deadline := time.Now().Add(time.Second)
+	for (us.wsSubscribers.Load() != 0 || us.events.count() != 0) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, resp := dialWS(t, server); resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("subscriber after a disconnect got %d, want 101", resp.StatusCode)
+	}
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	us := newTestShortener(t, nil)
+	expectStatus(t, serve(t, us, http.MethodGet, "/ws/events", ""), http.StatusBadRequest)
+}